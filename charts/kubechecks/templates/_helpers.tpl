@@ -67,3 +67,10 @@ Create the name of the secret file to use
 {{- define "kubechecks.secretsName" -}}
 {{- tpl .Values.secrets.name . }}
 {{- end -}}
+
+{{/*
+Namespace of the leader election lease
+*/}}
+{{- define "kubechecks.leaderElectionNamespace" -}}
+{{- .Values.leaderElection.namespace | default .Values.argocd.namespace }}
+{{- end -}}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/events"
+	"github.com/zapier/kubechecks/pkg/git"
+	"github.com/zapier/kubechecks/pkg/report"
+)
+
+var affectedCmd = &cobra.Command{
+	Use:   "affected [REPO_PATH]",
+	Short: "List the apps affected by changes in a local checkout",
+	Long: "List the Argo CD applications and application sets that are affected by a set of changed files, " +
+		"using the same matchers as a pull request check. The files are either given explicitly, or are the " +
+		"difference between the base and head refs. No checks are run, and nothing is posted to the VCS.",
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		// keep stdout for the list, so that it can be piped into other tools
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
+		baseRef := viper.GetString("affected-base-ref")
+		headRef := viper.GetString("affected-head-ref")
+		files := viper.GetStringSlice("affected-files")
+
+		cleanup := setupArgoSSHDataPath()
+		defer cleanup()
+
+		cfg, err := config.New()
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to generate config")
+		}
+		cfg.VcsType = "local"
+		// every app has to be known up front, there is no controller to watch them
+		cfg.MonitorAllApplications = true
+
+		repoPath, err := localRepoPath(args)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to resolve repo path")
+		}
+
+		repo := &git.Repo{
+			BranchName: baseRef,
+			Config:     cfg,
+			Directory:  repoPath,
+		}
+
+		pr, err := localPullRequest(repo)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to inspect local checkout")
+		}
+		repo.CloneURL = pr.CloneURL
+
+		if len(files) == 0 {
+			if files, err = repo.GetListOfChangedFilesBetween(ctx, "refs/remotes/origin/"+baseRef, headRef); err != nil {
+				log.Fatal().Err(err).Msg("failed to get list of changed files")
+			}
+			pr.HeadRef = headRef
+		}
+
+		ctr, err := container.New(ctx, cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create clients")
+		}
+
+		ce := events.NewCheckEvent(pr, ctr, ctr.RepoManager, nil, nil)
+		items, err := ce.AffectedLocal(ctx, repo, files)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to find affected apps")
+		}
+
+		result := report.NewAffected(pr, files, items)
+		switch output := viper.GetString("affected-output"); output {
+		case "json":
+			err = result.WriteJSON(os.Stdout)
+		case "table":
+			err = result.WriteTable(os.Stdout)
+		default:
+			log.Fatal().Str("output", output).Msg("unknown output format")
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to write affected apps")
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(affectedCmd)
+
+	flags := affectedCmd.Flags()
+	stringFlag(flags, "base-ref", "Branch to compare against, as known by the origin remote.",
+		newStringOpts().withDefault("main"))
+	stringFlag(flags, "head-ref", "Revision to compare to the base ref. Files are read from the working tree, so it should be checked out.",
+		newStringOpts().withDefault("HEAD"))
+	stringSliceFlag(flags, "files", "Changed files, relative to the repository root. Overrides the base and head refs.")
+	stringFlag(flags, "output", "Format of the printed list.",
+		newStringOpts().withDefault("table").withChoices("table", "json"))
+
+	// the flags share their names with the local command, and viper only holds one binding per key, so they're
+	// bound to keys of their own
+	for _, name := range []string{"base-ref", "head-ref", "files", "output"} {
+		panicIfError(viper.BindPFlag("affected-"+name, flags.Lookup(name)))
+	}
+}
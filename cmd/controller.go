@@ -8,6 +8,7 @@ import (
 	"time"
 
 	_ "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -17,6 +18,8 @@ import (
 	"github.com/zapier/kubechecks/pkg/config"
 	"github.com/zapier/kubechecks/pkg/container"
 	"github.com/zapier/kubechecks/pkg/events"
+	"github.com/zapier/kubechecks/pkg/leader"
+	"github.com/zapier/kubechecks/pkg/queue"
 	"github.com/zapier/kubechecks/pkg/server"
 	"github.com/zapier/kubechecks/telemetry"
 )
@@ -46,18 +49,24 @@ var ControllerCmd = &cobra.Command{
 		}
 
 		// watch app modifications, if necessary
-		if cfg.MonitorAllApplications {
-			appWatcher, err := app_watcher.NewApplicationWatcher(ctr, ctx)
-			if err != nil {
-				log.Fatal().Err(err).Msg("failed to create watch applications")
+		if cfg.ArgoCDOfflineAppsPath != "" {
+			log.Info().Str("path", cfg.ArgoCDOfflineAppsPath).Msg("not monitoring applications, running in offline mode")
+		} else if cfg.MonitorAllApplications {
+			// each argocd instance has its own watchers, which keep its own map of apps up to date. Every replica
+			// checks PRs against its own map, so the watchers run in every replica, not only in the leader.
+			for _, instanceCtr := range ctr.InstanceContainers() {
+				appWatcher, err := app_watcher.NewApplicationWatcher(instanceCtr, ctx)
+				if err != nil {
+					log.Fatal().Err(err).Str("instance", instanceCtr.InstanceName()).Msg("failed to create watch applications")
+				}
+				go appWatcher.Run(ctx, 1)
+
+				appSetWatcher, err := app_watcher.NewApplicationSetWatcher(instanceCtr, ctx)
+				if err != nil {
+					log.Fatal().Err(err).Str("instance", instanceCtr.InstanceName()).Msg("failed to create watch application sets")
+				}
+				go appSetWatcher.Run(ctx)
 			}
-			go appWatcher.Run(ctx, 1)
-
-			appSetWatcher, err := app_watcher.NewApplicationSetWatcher(ctr, ctx)
-			if err != nil {
-				log.Fatal().Err(err).Msg("failed to create watch application sets")
-			}
-			go appSetWatcher.Run(ctx)
 		} else {
 			log.Info().Msgf("not monitoring applications, MonitorAllApplications: %+v", cfg.MonitorAllApplications)
 		}
@@ -86,8 +95,38 @@ var ControllerCmd = &cobra.Command{
 		// Create AI review checker (nil if disabled)
 		aiReviewChecker := getAIReviewChecker(ctr)
 
+		identity, err := os.Hostname()
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to get hostname")
+		}
+
+		runs := server.NewRunRegistry()
+		checkQueue, closeQueue := newCheckQueue(cfg, ctr, identity, runs)
+		defer closeQueue()
+
 		// Create server
-		srv := server.NewServer(ctr, processors, aiReviewChecker)
+		srv := server.NewServer(ctr, processors, aiReviewChecker, checkQueue, runs)
+
+		if cfg.LeaderElection {
+			if ctr.KubeClientSet == nil {
+				log.Fatal().Msg("leader election needs a kubernetes client")
+			}
+
+			namespace := cfg.LeaderElectionNamespace
+			if namespace == "" {
+				namespace = cfg.ArgoCDNamespace
+			}
+
+			go func() {
+				if err := leader.Run(ctx, ctr.KubeClientSet.ClientSet(), leader.Config{
+					Namespace: namespace,
+					Name:      cfg.LeaderElectionLeaseName,
+					Identity:  identity,
+				}, srv.LeaderDuties()...); err != nil {
+					log.Fatal().Err(err).Msg("failed to run leader election")
+				}
+			}()
+		}
 
 		// Start HTTP server in background
 		log.Info().Msg("starting web server")
@@ -121,6 +160,32 @@ var ControllerCmd = &cobra.Command{
 	},
 }
 
+// newCheckQueue returns the queue that check requests wait in: shared with the other replicas through Redis, or
+// local to this replica, and persisted when a store path is set.
+func newCheckQueue(cfg config.ServerConfig, ctr container.Container, identity string, runs *server.RunRegistry) (queue.Queue, func()) {
+	if cfg.RedisAddr != "" {
+		if cfg.MaxConcurrentRepoChecks > 0 || len(cfg.RepoPriorities) > 0 {
+			log.Warn().Msg("max-concurrent-repo-checks and repo-priorities are ignored with redis-addr, redis-queue-workers limits the checks of each replica instead")
+		}
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+		})
+		return server.NewRedisQueue(ctr, client, identity, runs), func() { _ = client.Close() }
+	}
+
+	// Persist check requests, so that they are resumed after a restart
+	var store queue.Store
+	if cfg.QueueStorePath != "" {
+		var err error
+		if store, err = queue.NewBoltStore(cfg.QueueStorePath); err != nil {
+			log.Fatal().Err(err).Msg("failed to open queue store")
+		}
+	}
+
+	return server.NewQueueManager(ctr, store, runs), func() {}
+}
+
 func initTelemetry(ctx context.Context, cfg config.ServerConfig) (*telemetry.OperatorTelemetry, error) {
 	return telemetry.Init(
 		ctx, "kubechecks", pkg.GitTag, pkg.GitCommit,
@@ -188,12 +253,27 @@ func init() {
 		newStringOpts().withDefault("argocd-repo-server.argocd:8081"))
 	boolFlag(flags, "argocd-repository-insecure", `True if you need to skip validating the grpc tls certificate.`,
 		newBoolOpts().withDefault(true))
+	stringFlag(flags, "argocd-repository-selector", "Label selector of the Argo CD repo server pods in the Argo CD namespace. The images they run identify the manifests they render in the manifest cache.",
+		newStringOpts().withDefault("app.kubernetes.io/name=argocd-repo-server"))
 	boolFlag(flags, "argocd-send-full-repository", `Set to true if you want to try to send the full repository to ArgoCD when generating manifests.`)
 	stringFlag(flags, "label-filter", `(Optional) If set, The label that must be set on an MR (as "kubechecks:<value>") for kubechecks to process the merge request webhook (KUBECHECKS_LABEL_FILTER).`)
 	stringFlag(flags, "openai-api-token", "OpenAI API Token.")
 	stringFlag(flags, "webhook-url-base", "The endpoint to listen on for incoming PR/MR event webhooks. For example, 'https://checker.mycompany.com'.")
 	stringFlag(flags, "webhook-url-prefix", "If your application is running behind a proxy that uses path based routing, set this value to match the path prefix. For example, '/hello/world'.")
 	stringFlag(flags, "webhook-secret", "Optional secret key for validating the source of incoming webhooks.")
+	stringFlag(flags, "history-store-path", "Path of a file, e.g. on a persistent volume, that the runs of every check are recorded in. Runs are not recorded when empty.")
+	durationFlag(flags, "history-retention", "How long recorded runs are kept.",
+		newDurationOpts().withDefault(30*24*time.Hour))
+	stringFlag(flags, "dashboard-addr", "Address of a separate listener, e.g. :8081, that serves the dashboard of recorded runs. "+
+		"It is not authenticated, so it should only be reachable from inside the cluster or through an authenticating proxy. The dashboard is not served when empty.")
+	stringFlag(flags, "dashboard-url-base", "URL that the dashboard listener is reachable at by the readers of PRs, e.g. 'https://kubechecks-dashboard.mycompany.com'. "+
+		"Reports link to the page of their run only when set.")
+	durationFlag(flags, "revalidate-interval", "How often the base branches of the PRs that were checked, and the live state of their apps, are compared to their last check. PRs whose report got stale are checked again. Re-validation is disabled when 0.")
+	boolFlag(flags, "revalidate-live-state", "Check PRs again when the live state of one of their apps changed, not only when their base branch did.",
+		newBoolOpts().withDefault(true))
+	durationFlag(flags, "revalidate-max-age", "How long after their last check PRs are no longer re-validated.",
+		newDurationOpts().withDefault(7*24*time.Hour))
+	stringFlag(flags, "admin-token", "Bearer token that authenticates calls to the admin API, which lists, cancels and re-runs checks and purges caches. The admin API is disabled when empty.")
 	boolFlag(flags, "monitor-all-applications", "Monitor all applications in argocd automatically.",
 		newBoolOpts().withDefault(true))
 	boolFlag(flags, "ensure-webhooks", "Ensure that webhooks are created in repositories referenced by argo.")
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/events"
+	"github.com/zapier/kubechecks/pkg/git"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+var localCmd = &cobra.Command{
+	Use:   "local [REPO_PATH]",
+	Short: "Run checks against a local checkout",
+	Long: "Run all checks against the commits in a local checkout that are not in the base ref yet, " +
+		"and print the results. Nothing is posted to the VCS, and no VCS token is required.",
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		cleanup := setupArgoSSHDataPath()
+		defer cleanup()
+
+		cfg, err := config.New()
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to generate config")
+		}
+		cfg.VcsType = "local"
+
+		repoPath, err := localRepoPath(args)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to resolve repo path")
+		}
+
+		baseRef := viper.GetString("base-ref")
+		repo := &git.Repo{
+			BranchName: baseRef,
+			Config:     cfg,
+			Directory:  repoPath,
+		}
+
+		pr, err := localPullRequest(repo)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to inspect local checkout")
+		}
+		repo.CloneURL = pr.CloneURL
+
+		ctr, err := container.New(ctx, cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create clients")
+		}
+
+		processors, err := getProcessors(ctr)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to create processors")
+		}
+
+		log.Info().Strs("locations", cfg.PoliciesLocation).Msg("processing policies locations")
+		if err = processLocations(ctx, ctr, cfg.PoliciesLocation); err != nil {
+			log.Fatal().Err(err).Msg("failed to process policy locations")
+		}
+
+		log.Info().Strs("locations", cfg.SchemasLocations).Msg("processing schemas locations")
+		if err = processLocations(ctx, ctr, cfg.SchemasLocations); err != nil {
+			log.Fatal().Err(err).Msg("failed to process schema locations")
+		}
+
+		ce := events.NewCheckEvent(pr, ctr, ctr.RepoManager, processors, nil)
+		result, err := ce.ProcessLocal(ctx, repo)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to process local checkout")
+		}
+
+		switch output := viper.GetString("output"); output {
+		case "json":
+			err = result.WriteJSON(os.Stdout)
+		case "terminal":
+			err = result.WriteTerminal(os.Stdout)
+		default:
+			log.Fatal().Str("output", output).Msg("unknown output format")
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to write report")
+		}
+
+		if result.Failed() {
+			os.Exit(1)
+		}
+	},
+}
+
+// localRepoPath returns the absolute path of the checkout given on the command line, or of the working directory.
+func localRepoPath(args []string) (string, error) {
+	if len(args) == 1 {
+		return filepath.Abs(args[0])
+	}
+	return filepath.Abs(".")
+}
+
+// localPullRequest describes the local checkout as if it were a pull request from its current branch into the base ref.
+func localPullRequest(repo *git.Repo) (vcs.PullRequest, error) {
+	var pr vcs.PullRequest
+
+	cloneURL, err := repo.GetRemoteURL()
+	if err != nil {
+		return pr, err
+	}
+
+	parsed, err := pkg.Canonicalize(cloneURL)
+	if err != nil {
+		return pr, err
+	}
+
+	headRef, err := repo.GetCurrentBranch()
+	if err != nil {
+		return pr, err
+	}
+
+	sha, err := repo.GetCurrentCommitSHA()
+	if err != nil {
+		return pr, err
+	}
+
+	return vcs.PullRequest{
+		BaseRef:       repo.BranchName,
+		HeadRef:       headRef,
+		DefaultBranch: repo.BranchName,
+		CloneURL:      cloneURL,
+		Name:          path.Base(parsed.Path),
+		Owner:         path.Dir(parsed.Path),
+		FullName:      parsed.Path,
+		SHA:           sha,
+		Config:        repo.Config,
+	}, nil
+}
+
+func init() {
+	RootCmd.AddCommand(localCmd)
+
+	flags := localCmd.Flags()
+	stringFlag(flags, "base-ref", "Branch to compare the local checkout against, as known by the origin remote.",
+		newStringOpts().withDefault("main"))
+	stringFlag(flags, "output", "Format of the printed report.",
+		newStringOpts().withDefault("terminal").withChoices("terminal", "json"))
+
+	panicIfError(viper.BindPFlags(flags))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/argoproj/argo-cd/v3/common"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/zapier/kubechecks/pkg"
 	"github.com/zapier/kubechecks/pkg/config"
@@ -21,27 +22,8 @@ var processCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
 
-		tempPath, err := os.MkdirTemp("", "")
-		if err != nil {
-			log.Fatal().Err(err).Msg("fail to create ssh data dir")
-		}
-		defer pkg.WithErrorLogging(func() error { return os.RemoveAll(tempPath) }, "failed to remove temp directory")
-
-		// symlink local ssh known hosts to argocd ssh known hosts
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			log.Fatal().Err(err).Msg("failed to get user home dir")
-		}
-		source := filepath.Join(homeDir, ".ssh", "known_hosts")
-		target := filepath.Join(tempPath, common.DefaultSSHKnownHostsName)
-
-		if err := os.Symlink(source, target); err != nil {
-			log.Fatal().Err(err).Msg("fail to symlink ssh_known_hosts file")
-		}
-
-		if err := os.Setenv("ARGOCD_SSH_DATA_PATH", tempPath); err != nil {
-			log.Fatal().Err(err).Msg("fail to set ARGOCD_SSH_DATA_PATH")
-		}
+		cleanup := setupArgoSSHDataPath()
+		defer cleanup()
 
 		cfg, err := config.New()
 		if err != nil {
@@ -85,6 +67,43 @@ var processCmd = &cobra.Command{
 	},
 }
 
+// setupArgoSSHDataPath points the argocd libraries at the local ssh known hosts, so that repositories can
+// be cloned over ssh outside the cluster. The returned function removes the temporary data directory.
+func setupArgoSSHDataPath() func() {
+	tempPath, err := os.MkdirTemp("", "")
+	if err != nil {
+		log.Fatal().Err(err).Msg("fail to create ssh data dir")
+	}
+	cleanup := func() {
+		pkg.WithErrorLogging(func() error { return os.RemoveAll(tempPath) }, "failed to remove temp directory")
+	}
+
+	// symlink local ssh known hosts to argocd ssh known hosts
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to get user home dir")
+	}
+	source := filepath.Join(homeDir, ".ssh", "known_hosts")
+	target := filepath.Join(tempPath, common.DefaultSSHKnownHostsName)
+
+	if err := os.Symlink(source, target); err != nil {
+		log.Fatal().Err(err).Msg("fail to symlink ssh_known_hosts file")
+	}
+
+	if err := os.Setenv("ARGOCD_SSH_DATA_PATH", tempPath); err != nil {
+		log.Fatal().Err(err).Msg("fail to set ARGOCD_SSH_DATA_PATH")
+	}
+
+	return cleanup
+}
+
 func init() {
 	RootCmd.AddCommand(processCmd)
+
+	flags := processCmd.Flags()
+	stringFlag(flags, "report-json-file", "If set, write the full result of the run as a versioned JSON document to this path.")
+	stringFlag(flags, "report-sarif-file", "If set, write kubeconform, conftest and kubepug findings as a SARIF log to this path.")
+	stringFlag(flags, "report-junit-file", "If set, write the result of the run as JUnit XML to this path, with a test suite per app and a test case per check.")
+
+	panicIfError(viper.BindPFlags(flags))
 }
@@ -83,6 +83,7 @@ func getAIReviewChecker(ctr container.Container) queue.AIReviewChecker {
 		log.Error().Err(err).Msg("failed to create AI review provider, AI review disabled")
 		return nil
 	}
+	provider = aiproviders.WithLimit(provider, ctr.AILimit)
 
 	checkerOpts := []aireviewcheck.NewCheckerOption{
 		aireviewcheck.WithModel(ctr.Config.AIReviewModel),
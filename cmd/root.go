@@ -54,13 +54,15 @@ func init() {
 	boolFlag(flags, "persist-log-level", "Persists the set log level down to other module loggers.")
 	stringFlag(flags, "vcs-base-url", "VCS base url, useful if self hosting gitlab, enterprise github, etc.")
 	stringFlag(flags, "vcs-upload-url", "VCS upload url, required for enterprise github.")
-	stringFlag(flags, "vcs-type", "VCS type. One of gitlab or github. Defaults to gitlab.",
+	stringFlag(flags, "vcs-type", "VCS type. One of gitlab, github or local. Defaults to gitlab.",
 		newStringOpts().
-			withChoices("github", "gitlab").
+			withChoices("github", "gitlab", "local").
 			withDefault("gitlab"))
 	stringFlag(flags, "vcs-token", "VCS API token.")
 	stringFlag(flags, "vcs-username", "VCS Username.")
 	stringFlag(flags, "vcs-email", "VCS Email.")
+	stringFlag(flags, "local-vcs-output-dir", "Directory the local VCS client writes comments, commit statuses and reviews to. Nothing is written if unset.")
+	stringFlag(flags, "local-vcs-archive-dir", "Directory the local VCS client serves pull request archives from, named <sha>.zip.")
 	stringFlag(flags, "github-private-key", "Github App Private Key.")
 	int64Flag(flags, "github-app-id", "Github App ID.")
 	int64Flag(flags, "github-installation-id", "Github Installation ID.")
@@ -73,6 +75,24 @@ func init() {
 		newStringOpts().
 			withDefault("argocd"))
 	boolFlag(flags, "argocd-api-plaintext", "Enable to use plaintext connections without TLS.")
+	stringFlag(flags, "argocd-offline-apps-path", "Read Application and ApplicationSet manifests from this path instead of the ArgoCD API. "+
+		"An absolute path is loaded at startup, a relative path is resolved inside each repository that is checked. "+
+		"There is no live state in offline mode, so every resource is shown as new.")
+	stringFlag(flags, "argocd-instances-file", "Path to a yaml file listing several ArgoCD instances to check changes against, each with its own "+
+		"name, apiServerAddr, token or tokenEnv, namespace and kubernetes settings. Empty or unset settings fall back to the argocd-* flags. "+
+		"Affected apps are checked against the instance they were found in, and all of them are reported in one comment.")
+	stringFlag(flags, "manifest-renderer", "How manifests are rendered: by the ArgoCD repo server, or locally in the kubechecks process "+
+		"(requires the helm and kustomize binaries). Can be overridden per application with the 'kubechecks.io/manifest-renderer' annotation, "+
+		"within manifest-renderer-overrides.",
+		newStringOpts().
+			withChoices("repo-server", "local").
+			withDefault("repo-server"))
+	stringSliceFlag(flags, "manifest-renderer-overrides", "Renderers that the 'kubechecks.io/manifest-renderer' annotation of an application may select "+
+		"instead of manifest-renderer. Applications are changed by pull requests, so the annotation is rejected unless it names one of these. Can be repeated.",
+		newStringSliceOpts().
+			withChoices("repo-server", "local"))
+	stringSliceFlag(flags, "cmp-plugin-files", "Files that affect the manifests rendered by a config management plugin, as <plugin>=<glob>, e.g. jsonnet=lib/**. "+
+		"Globs are relative to the source path of the app, or to the repository root when they start with a slash. Can be repeated.")
 	stringFlag(flags, "kubernetes-type", "Kubernetes Type One of eks, or local. Defaults to local.",
 		newStringOpts().
 			withChoices("eks", "local").
@@ -80,6 +100,12 @@ func init() {
 	stringFlag(flags, "kubernetes-clusterid", "Kubernetes Cluster ID, must be specified if kubernetes-type is eks.")
 	stringFlag(flags, "kubernetes-config", "Path to your kubernetes config file, used to monitor applications.")
 
+	boolFlag(flags, "enable-appset-scm-providers", "Allow the SCM provider and pull request ApplicationSet generators to call the APIs of SCM providers. "+
+		"Requires appset-allowed-scm-providers. GitHub App secrets referenced by appsets are read from the repository credentials of Argo CD.")
+	stringSliceFlag(flags, "appset-allowed-scm-providers", "The SCM provider API URLs that ApplicationSet generators may call, with the credentials their appsets refer to. "+
+		"Appsets that use the default API URL of a provider are always allowed.")
+	stringFlag(flags, "appset-scm-root-ca-path", "Path to a file of root CAs to trust when ApplicationSet generators call SCM providers.")
+
 	stringFlag(flags, "otel-collector-port", "The OpenTelemetry collector port.")
 	stringFlag(flags, "otel-collector-host", "The OpenTelemetry collector host.")
 	boolFlag(flags, "otel-enabled", "Enable OpenTelemetry.")
@@ -114,9 +140,40 @@ func init() {
 	int64Flag(flags, "max-concurrent-checks", "Number of concurrent checks to run.",
 		newInt64Opts().
 			withDefault(32))
+	int64Flag(flags, "max-repo-server-calls", "Maximum number of manifest generation calls sent to each Argo CD repo server at once, across every check. There is no limit when 0.",
+		newInt64Opts().
+			withDefault(0))
+	int64Flag(flags, "max-cluster-calls", "Maximum number of live state and diff calls made for each destination cluster at once, across every check. There is no limit when 0.",
+		newInt64Opts().
+			withDefault(0))
+	int64Flag(flags, "max-ai-calls", "Maximum number of calls made to the AI provider at once, across every check. There is no limit when 0.",
+		newInt64Opts().
+			withDefault(0))
 	int64Flag(flags, "max-repo-worker-queue-size", "Maximum size of check request queue per repository worker.",
 		newInt64Opts().
 			withDefault(100))
+	stringFlag(flags, "queue-store-path", "Path of a file, e.g. on a persistent volume, that check requests are stored in until they are processed, so that they are resumed after a restart. Requests are only kept in memory when empty.")
+	int64Flag(flags, "max-concurrent-repo-checks", "Maximum number of check requests processed at once across every repository. Waiting requests are scheduled by priority, fairly between repositories. There is no limit when 0.",
+		newInt64Opts().
+			withDefault(0))
+	stringSliceFlag(flags, "repo-priorities", "Check priority of repositories, e.g. zapier/kubechecks=high. The priority is low, normal or high, and can be overridden on a PR with a label like kubechecks:priority-low.")
+	stringSliceFlag(flags, "label-priorities", "Priorities that PR labels like kubechecks:priority-low may set. Anyone who can label a PR can use them, so only list normal and high when you trust them not to jump the queue.",
+		newStringSliceOpts().withDefault([]string{"low"}))
+	stringFlag(flags, "redis-addr", "Address of a Redis server that holds a check queue shared by every replica, so that kubechecks can run more than one replica. Requests are queued in each replica when empty.")
+	stringFlag(flags, "redis-password", "Password of the Redis server.")
+	int64Flag(flags, "redis-queue-workers", "Number of check requests each replica processes at once from the shared queue.",
+		newInt64Opts().
+			withDefault(4))
+	boolFlag(flags, "leader-election", "Elect a leader among the replicas with a Kubernetes lease. Only the leader creates webhooks.",
+		newBoolOpts().
+			withDefault(false))
+	stringFlag(flags, "leader-election-namespace", "Namespace of the leader election lease. The Argo CD namespace is used when empty.")
+	stringFlag(flags, "leader-election-lease-name", "Name of the leader election lease.",
+		newStringOpts().
+			withDefault("kubechecks"))
+	int64Flag(flags, "max-app-of-apps-depth", "How many levels of child apps found in app-of-apps are checked. Deeper apps are listed, but not checked.",
+		newInt64Opts().
+			withDefault(5))
 	boolFlag(flags, "enable-hooks-renderer", "Render hooks.", newBoolOpts().withDefault(true))
 	stringFlag(flags, "worst-hooks-state", "The worst state that can be returned from the hooks renderer.",
 		newStringOpts().
@@ -140,6 +197,17 @@ func init() {
 	durationFlag(flags, "archive-cache-ttl", "Time-to-live for cached archives.",
 		newDurationOpts().
 			withDefault(1*time.Hour))
+	stringFlag(flags, "manifest-cache-dir", "Directory that rendered manifests are cached in, so that apps whose sources did not change are not rendered again. Manifests are not cached when empty.")
+	durationFlag(flags, "manifest-cache-ttl", "Time-to-live for cached manifests.",
+		newDurationOpts().
+			withDefault(24*time.Hour))
+	int64Flag(flags, "manifest-cache-max-size-mb", "Maximum size of the manifest cache in megabytes. The least recently used manifests are removed beyond it.",
+		newInt64Opts().
+			withDefault(1024))
+	stringFlag(flags, "check-cache-dir", "Directory that the check results of each app of a PR are stored in, so that a new push reuses the results of apps whose rendered manifests, live state and policies did not change. Every app is checked when empty.")
+	durationFlag(flags, "check-cache-ttl", "How long the check results of an app are reused.",
+		newDurationOpts().
+			withDefault(7*24*time.Hour))
 	stringFlag(flags, "identifier", "Identifier for the kubechecks instance. Used to differentiate between multiple kubechecks instances.",
 		newStringOpts().
 			withDefault(""))
@@ -39,3 +39,17 @@ The full list of supported environment variables is described below:
 {{- range .Options }}
 |`{{ .Env }}`|{{ .Usage }}|{{ if .Default }}`{{ .Default }}`{{ end }}|
 {{- end }}
+
+### ApplicationSet Generators
+
+The `ClusterDecisionResource` generator reads the config map its appset refers to, in the Argo CD namespace, and lists the resources of the kind that config map names, e.g. `placementdecisions` of Open Cluster Management. The chart does not grant access to those resources, since they depend on the tool that makes the decisions. Add the rules with `rbac.extraClusterRoleRules` in your `values.yaml`:
+
+```yaml
+rbac:
+  extraClusterRoleRules:
+    - apiGroups: ['cluster.open-cluster-management.io']
+      resources: ['placementdecisions']
+      verbs: ['get', 'list']
+```
+
+Without them, the apps of these appsets can't be generated, and the appsets are skipped with an error in the logs.
@@ -2,6 +2,7 @@ package affected_apps
 
 import (
 	"context"
+	"io/fs"
 	"os"
 
 	"github.com/rs/zerolog/log"
@@ -14,13 +15,19 @@ type ArgocdMatcher struct {
 	appSetsDirectory *appdir.AppSetDirectory
 }
 
-func NewArgocdMatcher(vcsToArgoMap appdir.VcsToArgoMap, repo *git.Repo) (*ArgocdMatcher, error) {
+// NewArgocdMatcher creates a matcher for the apps and appsets in a repo. pluginFiles holds the glob patterns
+// of the files that affect each config management plugin, keyed by plugin name.
+func NewArgocdMatcher(vcsToArgoMap appdir.VcsToArgoMap, repo *git.Repo, pluginFiles map[string][]string) (*ArgocdMatcher, error) {
 	repoApps := getArgocdApps(vcsToArgoMap, repo)
 	kustomizeAppFiles := getKustomizeApps(vcsToArgoMap, repo, repo.Directory)
+	helmAppFiles := getHelmApps(vcsToArgoMap, repo, repo.Directory)
+	pluginAppFiles := getPluginApps(vcsToArgoMap, repo, pluginFiles)
 
 	appDirectory := appdir.NewAppDirectory().
 		Union(repoApps).
-		Union(kustomizeAppFiles)
+		Union(kustomizeAppFiles).
+		Union(helmAppFiles).
+		Union(pluginAppFiles)
 
 	repoAppSets := getArgocdAppSets(vcsToArgoMap, repo)
 	appSetDirectory := appdir.NewAppSetDirectory().
@@ -55,6 +62,25 @@ func getKustomizeApps(vcsToArgoMap appdir.VcsToArgoMap, repo *git.Repo, repoPath
 	return kustomizeAppFiles
 }
 
+func getHelmApps(vcsToArgoMap appdir.VcsToArgoMap, repo *git.Repo, repoPath string) *appdir.AppDirectory {
+	log.Debug().Caller().Msgf("creating fs for %s", repoPath)
+	fs := os.DirFS(repoPath)
+
+	log.Debug().Caller().Msg("following helm apps")
+	helmAppFiles := vcsToArgoMap.WalkHelmApps(repo.CloneURL, fs)
+
+	logCounts(helmAppFiles)
+	return helmAppFiles
+}
+
+func getPluginApps(vcsToArgoMap appdir.VcsToArgoMap, repo *git.Repo, pluginFiles map[string][]string) *appdir.AppDirectory {
+	log.Debug().Caller().Msg("following config management plugin apps")
+	pluginAppFiles := vcsToArgoMap.GetPluginApps(repo.CloneURL, pluginFiles)
+
+	logCounts(pluginAppFiles)
+	return pluginAppFiles
+}
+
 func getArgocdApps(vcsToArgoMap appdir.VcsToArgoMap, repo *git.Repo) *appdir.AppDirectory {
 	log.Debug().Caller().Msgf("looking for %s repos", repo.CloneURL)
 	repoApps := vcsToArgoMap.GetAppsInRepo(repo.CloneURL)
@@ -80,13 +106,20 @@ func (a *ArgocdMatcher) AffectedApps(_ context.Context, changeList []string, tar
 		return AffectedItems{}, nil
 	}
 
-	appsSlice := a.appsDirectory.FindAppsBasedOnChangeList(changeList, targetBranch)
-	appSetsSlice := a.appSetsDirectory.FindAppSetsBasedOnChangeList(changeList, repo)
+	var sourceFS fs.FS
+	if repo != nil {
+		sourceFS = os.DirFS(repo.Directory)
+	}
+
+	appsSlice, appReasons := a.appsDirectory.FindAppsBasedOnChangeList(changeList, targetBranch, sourceFS)
+	appSetsSlice, appSetReasons := a.appSetsDirectory.FindAppSetsBasedOnChangeList(changeList, repo)
 
 	// and return both apps and appSets
 	return AffectedItems{
 		Applications:    appsSlice,
 		ApplicationSets: appSetsSlice,
+		AppReasons:      appReasons,
+		AppSetReasons:   appSetReasons,
 	}, nil
 }
 
@@ -24,7 +24,7 @@ func TestCreateNewMatcherWithNilVcsMap(t *testing.T) {
 	)
 
 	// run test
-	matcher, err := NewArgocdMatcher(vcsMap, &repo)
+	matcher, err := NewArgocdMatcher(vcsMap, &repo, nil)
 	require.NoError(t, err)
 
 	// verify results
@@ -161,7 +161,7 @@ resources:
 			testVCSMap := appdir.NewVcsToArgoMap("vcs-username")
 			testVCSMap.AddApp(&tc.app)
 
-			m, err := NewArgocdMatcher(testVCSMap, testRepo)
+			m, err := NewArgocdMatcher(testVCSMap, testRepo, nil)
 			require.NoError(t, err)
 
 			ctx := context.Background()
@@ -34,7 +34,8 @@ func (b *ConfigMatcher) AffectedApps(ctx context.Context, changeList []string, _
 	triggeredAppsMap := make(map[string]string)
 	var appSetList []v1alpha1.ApplicationSet
 
-	triggeredApps, triggeredAppsets, err := b.triggeredApps(ctx, changeList)
+	var reasons AffectedItems
+	triggeredApps, triggeredAppsets, err := b.triggeredApps(ctx, changeList, &reasons)
 	if err != nil {
 		return AffectedItems{}, err
 	}
@@ -65,10 +66,17 @@ func (b *ConfigMatcher) AffectedApps(ctx context.Context, changeList []string, _
 		triggeredAppsSlice = append(triggeredAppsSlice, app)
 	}
 
-	return AffectedItems{Applications: triggeredAppsSlice, ApplicationSets: appSetList}, nil
+	return AffectedItems{
+		Applications:    triggeredAppsSlice,
+		ApplicationSets: appSetList,
+		AppReasons:      reasons.AppReasons,
+		AppSetReasons:   reasons.AppSetReasons,
+	}, nil
 }
 
-func (b *ConfigMatcher) triggeredApps(ctx context.Context, modifiedFiles []string) ([]*repo_config.ArgoCdApplicationConfig, []*repo_config.ArgocdApplicationSetConfig, error) {
+// triggeredApps returns the apps and appsets from the repo config that are affected by the modified files,
+// and records why each of them was triggered in reasons.
+func (b *ConfigMatcher) triggeredApps(ctx context.Context, modifiedFiles []string, reasons *AffectedItems) ([]*repo_config.ArgoCdApplicationConfig, []*repo_config.ArgocdApplicationSetConfig, error) {
 	triggeredAppsMap := map[string]*repo_config.ArgoCdApplicationConfig{}
 	triggeredAppsetsMap := map[string]*repo_config.ArgocdApplicationSetConfig{}
 
@@ -77,6 +85,7 @@ func (b *ConfigMatcher) triggeredApps(ctx context.Context, modifiedFiles []strin
 
 		for _, app := range apps {
 			triggeredAppsMap[app.Name] = app
+			reasons.AddAppReason(app.Name, fmt.Sprintf("directory `%s` matches the paths of the app in the repo config", dir))
 		}
 
 		// Check if an appset is modified and fetch it's apps
@@ -88,10 +97,12 @@ func (b *ConfigMatcher) triggeredApps(ctx context.Context, modifiedFiles []strin
 
 			for _, appset := range appsets {
 				triggeredAppsetsMap[appset.Name] = appset
+				reasons.AddAppSetReason(appset.Name, fmt.Sprintf("directory `%s` matches the paths of the appset in the repo config", dir))
 			}
 
 			for _, app := range appsetApps {
 				triggeredAppsMap[app.Name] = app
+				reasons.AddAppReason(app.Name, fmt.Sprintf("directory `%s` matches the paths of an appset in the repo config that manages the app", dir))
 			}
 		}
 	}
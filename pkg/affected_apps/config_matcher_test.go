@@ -105,7 +105,7 @@ func TestConfigMatcher_triggeredApps(t *testing.T) {
 				cfg:        c,
 				argoClient: mockArgoClient,
 			}
-			gotApps, gotAppSets, _ := b.triggeredApps(context.TODO(), tt.args.modifiedFiles)
+			gotApps, gotAppSets, _ := b.triggeredApps(context.TODO(), tt.args.modifiedFiles, new(AffectedItems))
 			assert.ElementsMatch(t, gotApps, c.Applications, "applications did not match.")
 			assert.ElementsMatch(t, gotAppSets, c.ApplicationSets, "applicationsets did not match.")
 		})
@@ -0,0 +1,41 @@
+package affected_apps
+
+import (
+	"context"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/git"
+)
+
+// NewInstanceMatcher tags everything the matcher finds with the Argo CD instance it was found in.
+func NewInstanceMatcher(instance string, matcher Matcher) Matcher {
+	return InstanceMatcher{instance: instance, matcher: matcher}
+}
+
+type InstanceMatcher struct {
+	instance string
+	matcher  Matcher
+}
+
+func (m InstanceMatcher) AffectedApps(ctx context.Context, changeList []string, targetBranch string, repo *git.Repo) (AffectedItems, error) {
+	items, err := m.matcher.AffectedApps(ctx, changeList, targetBranch, repo)
+	if err != nil {
+		return items, err
+	}
+
+	var tagged AffectedItems
+	for _, app := range items.Applications {
+		reasons := items.AppReasons[pkg.QualifiedName(app.ObjectMeta)]
+		app.ObjectMeta = pkg.WithArgoCDInstance(app.ObjectMeta, m.instance)
+		tagged.Applications = append(tagged.Applications, app)
+		tagged.AddAppReason(pkg.QualifiedName(app.ObjectMeta), reasons...)
+	}
+	for _, appSet := range items.ApplicationSets {
+		reasons := items.AppSetReasons[pkg.QualifiedName(appSet.ObjectMeta)]
+		appSet.ObjectMeta = pkg.WithArgoCDInstance(appSet.ObjectMeta, m.instance)
+		tagged.ApplicationSets = append(tagged.ApplicationSets, appSet)
+		tagged.AddAppSetReason(pkg.QualifiedName(appSet.ObjectMeta), reasons...)
+	}
+
+	return tagged, nil
+}
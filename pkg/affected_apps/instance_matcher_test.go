@@ -0,0 +1,40 @@
+package affected_apps
+
+import (
+	"context"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zapier/kubechecks/pkg"
+)
+
+func TestInstanceMatcher(t *testing.T) {
+	found := func(reason string) fakeMatcher {
+		var items AffectedItems
+		items.Applications = []v1alpha1.Application{{ObjectMeta: v1.ObjectMeta{Name: "app-1"}}}
+		items.ApplicationSets = []v1alpha1.ApplicationSet{{ObjectMeta: v1.ObjectMeta{Name: "appset-1"}}}
+		items.AddAppReason("app-1", reason)
+		items.AddAppSetReason("appset-1", reason)
+		return fakeMatcher{items: items}
+	}
+
+	matcher := NewMultiMatcher(
+		NewInstanceMatcher("us", found("changed in us")),
+		NewInstanceMatcher("eu", found("changed in eu")),
+	)
+	total, err := matcher.AffectedApps(context.Background(), nil, "", nil)
+	require.NoError(t, err)
+
+	require.Len(t, total.Applications, 2, "apps with the same name in different instances are both kept")
+	assert.Equal(t, "us", pkg.ArgoCDInstanceOf(total.Applications[0].ObjectMeta))
+	assert.Equal(t, "eu", pkg.ArgoCDInstanceOf(total.Applications[1].ObjectMeta))
+	assert.Equal(t, []string{"changed in us"}, total.AppReasons["us/app-1"])
+	assert.Equal(t, []string{"changed in eu"}, total.AppReasons["eu/app-1"])
+
+	require.Len(t, total.ApplicationSets, 2)
+	assert.Equal(t, []string{"changed in eu"}, total.AppSetReasons["eu/appset-1"])
+}
@@ -3,24 +3,61 @@ package affected_apps
 import (
 	"context"
 	"path"
+	"slices"
 
 	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+
+	"github.com/zapier/kubechecks/pkg"
 	"github.com/zapier/kubechecks/pkg/git"
 )
 
 type AffectedItems struct {
 	Applications    []v1alpha1.Application
 	ApplicationSets []v1alpha1.ApplicationSet
+
+	// AppReasons and AppSetReasons explain why each item was considered affected, keyed by name,
+	// prefixed with the Argo CD instance when there is more than one.
+	AppReasons    map[string][]string
+	AppSetReasons map[string][]string
+}
+
+// AddAppReason records why an application was considered affected.
+func (ai *AffectedItems) AddAppReason(name string, reasons ...string) {
+	ai.AppReasons = addReasons(ai.AppReasons, name, reasons)
+}
+
+// AddAppSetReason records why an application set was considered affected.
+func (ai *AffectedItems) AddAppSetReason(name string, reasons ...string) {
+	ai.AppSetReasons = addReasons(ai.AppSetReasons, name, reasons)
+}
+
+func addReasons(all map[string][]string, name string, reasons []string) map[string][]string {
+	if len(reasons) == 0 {
+		return all
+	}
+
+	if all == nil {
+		all = make(map[string][]string)
+	}
+
+	for _, reason := range reasons {
+		if slices.Contains(all[name], reason) {
+			continue
+		}
+		all[name] = append(all[name], reason)
+	}
+
+	return all
 }
 
 func (ai AffectedItems) Union(other AffectedItems) AffectedItems {
 	// merge apps
 	appNameSet := make(map[string]struct{})
 	for _, app := range ai.Applications {
-		appNameSet[app.Name] = struct{}{}
+		appNameSet[pkg.QualifiedName(app.ObjectMeta)] = struct{}{}
 	}
 	for _, app := range other.Applications {
-		if _, ok := appNameSet[app.Name]; ok {
+		if _, ok := appNameSet[pkg.QualifiedName(app.ObjectMeta)]; ok {
 			continue
 		}
 
@@ -30,16 +67,30 @@ func (ai AffectedItems) Union(other AffectedItems) AffectedItems {
 	// merge appsets
 	appSetNameSet := make(map[string]struct{})
 	for _, appSet := range ai.ApplicationSets {
-		appSetNameSet[appSet.Name] = struct{}{}
+		appSetNameSet[pkg.QualifiedName(appSet.ObjectMeta)] = struct{}{}
 	}
 	for _, appSet := range other.ApplicationSets {
-		if _, ok := appSetNameSet[appSet.Name]; ok {
+		if _, ok := appSetNameSet[pkg.QualifiedName(appSet.ObjectMeta)]; ok {
 			continue
 		}
 
 		ai.ApplicationSets = append(ai.ApplicationSets, appSet)
 	}
 
+	// merge reasons, without modifying the maps of either side
+	appReasons, appSetReasons := ai.AppReasons, ai.AppSetReasons
+	ai.AppReasons, ai.AppSetReasons = nil, nil
+	for _, reasons := range []map[string][]string{appReasons, other.AppReasons} {
+		for name, r := range reasons {
+			ai.AddAppReason(name, r...)
+		}
+	}
+	for _, reasons := range []map[string][]string{appSetReasons, other.AppSetReasons} {
+		for name, r := range reasons {
+			ai.AddAppSetReason(name, r...)
+		}
+	}
+
 	// return the merge
 	return ai
 }
@@ -100,4 +100,30 @@ func TestMultiMatcher(t *testing.T) {
 		require.Equal(t, app1, total.Applications[0])
 		require.Equal(t, app2, total.Applications[1])
 	})
+
+	t.Run("reasons are merged", func(t *testing.T) {
+		app1 := v1alpha1.Application{ObjectMeta: v1.ObjectMeta{Name: "app-1"}}
+		matcher1 := fakeMatcher{
+			items: AffectedItems{
+				Applications: []v1alpha1.Application{app1},
+				AppReasons:   map[string][]string{"app-1": {"reason 1", "reason 2"}},
+			},
+		}
+		matcher2 := fakeMatcher{
+			items: AffectedItems{
+				Applications:  []v1alpha1.Application{app1},
+				AppReasons:    map[string][]string{"app-1": {"reason 2", "reason 3"}},
+				AppSetReasons: map[string][]string{"appset-1": {"reason 4"}},
+			},
+		}
+
+		ctx := context.Background()
+		matcher := NewMultiMatcher(matcher1, matcher2)
+		total, err := matcher.AffectedApps(ctx, nil, "", nil)
+
+		require.NoError(t, err)
+		require.Equal(t, map[string][]string{"app-1": {"reason 1", "reason 2", "reason 3"}}, total.AppReasons)
+		require.Equal(t, map[string][]string{"appset-1": {"reason 4"}}, total.AppSetReasons)
+		require.Equal(t, []string{"reason 1", "reason 2"}, matcher1.items.AppReasons["app-1"], "inputs must not be modified")
+	})
 }
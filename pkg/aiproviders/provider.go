@@ -3,6 +3,8 @@ package aiproviders
 import (
 	"context"
 	"encoding/json"
+
+	"github.com/zapier/kubechecks/pkg/limiter"
 )
 
 // Provider abstracts the LLM chat completion API with tool use support.
@@ -11,6 +13,29 @@ type Provider interface {
 	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
 }
 
+// WithLimit returns a provider that waits for a free slot of limit before each chat call.
+func WithLimit(p Provider, limit *limiter.Semaphore) Provider {
+	if limit == nil {
+		return p
+	}
+	return &limitedProvider{Provider: p, limit: limit}
+}
+
+type limitedProvider struct {
+	Provider
+	limit *limiter.Semaphore
+}
+
+func (p *limitedProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	release, err := p.limit.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return p.Provider.Chat(ctx, req)
+}
+
 type ChatRequest struct {
 	Model        string
 	SystemPrompt string
@@ -1,11 +1,16 @@
 package appdir
 
 import (
+	"fmt"
+	"io/fs"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/util/glob"
 	"github.com/rs/zerolog/log"
+	"github.com/zapier/kubechecks/pkg/cmp"
 )
 
 // AppDirectory manages the mapping between applications and their associated directories and files.
@@ -20,16 +25,56 @@ type AppDirectory struct {
 	// This is used to quickly identify which applications are affected when specific files change.
 	appFiles map[string][]string
 
+	// appGlobs maps glob patterns to the names of applications that use the files matching them.
+	// This is used for files that are only known by pattern, like the files of a config management plugin.
+	appGlobs map[string][]string
+
 	// appsMap stores the full Argo CD application definitions, indexed by application name.
 	// This serves as the source of truth for application configurations.
 	appsMap map[string]v1alpha1.Application
+
+	// pathKinds describes how an application uses each of its directories and files, e.g. as a helm value file.
+	// This is used to explain why an application was affected by a change.
+	pathKinds map[appPath]PathKind
+
+	// directorySources holds the directory options of source paths that are, or may be, rendered as plain
+	// directories. Changes in those paths only affect the application if Argo CD would read the changed file.
+	directorySources map[appPath]directorySource
+}
+
+type directorySource struct {
+	// explicit is true if the source is declared as a directory, rather than detected as one by Argo CD.
+	explicit bool
+	options  v1alpha1.ApplicationSourceDirectory
 }
 
+type appPath struct {
+	app, path string
+}
+
+// PathKind describes how an application uses a directory or file.
+type PathKind string
+
+const (
+	PathKindSource             PathKind = "the source path"
+	PathKindHelmValueFile      PathKind = "a helm value file"
+	PathKindHelmValueFileDir   PathKind = "a directory of helm value files"
+	PathKindHelmFileParameter  PathKind = "a helm file parameter"
+	PathKindHelmDependency     PathKind = "a local helm chart dependency"
+	PathKindKustomizeResource  PathKind = "a kustomize resource"
+	PathKindKustomizeDirectory PathKind = "a kustomize directory"
+	PathKindPluginFile         PathKind = "a file of its config management plugin"
+)
+
 func NewAppDirectory() *AppDirectory {
 	return &AppDirectory{
-		appDirs:  make(map[string][]string),
-		appFiles: make(map[string][]string),
-		appsMap:  make(map[string]v1alpha1.Application),
+		appDirs:   make(map[string][]string),
+		appFiles:  make(map[string][]string),
+		appGlobs:  make(map[string][]string),
+		appsMap:   make(map[string]v1alpha1.Application),
+		pathKinds: make(map[appPath]PathKind),
+
+		directorySources: make(map[appPath]directorySource),
 	}
 }
 
@@ -50,19 +95,24 @@ func (d *AppDirectory) Union(other *AppDirectory) *AppDirectory {
 	join.appsMap = mergeMaps(d.appsMap, other.appsMap, takeFirst[v1alpha1.Application])
 	join.appDirs = mergeMaps(d.appDirs, other.appDirs, mergeLists[string])
 	join.appFiles = mergeMaps(d.appFiles, other.appFiles, mergeLists[string])
+	join.appGlobs = mergeMaps(d.appGlobs, other.appGlobs, mergeLists[string])
+	join.pathKinds = mergeMaps(d.pathKinds, other.pathKinds, takeFirst[PathKind])
+	join.directorySources = mergeMaps(d.directorySources, other.directorySources, takeFirst[directorySource])
 	return &join
 }
 
 // FindAppsBasedOnChangeList receives a list of modified file paths and
-// returns the list of applications that are affected by the changes.
+// returns the list of applications that are affected by the changes,
+// along with the reasons each application was matched, keyed by application name.
 //
 // changeList: a slice of strings representing the paths of modified files.
 // targetBranch: the branch name to compare against the target revision of the applications.
+// sourceFS: the repository the files changed in, used to detect which sources are plain directories. May be nil.
 // e.g. changeList = ["path/to/file1", "path/to/file2"]
-func (d *AppDirectory) FindAppsBasedOnChangeList(changeList []string, targetBranch string) []v1alpha1.Application {
+func (d *AppDirectory) FindAppsBasedOnChangeList(changeList []string, targetBranch string, sourceFS fs.FS) ([]v1alpha1.Application, map[string][]string) {
 	log.Debug().Caller().Msgf("checking %d changes", len(changeList))
 
-	appsSet := make(map[string]struct{})
+	reasons := make(map[string][]string)
 	for _, changePath := range changeList {
 		log.Debug().Caller().Msgf("change: %s", changePath)
 		for dir, appNames := range d.appDirs {
@@ -72,7 +122,16 @@ func (d *AppDirectory) FindAppsBasedOnChangeList(changeList []string, targetBran
 					Str("dir", dir).
 					Msg("dir match!")
 				for _, appName := range appNames {
-					appsSet[appName] = struct{}{}
+					if !d.directoryReads(sourceFS, appName, dir, changePath) {
+						log.Debug().Caller().
+							Str("app", appName).
+							Str("changePath", changePath).
+							Msg("file is not read by the directory source")
+						continue
+					}
+
+					reason := fmt.Sprintf("file `%s` is in `%s`, which is %s of the app", changePath, dir, d.pathKind(appName, dir))
+					reasons[appName] = append(reasons[appName], reason)
 				}
 			}
 		}
@@ -81,30 +140,121 @@ func (d *AppDirectory) FindAppsBasedOnChangeList(changeList []string, targetBran
 		if ok {
 			log.Debug().Caller().Str("changePath", changePath).Msg("file match!")
 			for _, appName := range appNames {
-				appsSet[appName] = struct{}{}
+				reason := fmt.Sprintf("file `%s` is %s of the app", changePath, d.pathKind(appName, changePath))
+				reasons[appName] = append(reasons[appName], reason)
+			}
+		}
+
+		for pattern, appNames := range d.appGlobs {
+			if !cmp.Match(pattern, changePath) {
+				continue
+			}
+
+			log.Debug().Caller().Str("changePath", changePath).Str("pattern", pattern).Msg("glob match!")
+			for _, appName := range appNames {
+				reason := fmt.Sprintf("file `%s` matches `%s`, which is %s", changePath, pattern, d.pathKind(appName, pattern))
+				reasons[appName] = append(reasons[appName], reason)
 			}
 		}
 	}
 
 	var appsSlice []v1alpha1.Application
-	for appName := range appsSet {
+	for appName := range reasons {
 		app, ok := d.appsMap[appName]
 		if !ok {
 			log.Warn().Msgf("failed to find matched app named '%s'", appName)
+			delete(reasons, appName)
 			continue
 		}
 
 		if !shouldInclude(app, targetBranch) {
 			log.Debug().Caller().
 				Msgf("target revision of %s is %s and does not match '%s'", appName, getTargetRevision(app), targetBranch)
+			delete(reasons, appName)
 			continue
 		}
 
+		reasons[appName] = dedupe(reasons[appName])
 		appsSlice = append(appsSlice, app)
 	}
 
-	log.Debug().Caller().Msgf("matched %d files into %d apps", len(changeList), len(appsSet))
-	return appsSlice
+	log.Debug().Caller().Msgf("matched %d files into %d apps", len(changeList), len(appsSlice))
+	return appsSlice, reasons
+}
+
+func (d *AppDirectory) pathKind(appName, path string) PathKind {
+	if kind, ok := d.pathKinds[appPath{appName, path}]; ok {
+		return kind
+	}
+	return PathKindSource
+}
+
+// manifestFile matches the files that Argo CD reads from a directory source.
+var manifestFile = regexp.MustCompile(`^.*\.(yaml|yml|json|jsonnet)$`)
+
+// directoryReads returns false if dir is a directory source of the app, and Argo CD would not read the changed file
+// when rendering it, because of the recurse, include and exclude options of the source.
+func (d *AppDirectory) directoryReads(sourceFS fs.FS, appName, dir, changePath string) bool {
+	src, ok := d.directorySources[appPath{appName, dir}]
+	if !ok {
+		return true
+	}
+
+	if !src.explicit && !isPlainDirectory(sourceFS, dir) {
+		return true
+	}
+
+	relPath, err := filepath.Rel(filepath.Clean(dir), changePath)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, "../") {
+		// a sibling that shares the prefix, e.g. apps/app-10 for apps/app-1
+		return false
+	}
+
+	// anything else may still be imported by jsonnet, so only manifests can be ruled out
+	if !manifestFile.MatchString(filepath.Base(changePath)) {
+		return true
+	}
+
+	if !src.options.Recurse && strings.Contains(relPath, "/") {
+		return false
+	}
+
+	if src.options.Exclude != "" && glob.Match(src.options.Exclude, relPath) {
+		return false
+	}
+
+	if src.options.Include != "" && !glob.Match(src.options.Include, relPath) {
+		return false
+	}
+
+	return true
+}
+
+// isPlainDirectory mirrors how Argo CD detects the type of a source without an explicit one: it is a helm chart or
+// kustomization if the source path contains a Chart.yaml or kustomization file, and a plain directory otherwise.
+// Config management plugins that are discovered by their own rules are not detected.
+func isPlainDirectory(sourceFS fs.FS, dir string) bool {
+	if sourceFS == nil {
+		return false
+	}
+
+	entries, err := fs.ReadDir(sourceFS, filepath.Clean(dir))
+	if err != nil {
+		return false
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasSuffix(name, "Chart.yaml") {
+			return false
+		}
+		switch name {
+		case "kustomization.yaml", "kustomization.yml", "Kustomization":
+			return false
+		}
+	}
+
+	return true
 }
 
 func getTargetRevision(app v1alpha1.Application) string {
@@ -166,18 +316,19 @@ func (d *AppDirectory) AddApp(app v1alpha1.Application) {
 			Msg("add app")
 
 		d.appsMap[app.Name] = app
-		d.addDir(app.Name, sourcePath)
+		d.addDir(app.Name, sourcePath, PathKindSource)
+		d.addDirectorySource(app.Name, src)
 
 		// handle extra helm paths
 		if helm := src.Helm; helm != nil {
 			for _, param := range helm.FileParameters {
 				path := filepath.Join(sourcePath, param.Path)
-				d.addFile(app.Name, path)
+				d.addFile(app.Name, path, PathKindHelmFileParameter)
 			}
 
 			for _, valueFilePath := range helm.ValueFiles {
 				path := filepath.Join(sourcePath, valueFilePath)
-				d.addFile(app.Name, path)
+				d.addFile(app.Name, path, PathKindHelmValueFile)
 			}
 		}
 	}
@@ -191,12 +342,46 @@ func getSources(app v1alpha1.Application) []v1alpha1.ApplicationSource {
 	return app.Spec.Sources
 }
 
-func (d *AppDirectory) addDir(appName, path string) {
+func (d *AppDirectory) addDir(appName, path string, kind PathKind) {
 	d.appDirs[path] = append(d.appDirs[path], appName)
+	d.addPathKind(appName, path, kind)
 }
 
-func (d *AppDirectory) addFile(appName, path string) {
+func (d *AppDirectory) addFile(appName, path string, kind PathKind) {
 	d.appFiles[path] = append(d.appFiles[path], appName)
+	d.addPathKind(appName, path, kind)
+}
+
+// addDirectorySource records the directory options of a source that is, or may be detected as, a plain directory.
+func (d *AppDirectory) addDirectorySource(appName string, src v1alpha1.ApplicationSource) {
+	sourceType, err := src.ExplicitType()
+	if err != nil {
+		return
+	}
+
+	var options v1alpha1.ApplicationSourceDirectory
+	if src.Directory != nil {
+		options = *src.Directory
+	}
+
+	switch {
+	case sourceType == nil:
+		d.directorySources[appPath{appName, src.Path}] = directorySource{options: options}
+	case *sourceType == v1alpha1.ApplicationSourceTypeDirectory:
+		d.directorySources[appPath{appName, src.Path}] = directorySource{explicit: true, options: options}
+	}
+}
+
+func (d *AppDirectory) addGlob(appName, pattern string, kind PathKind) {
+	d.appGlobs[pattern] = append(d.appGlobs[pattern], appName)
+	d.addPathKind(appName, pattern, kind)
+}
+
+func (d *AppDirectory) addPathKind(appName, path string, kind PathKind) {
+	key := appPath{appName, path}
+	if _, ok := d.pathKinds[key]; !ok {
+		d.pathKinds[key] = kind
+	}
 }
 
 func (d *AppDirectory) RemoveApp(app v1alpha1.Application) {
@@ -210,6 +395,25 @@ func (d *AppDirectory) RemoveApp(app v1alpha1.Application) {
 	// remove app from appsMap
 	delete(d.appsMap, app.Name)
 
+	// Clean up app from pathKinds
+	for key := range d.pathKinds {
+		if key.app == app.Name {
+			delete(d.pathKinds, key)
+		}
+	}
+
+	// Clean up app from directorySources
+	for key := range d.directorySources {
+		if key.app == app.Name {
+			delete(d.directorySources, key)
+		}
+	}
+
+	// Clean up app from appGlobs
+	for pattern, appNames := range d.appGlobs {
+		d.appGlobs[pattern] = removeFromSlice[string](appNames, app.Name, func(a, b string) bool { return a == b })
+	}
+
 	// Clean up app from appDirs
 	sourcePath := getSourcePath(app)
 	d.appDirs[sourcePath] = removeFromSlice[string](d.appDirs[sourcePath], app.Name, func(a, b string) bool { return a == b })
@@ -230,8 +434,8 @@ func (d *AppDirectory) RemoveApp(app v1alpha1.Application) {
 	}
 }
 
-func mergeMaps[T any](first map[string]T, second map[string]T, combine func(T, T) T) map[string]T {
-	result := make(map[string]T)
+func mergeMaps[K comparable, T any](first map[K]T, second map[K]T, combine func(T, T) T) map[K]T {
+	result := make(map[K]T)
 	for key, value := range first {
 		result[key] = value
 	}
@@ -254,6 +458,19 @@ func takeFirst[T any](a, _ T) T {
 	return a
 }
 
+func dedupe(items []string) []string {
+	seen := make(map[string]struct{}, len(items))
+	result := items[:0]
+	for _, item := range items {
+		if _, ok := seen[item]; ok {
+			continue
+		}
+		seen[item] = struct{}{}
+		result = append(result, item)
+	}
+	return result
+}
+
 func removeFromSlice[T any](slice []T, element T, equal func(T, T) bool) []T {
 	for i, j := range slice {
 		if equal(j, element) {
@@ -2,11 +2,14 @@ package appdir
 
 import (
 	"fmt"
+	"io/fs"
 	"reflect"
 	"testing"
+	"testing/fstest"
 
 	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -128,3 +131,140 @@ func TestRemoveFromSlice(t *testing.T) {
 	t.Run("Strings", stringsTest)
 	// Add more subtests for different generic types if necessary
 }
+
+func TestFindAppsBasedOnChangeList_Reasons(t *testing.T) {
+	rad := NewAppDirectory()
+	rad.AddApp(v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1"},
+		Spec: v1alpha1.ApplicationSpec{
+			Source: &v1alpha1.ApplicationSource{
+				Path: "apps/app-1",
+				Helm: &v1alpha1.ApplicationSourceHelm{
+					ValueFiles: []string{"../../values/common.yaml"},
+				},
+			},
+		},
+	})
+	rad.AddApp(v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-2"},
+		Spec: v1alpha1.ApplicationSpec{
+			Source: &v1alpha1.ApplicationSource{Path: "apps/app-2", TargetRevision: "staging"},
+		},
+	})
+
+	apps, reasons := rad.FindAppsBasedOnChangeList([]string{
+		"apps/app-1/Chart.yaml",
+		"values/common.yaml",
+		"apps/app-2/deploy.yaml",
+	}, "main", nil)
+
+	require.Len(t, apps, 1)
+	assert.Equal(t, "app-1", apps[0].Name)
+	assert.Equal(t, map[string][]string{
+		"app-1": {
+			"file `apps/app-1/Chart.yaml` is in `apps/app-1`, which is the source path of the app",
+			"file `values/common.yaml` is a helm value file of the app",
+		},
+	}, reasons)
+}
+
+func TestFindAppsBasedOnChangeList_DirectorySources(t *testing.T) {
+	sourceFS := fstest.MapFS{
+		"apps/plain/deploy.yaml":            {},
+		"apps/chart/Chart.yaml":             {},
+		"apps/kustomize/kustomization.yaml": {},
+	}
+
+	newApp := func(path string, helm *v1alpha1.ApplicationSourceHelm, directory *v1alpha1.ApplicationSourceDirectory) v1alpha1.Application {
+		return v1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: "app"},
+			Spec: v1alpha1.ApplicationSpec{
+				Source: &v1alpha1.ApplicationSource{Path: path, Helm: helm, Directory: directory},
+			},
+		}
+	}
+
+	testcases := map[string]struct {
+		app        v1alpha1.Application
+		changePath string
+		noSourceFS bool
+		expected   bool
+	}{
+		"top level manifest": {
+			app:        newApp("apps/plain", nil, nil),
+			changePath: "apps/plain/service.yaml",
+			expected:   true,
+		},
+		"subdirectory without recurse": {
+			app:        newApp("apps/plain", nil, nil),
+			changePath: "apps/plain/sub/service.yaml",
+		},
+		"subdirectory with recurse": {
+			app:        newApp("apps/plain", nil, &v1alpha1.ApplicationSourceDirectory{Recurse: true}),
+			changePath: "apps/plain/sub/service.yaml",
+			expected:   true,
+		},
+		"subdirectory of a detected helm chart": {
+			app:        newApp("apps/chart", nil, nil),
+			changePath: "apps/chart/templates/service.yaml",
+			expected:   true,
+		},
+		"subdirectory of an explicit helm chart": {
+			app:        newApp("apps/plain", &v1alpha1.ApplicationSourceHelm{}, nil),
+			changePath: "apps/plain/templates/service.yaml",
+			expected:   true,
+		},
+		"subdirectory of a detected kustomization": {
+			app:        newApp("apps/kustomize", nil, nil),
+			changePath: "apps/kustomize/base/service.yaml",
+			expected:   true,
+		},
+		"subdirectory without a filesystem to detect the type": {
+			app:        newApp("apps/plain", nil, nil),
+			changePath: "apps/plain/sub/service.yaml",
+			noSourceFS: true,
+			expected:   true,
+		},
+		"excluded manifest": {
+			app:        newApp("apps/plain", nil, &v1alpha1.ApplicationSourceDirectory{Exclude: "{secret.yaml,*-test.yaml}"}),
+			changePath: "apps/plain/app-test.yaml",
+		},
+		"manifest that is not included": {
+			app:        newApp("apps/plain", nil, &v1alpha1.ApplicationSourceDirectory{Recurse: true, Include: "prod/*"}),
+			changePath: "apps/plain/staging/service.yaml",
+		},
+		"included manifest": {
+			app:        newApp("apps/plain", nil, &v1alpha1.ApplicationSourceDirectory{Recurse: true, Include: "prod/*"}),
+			changePath: "apps/plain/prod/service.yaml",
+			expected:   true,
+		},
+		"file that may be imported by jsonnet": {
+			app:        newApp("apps/plain", nil, &v1alpha1.ApplicationSourceDirectory{Include: "main.jsonnet"}),
+			changePath: "apps/plain/lib/utils.libsonnet",
+			expected:   true,
+		},
+		"sibling directory with the same prefix": {
+			app:        newApp("apps/plain", nil, nil),
+			changePath: "apps/plain-2/service.yaml",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			rad := NewAppDirectory()
+			rad.AddApp(tc.app)
+
+			var testFS fs.FS = sourceFS
+			if tc.noSourceFS {
+				testFS = nil
+			}
+
+			apps, _ := rad.FindAppsBasedOnChangeList([]string{tc.changePath}, "main", testFS)
+			if tc.expected {
+				assert.Len(t, apps, 1)
+			} else {
+				assert.Empty(t, apps)
+			}
+		})
+	}
+}
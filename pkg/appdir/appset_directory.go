@@ -2,6 +2,7 @@ package appdir
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -74,17 +75,19 @@ func (d *AppSetDirectory) ProcessAppSet(app v1alpha1.ApplicationSet) {
 }
 
 // FindAppSetsBasedOnChangeList receives the modified file path and
-// returns the list of applications that are affected by the changes.
+// returns the list of application sets that are affected by the changes,
+// along with the reasons each application set was matched, keyed by application set name.
 //
 //	e.g. changeList = ["/appset/httpdump/httpdump.yaml", "/app/testapp/values.yaml"]
 //  if the changed file is application set file, return it.
 
-func (d *AppSetDirectory) FindAppSetsBasedOnChangeList(changeList []string, repo *git.Repo) []v1alpha1.ApplicationSet {
+func (d *AppSetDirectory) FindAppSetsBasedOnChangeList(changeList []string, repo *git.Repo) ([]v1alpha1.ApplicationSet, map[string][]string) {
 	log.Debug().Caller().
 		Str("type", "applicationsets").
 		Msgf("checking %d changes", len(changeList))
 
 	appsSet := make(map[string]struct{})
+	reasons := make(map[string][]string)
 	var appSets []v1alpha1.ApplicationSet
 
 	for _, changePath := range changeList {
@@ -111,6 +114,8 @@ func (d *AppSetDirectory) FindAppSetsBasedOnChangeList(changeList []string, repo
 			continue
 		}
 
+		reasons[appSet.Name] = append(reasons[appSet.Name], fmt.Sprintf("file `%s` defines the appset, and was changed", changePath))
+
 		// Store the unique ApplicationSet
 		if _, exists := appsSet[appSet.Name]; !exists {
 			appsSet[appSet.Name] = struct{}{}
@@ -119,7 +124,7 @@ func (d *AppSetDirectory) FindAppSetsBasedOnChangeList(changeList []string, repo
 	}
 
 	log.Debug().Caller().Str("source", "appset_directory").Msgf("matched %d files into %d appset", len(changeList), len(appSets))
-	return appSets
+	return appSets, reasons
 }
 
 func appSetGetSourcePath(app *v1alpha1.ApplicationSet) string {
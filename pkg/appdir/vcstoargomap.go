@@ -3,10 +3,13 @@ package appdir
 import (
 	"io/fs"
 	"path/filepath"
+	"strings"
 
 	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/rs/zerolog/log"
 	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/cmp"
+	"github.com/zapier/kubechecks/pkg/helm"
 	"github.com/zapier/kubechecks/pkg/kustomize"
 )
 
@@ -78,16 +81,147 @@ func (v2a VcsToArgoMap) WalkKustomizeApps(cloneURL string, rootFS fs.FS) *AppDir
 			log.Error().Err(err).Msgf("failed to parse kustomize.yaml in %s", appPath)
 		}
 		for _, file := range kustomizeFiles {
-			result.addFile(app.Name, file)
+			result.addFile(app.Name, file, PathKindKustomizeResource)
 		}
 		for _, dir := range kustomizeDir {
-			result.addDir(app.Name, dir)
+			result.addDir(app.Name, dir, PathKindKustomizeDirectory)
 		}
 	}
 
 	return result
 }
 
+// WalkHelmApps returns the files and directories that the helm sources of the apps in a repository depend on:
+// local chart dependencies, value files (including globs and $ref value files from multi-source apps) and file parameters.
+// Inline values and valuesObject are not walked: Argo CD hands them to helm as they are, without resolving any paths
+// in them, so they point to no files and a change to them is a change to the Application itself.
+func (v2a VcsToArgoMap) WalkHelmApps(cloneURL string, rootFS fs.FS) *AppDirectory {
+	var (
+		result = NewAppDirectory()
+		appdir = v2a.GetAppsInRepo(cloneURL)
+		apps   = appdir.GetApps(nil)
+	)
+
+	for _, app := range apps {
+		sources := getSources(app)
+
+		refs := make(map[string]v1alpha1.ApplicationSource)
+		for _, src := range sources {
+			if src.Ref != "" {
+				refs[src.Ref] = src
+			}
+		}
+
+		for _, src := range sources {
+			inRepo := pkg.AreSameRepos(src.RepoURL, cloneURL)
+
+			if inRepo && src.Ref == "" && src.Chart == "" {
+				chartDirs, err := helm.ProcessChart(rootFS, src.Path)
+				if err != nil {
+					log.Error().Err(err).Msgf("failed to parse Chart.yaml in %s", src.Path)
+				}
+				for _, dir := range chartDirs {
+					kind := PathKindHelmDependency
+					if dir == filepath.Clean(src.Path) {
+						kind = PathKindSource
+					}
+					result.addDir(app.Name, dir, kind)
+				}
+			}
+
+			if src.Helm == nil {
+				continue
+			}
+
+			paths := make(map[string]PathKind)
+			for _, valueFile := range src.Helm.ValueFiles {
+				paths[valueFile] = PathKindHelmValueFile
+			}
+			for _, param := range src.Helm.FileParameters {
+				paths[param.Path] = PathKindHelmFileParameter
+			}
+
+			for path, kind := range paths {
+				relPath, ok := resolveHelmPath(src, refs, cloneURL, path)
+				if !ok {
+					continue
+				}
+
+				files, dirs, err := helm.ProcessValueFile(rootFS, relPath)
+				if err != nil {
+					log.Error().Err(err).Msgf("failed to process value file %s", path)
+				}
+				for _, file := range files {
+					result.addFile(app.Name, file, kind)
+				}
+				for _, dir := range dirs {
+					result.addDir(app.Name, dir, PathKindHelmValueFileDir)
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// GetPluginApps returns the files that the config management plugin sources of the apps in a repository depend on,
+// as configured per plugin name.
+func (v2a VcsToArgoMap) GetPluginApps(cloneURL string, pluginFiles map[string][]string) *AppDirectory {
+	var (
+		result = NewAppDirectory()
+		appdir = v2a.GetAppsInRepo(cloneURL)
+		apps   = appdir.GetApps(nil)
+	)
+
+	if len(pluginFiles) == 0 {
+		return result
+	}
+
+	for _, app := range apps {
+		for _, src := range getSources(app) {
+			if src.Plugin == nil || !pkg.AreSameRepos(src.RepoURL, cloneURL) {
+				continue
+			}
+
+			for _, pattern := range pluginFiles[src.Plugin.Name] {
+				resolved, ok := cmp.ResolvePattern(src.Path, pattern)
+				if !ok {
+					log.Warn().Str("app", app.Name).Str("pattern", pattern).Msg("ignoring invalid plugin file pattern")
+					continue
+				}
+				result.addGlob(app.Name, resolved, PathKindPluginFile)
+			}
+		}
+	}
+
+	return result
+}
+
+// resolveHelmPath returns the path of a value file or file parameter relative to the root of the repository,
+// or false if the file lives in a different repository.
+func resolveHelmPath(src v1alpha1.ApplicationSource, refs map[string]v1alpha1.ApplicationSource, cloneURL, path string) (string, bool) {
+	if strings.Contains(path, "://") {
+		return "", false
+	}
+
+	if !strings.HasPrefix(path, "$") {
+		return filepath.Join(src.Path, path), pkg.AreSameRepos(src.RepoURL, cloneURL)
+	}
+
+	refName, refPath, err := helm.SplitValueRef(path)
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to parse value file reference")
+		return "", false
+	}
+
+	ref, ok := refs[refName]
+	if !ok || !pkg.AreSameRepos(ref.RepoURL, cloneURL) {
+		return "", false
+	}
+
+	return refPath, true
+}
+
 func (v2a VcsToArgoMap) processApp(app v1alpha1.Application, fn func(*AppDirectory)) {
 
 	if src := app.Spec.Source; src != nil {
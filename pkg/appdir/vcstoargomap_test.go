@@ -2,10 +2,12 @@ package appdir
 
 import (
 	"testing"
+	"testing/fstest"
 
 	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // TestAddApp tests the AddAppSet method from the VcsToArgoMap type.
@@ -184,3 +186,110 @@ func TestVcsToArgoMap_DeleteAppSet(t *testing.T) {
 	assert.Equal(t, appDir.Count(), 1)
 	assert.Equal(t, len(appDir.appSetDirs["test-app-2"]), 0)
 }
+
+func TestVcsToArgoMap_WalkHelmApps(t *testing.T) {
+	const (
+		repoURL   = "https://github.com/zapier/kubechecks.git"
+		otherRepo = "https://github.com/zapier/other.git"
+	)
+
+	rootFS := fstest.MapFS{
+		"charts/app/Chart.yaml":    {Data: []byte("name: app\ndependencies:\n- name: lib\n  repository: file://../lib\n- name: redis\n  repository: https://charts.example.com\n")},
+		"charts/lib/Chart.yaml":    {Data: []byte("name: lib\n")},
+		"values/common.yaml":       {Data: []byte("replicas: 1\n")},
+		"values/envs/prod.yaml":    {Data: []byte("replicas: 3\n")},
+		"values/envs/staging.yaml": {Data: []byte("replicas: 2\n")},
+	}
+
+	v2a := NewVcsToArgoMap("vcs-username")
+	v2a.AddApp(&v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "single-source"},
+		Spec: v1alpha1.ApplicationSpec{
+			Source: &v1alpha1.ApplicationSource{
+				RepoURL: repoURL,
+				Path:    "charts/app",
+				Helm: &v1alpha1.ApplicationSourceHelm{
+					ValueFiles:     []string{"../../values/common.yaml", "../../values/envs/*.yaml"},
+					FileParameters: []v1alpha1.HelmFileParameter{{Name: "config", Path: "files/config.json"}},
+				},
+			},
+		},
+	})
+	v2a.AddApp(&v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "multi-source"},
+		Spec: v1alpha1.ApplicationSpec{
+			Sources: v1alpha1.ApplicationSources{
+				{
+					RepoURL: otherRepo,
+					Path:    "charts/other",
+					Helm: &v1alpha1.ApplicationSourceHelm{
+						ValueFiles: []string{"$values/values/envs/prod.yaml", "values.yaml"},
+					},
+				},
+				{RepoURL: repoURL, Ref: "values"},
+			},
+		},
+	})
+
+	v2a.AddApp(&v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "inline-values"},
+		Spec: v1alpha1.ApplicationSpec{
+			Source: &v1alpha1.ApplicationSource{
+				RepoURL: repoURL,
+				Path:    "charts/lib",
+				Helm: &v1alpha1.ApplicationSourceHelm{
+					Values:       "file: values/common.yaml\n",
+					ValuesObject: &runtime.RawExtension{Raw: []byte(`{"file":"$values/values/envs/staging.yaml"}`)},
+				},
+			},
+		},
+	})
+
+	result := v2a.WalkHelmApps(repoURL, rootFS)
+
+	assert.ElementsMatch(t, []string{"single-source"}, result.appDirs["charts/app"])
+	assert.ElementsMatch(t, []string{"single-source", "inline-values"}, result.appDirs["charts/lib"])
+	assert.ElementsMatch(t, []string{"single-source"}, result.appDirs["values/envs"])
+	assert.ElementsMatch(t, []string{"single-source"}, result.appFiles["values/common.yaml"])
+	assert.ElementsMatch(t, []string{"single-source"}, result.appFiles["values/envs/staging.yaml"])
+	assert.ElementsMatch(t, []string{"single-source"}, result.appFiles["charts/app/files/config.json"])
+	assert.ElementsMatch(t, []string{"single-source", "multi-source"}, result.appFiles["values/envs/prod.yaml"])
+
+	// files in other repositories are not registered
+	assert.NotContains(t, result.appDirs, "charts/other")
+	assert.NotContains(t, result.appFiles, "charts/other/values.yaml")
+
+	// inline values point to no files
+	for _, apps := range result.appFiles {
+		assert.NotContains(t, apps, "inline-values")
+	}
+}
+
+func TestVcsToArgoMap_GetPluginApps(t *testing.T) {
+	const repoURL = "https://github.com/zapier/kubechecks.git"
+
+	v2a := NewVcsToArgoMap("vcs-username")
+	for name, plugin := range map[string]string{"app-1": "jsonnet", "app-2": "jsonnet", "app-3": "cue"} {
+		v2a.AddApp(&v1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1alpha1.ApplicationSpec{
+				Source: &v1alpha1.ApplicationSource{
+					RepoURL: repoURL,
+					Path:    "apps/" + name,
+					Plugin:  &v1alpha1.ApplicationSourcePlugin{Name: plugin},
+				},
+			},
+		})
+	}
+
+	pluginFiles := map[string][]string{"jsonnet": {"jsonnetfile.json", "/lib/**"}}
+	result := v2a.GetPluginApps(repoURL, pluginFiles)
+
+	assert.ElementsMatch(t, []string{"app-1", "app-2"}, result.appGlobs["lib/**"])
+	assert.ElementsMatch(t, []string{"app-1"}, result.appGlobs["apps/app-1/jsonnetfile.json"])
+	assert.ElementsMatch(t, []string{"app-2"}, result.appGlobs["apps/app-2/jsonnetfile.json"])
+
+	apps, reasons := v2a.GetAppsInRepo(repoURL).Union(result).FindAppsBasedOnChangeList([]string{"lib/util/strings.libsonnet"}, "main", nil)
+	assert.Len(t, apps, 2)
+	assert.Equal(t, []string{"file `lib/util/strings.libsonnet` matches `lib/**`, which is a file of its config management plugin"}, reasons["app-1"])
+}
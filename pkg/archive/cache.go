@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -37,6 +38,8 @@ type CacheEntry struct {
 type Config struct {
 	BaseDir string        // Base directory for cache (e.g., /tmp/kubechecks/archives)
 	TTL     time.Duration // Time-to-live for cached archives
+	// LocalFiles allows archives to be read from file:// urls, which only the local vcs client provides
+	LocalFiles bool
 }
 
 // NewCache creates a new archive cache
@@ -49,7 +52,7 @@ func NewCache(cfg Config) *Cache {
 		entries:    make(map[string]*CacheEntry),
 		baseDir:    cfg.BaseDir,
 		ttl:        cfg.TTL,
-		downloader: NewDownloader(),
+		downloader: NewDownloader(cfg.LocalFiles),
 		done:       make(chan struct{}),
 	}
 
@@ -265,11 +268,22 @@ func (c *Cache) startCleanupRoutine() {
 func (c *Cache) cleanupStaleArchives() {
 	log.Debug().Caller().Msg("starting cleanup of stale archives")
 
+	removed, remaining := c.removeUnused(c.ttl)
+	if removed > 0 {
+		log.Info().
+			Int("removed", removed).
+			Int("remaining", remaining).
+			Msg("cleanup completed")
+	}
+}
+
+// removeUnused removes the archives that are not in use and were last used longer than unusedFor ago. It returns how
+// many archives it removed, and how many are left.
+func (c *Cache) removeUnused(unusedFor time.Duration) (removed, remaining int) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	now := time.Now()
-	var removed int
 
 	for key, entry := range c.entries {
 		// Skip if archive is currently in use
@@ -278,7 +292,7 @@ func (c *Cache) cleanupStaleArchives() {
 		}
 
 		// Check if archive is stale
-		if now.Sub(entry.lastUsed) > c.ttl {
+		if now.Sub(entry.lastUsed) > unusedFor {
 			log.Info().
 				Str("path", entry.extractedPath).
 				Dur("unused_for", now.Sub(entry.lastUsed)).
@@ -294,12 +308,43 @@ func (c *Cache) cleanupStaleArchives() {
 		}
 	}
 
-	if removed > 0 {
-		log.Info().
-			Int("removed", removed).
-			Int("remaining", len(c.entries)).
-			Msg("cleanup completed")
+	return removed, len(c.entries)
+}
+
+// CachedArchive describes an archive in the cache.
+type CachedArchive struct {
+	SHA      string    `json:"sha"`
+	Path     string    `json:"path"`
+	LastUsed time.Time `json:"last_used"`
+	// InUse is the number of checks using the archive.
+	InUse int32 `json:"in_use"`
+}
+
+// Entries lists the archives in the cache, the most recently used first.
+func (c *Cache) Entries() []CachedArchive {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	entries := make([]CachedArchive, 0, len(c.entries))
+	for sha, entry := range c.entries {
+		entries = append(entries, CachedArchive{
+			SHA:      sha,
+			Path:     entry.extractedPath,
+			LastUsed: entry.lastUsed,
+			InUse:    atomic.LoadInt32(&entry.refCount),
+		})
 	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries
+}
+
+// Purge removes every archive that is not in use, and returns how many it removed.
+func (c *Cache) Purge() int {
+	removed, _ := c.removeUnused(-1)
+	return removed
 }
 
 // startMetricsUpdateRoutine periodically updates cache metrics
@@ -43,11 +43,19 @@ type Downloader struct {
 	httpClient *http.Client
 }
 
-// NewDownloader creates a new archive downloader
-func NewDownloader() *Downloader {
+// NewDownloader creates a new archive downloader. With localFiles, file:// urls are served from the local
+// filesystem, for archives provided by the local vcs client. It must stay off for remote vcs clients, whose archive
+// urls and redirects would otherwise be able to read any file of the pod.
+func NewDownloader(localFiles bool) *Downloader {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if localFiles {
+		transport.RegisterProtocol("file", http.NewFileTransport(http.Dir("/")))
+	}
+
 	return &Downloader{
 		httpClient: &http.Client{
-			Timeout: 0, // No timeout, let context handle it
+			Timeout:   0, // No timeout, let context handle it
+			Transport: transport,
 		},
 	}
 }
@@ -1,13 +1,17 @@
 package archive
 
 import (
+	"archive/zip"
 	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsRetriableDownloadError(t *testing.T) {
@@ -136,3 +140,34 @@ func TestIsRetriableDownloadError(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadAndExtractLocalFile(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "abc123.zip")
+	f, err := os.Create(archivePath)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	_, err = w.Create("repo-abc123/")
+	require.NoError(t, err)
+	fw, err := w.Create("repo-abc123/app.yaml")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte("kind: ConfigMap"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+
+	targetDir := t.TempDir()
+	extracted, err := NewDownloader(true).DownloadAndExtract(context.Background(), "file://"+archivePath, targetDir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(targetDir, "repo-abc123"), extracted)
+
+	data, err := os.ReadFile(filepath.Join(extracted, "app.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "kind: ConfigMap", string(data))
+
+	_, err = NewDownloader(true).DownloadAndExtract(context.Background(), "file:///does/not/exist.zip", t.TempDir(), nil)
+	var httpErr *HTTPError
+	assert.ErrorAs(t, err, &httpErr)
+
+	_, err = NewDownloader(false).DownloadAndExtract(context.Background(), "file://"+archivePath, t.TempDir(), nil)
+	assert.Error(t, err, "remote vcs clients can't read local files")
+}
@@ -36,6 +36,8 @@ func NewManager(cfg config.ServerConfig, vcsClient vcs.Client) *Manager {
 	cacheConfig := Config{
 		BaseDir: cfg.ArchiveCacheDir,
 		TTL:     cfg.ArchiveCacheTTL,
+		// archives of remote vcs clients must never be read from the filesystem of the pod
+		LocalFiles: vcsClient.GetName() == "local",
 	}
 
 	return &Manager{
@@ -139,6 +141,16 @@ func (m *Manager) Shutdown() {
 	m.cache.Shutdown()
 }
 
+// CacheEntries lists the archives in the cache.
+func (m *Manager) CacheEntries() []CachedArchive {
+	return m.cache.Entries()
+}
+
+// PurgeCache removes every cached archive that is not in use, and returns how many it removed.
+func (m *Manager) PurgeCache() int {
+	return m.cache.Purge()
+}
+
 // Release releases a reference to an archive
 func (m *Manager) Release(cloneURL, mergeCommitSHA string) {
 	m.cache.Release(cloneURL, mergeCommitSHA)
@@ -304,6 +316,18 @@ func (m *Manager) PostArchiveErrorMessage(ctx context.Context, pr vcs.PullReques
 // - GitHub: https://github.com/owner/repo/archive/{sha}.zip
 // - GitLab: https://gitlab.com/api/v4/projects/{encoded}/repository/archive.zip?sha={ref}
 func extractSHAFromArchiveURL(archiveURL string) (string, error) {
+	// Local archives (local vcs client): file:///path/to/{sha}.zip
+	if strings.HasPrefix(archiveURL, "file://") {
+		filename := filepath.Base(archiveURL)
+		sha := strings.TrimSuffix(filename, filepath.Ext(filename))
+
+		if sha == "" || sha == "." || sha == "/" {
+			return "", fmt.Errorf("empty SHA extracted from archive URL: %s", archiveURL)
+		}
+
+		return sha, nil
+	}
+
 	// Try GitHub format first: /archive/{sha}.zip or /archive/{sha}.tar.gz
 	if strings.Contains(archiveURL, "/archive/") {
 		// Extract filename from URL path
@@ -31,6 +31,18 @@ func TestExtractSHAFromArchiveURL(t *testing.T) {
 			wantSHA: "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2",
 		},
 
+		// Local formats
+		{
+			name:    "Local file",
+			url:     "file:///tmp/archives/abc123def456.zip",
+			wantSHA: "abc123def456",
+		},
+		{
+			name:    "Local file in an archive directory",
+			url:     "file:///srv/archive/abc123def456.zip",
+			wantSHA: "abc123def456",
+		},
+
 		// GitLab formats
 		{
 			name:    "GitLab sha as first query param",
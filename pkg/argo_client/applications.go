@@ -29,6 +29,10 @@ func (a *ArgoClient) GetApplicationByName(ctx context.Context, name string) (*v1
 	ctx, span := tracer.Start(ctx, "GetApplicationByName")
 	defer span.End()
 
+	if a.IsOffline() {
+		return a.getOfflineApplicationByName(name)
+	}
+
 	closer, appClient := a.GetApplicationClient()
 	defer pkg.WithErrorLogging(closer.Close, "failed to close connection")
 
@@ -41,6 +45,37 @@ func (a *ArgoClient) GetApplicationByName(ctx context.Context, name string) (*v1
 	return resp, nil
 }
 
+// GetManagedResources returns the resources that Argo CD manages for an application, with their live and target
+// states. There is no live state in offline mode, so there are no managed resources either.
+func (a *ArgoClient) GetManagedResources(ctx context.Context, app v1alpha1.Application) ([]*v1alpha1.ResourceDiff, error) {
+	ctx, span := tracer.Start(ctx, "GetManagedResources")
+	defer span.End()
+
+	if a.IsOffline() {
+		return nil, nil
+	}
+
+	release, err := a.AcquireCluster(ctx, app.Spec.Destination)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	closer, appClient := a.GetApplicationClient()
+	defer pkg.WithErrorLogging(closer.Close, "failed to close application connection")
+
+	resources, err := appClient.ManagedResources(ctx, &application.ResourcesQuery{
+		ApplicationName: &app.Name,
+		AppNamespace:    &app.Namespace,
+	})
+	if err != nil {
+		telemetry.SetError(span, err, "Argo Get Managed Resources error")
+		return nil, err
+	}
+
+	return resources.Items, nil
+}
+
 // GetKubernetesVersionByApplication is a method on the ArgoClient struct that takes a context and an application name as parameters,
 // and returns the Kubernetes version of the destination cluster where the specified application is running.
 // It returns an error if the application or cluster information cannot be retrieved.
@@ -48,6 +83,11 @@ func (a *ArgoClient) GetKubernetesVersionByApplication(ctx context.Context, app
 	ctx, span := tracer.Start(ctx, "GetKubernetesVersionByApplicationName")
 	defer span.End()
 
+	// there is no cluster to ask, callers fall back to the configured default version
+	if a.IsOffline() {
+		return "", ErrNoVersionFound
+	}
+
 	// Get destination cluster
 	// Some app specs have a Name defined, some have a Server defined, some have both, take a valid one and use it
 	log.Debug().Caller().Msgf("for appname %s, server dest says: %s and name dest says: %s", app.Name, app.Spec.Destination.Server, app.Spec.Destination.Name)
@@ -90,6 +130,10 @@ func (a *ArgoClient) GetApplicationsByLabels(ctx context.Context, labels string)
 	ctx, span := tracer.Start(ctx, "GetApplicationsByLabels")
 	defer span.End()
 
+	if a.IsOffline() {
+		return a.getOfflineApplicationsByLabels(labels)
+	}
+
 	closer, appClient := a.GetApplicationClient()
 	defer pkg.WithErrorLogging(closer.Close, "failed to close connection")
 
@@ -113,6 +157,14 @@ func (a *ArgoClient) GetApplications(ctx context.Context) (*v1alpha1.Application
 	ctx, span := tracer.Start(ctx, "GetApplications")
 	defer span.End()
 
+	if a.IsOffline() {
+		offline, err := a.getOfflineApps()
+		if err != nil {
+			return nil, err
+		}
+		return offline.apps.DeepCopy(), nil
+	}
+
 	closer, appClient := a.GetApplicationClient()
 	defer pkg.WithErrorLogging(closer.Close, "failed to close connection")
 
@@ -128,6 +180,14 @@ func (a *ArgoClient) GetApplicationSets(ctx context.Context) (*v1alpha1.Applicat
 	ctx, span := tracer.Start(ctx, "GetApplications")
 	defer span.End()
 
+	if a.IsOffline() {
+		offline, err := a.getOfflineApps()
+		if err != nil {
+			return nil, err
+		}
+		return offline.appSets.DeepCopy(), nil
+	}
+
 	closer, appClient := a.GetApplicationSetClient()
 	defer pkg.WithErrorLogging(closer.Close, "failed to close connection")
 
@@ -1,13 +1,16 @@
 package argo_client
 
 import (
+	"context"
 	"crypto/tls"
 	"io"
+	"path/filepath"
 
 	"github.com/argoproj/argo-cd/v3/pkg/apiclient"
 	"github.com/argoproj/argo-cd/v3/pkg/apiclient/application"
 	"github.com/argoproj/argo-cd/v3/pkg/apiclient/applicationset"
 	"github.com/argoproj/argo-cd/v3/pkg/apiclient/settings"
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	repoapiclient "github.com/argoproj/argo-cd/v3/reposerver/apiclient"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
@@ -20,6 +23,8 @@ import (
 	"github.com/argoproj/argo-cd/v3/pkg/apiclient/cluster"
 
 	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/limiter"
+	"github.com/zapier/kubechecks/pkg/manifestcache"
 )
 
 type ArgoClient struct {
@@ -27,12 +32,56 @@ type ArgoClient struct {
 	k8s       kubernetes.Interface
 	k8sConfig *rest.Config
 	cfg       config.ServerConfig
+
+	// repoServerLimit bounds the manifest generation calls to the repo server, clusterLimit the live state calls
+	// for each destination cluster.
+	repoServerLimit *limiter.Semaphore
+	clusterLimit    *limiter.Keyed
+
+	// manifestCache is shared by the clients of every Argo CD instance, and is nil when rendered manifests are not
+	// cached
+	manifestCache *manifestcache.Cache
+
+	// version is shared with the copies made by WithApplications
+	version *repoServerVersion
+
+	// offline is only set when Applications and ApplicationSets are read from files instead of the Argo CD API.
+	offline *offlineApps
 }
 
 func NewArgoClient(
 	cfg config.ServerConfig,
 	k8s client.Interface,
+	manifestCache *manifestcache.Cache,
 ) (*ArgoClient, error) {
+	a := &ArgoClient{
+		cfg:             cfg,
+		repoServerLimit: limiter.New("repo-server", cfg.ArgoCDRepositoryEndpoint, cfg.MaxRepoServerCalls),
+		clusterLimit:    limiter.NewKeyed("cluster", cfg.MaxClusterCalls),
+		manifestCache:   manifestCache,
+		version:         &repoServerVersion{},
+	}
+
+	if k8s != nil {
+		a.k8s = k8s.ClientSet()
+		a.k8sConfig = k8s.Config()
+	}
+
+	if a.IsOffline() {
+		log.Info().Str("path", cfg.ArgoCDOfflineAppsPath).Msg("ArgoCD client running in offline mode")
+
+		// relative paths are resolved inside the repository under check, so there is nothing to load yet
+		if filepath.IsAbs(cfg.ArgoCDOfflineAppsPath) {
+			apps, appSets, err := LoadApplications(cfg.ArgoCDOfflineAppsPath)
+			if err != nil {
+				return nil, err
+			}
+			a.offline = &offlineApps{apps: apps, appSets: appSets}
+		}
+
+		return a, nil
+	}
+
 	opts := &apiclient.ClientOptions{
 		ServerAddr:      cfg.ArgoCDServerAddr,
 		AuthToken:       cfg.ArgoCDToken,
@@ -52,13 +101,9 @@ func NewArgoClient(
 	if err != nil {
 		return nil, err
 	}
+	a.client = argo
 
-	return &ArgoClient{
-		cfg:       cfg,
-		client:    argo,
-		k8s:       k8s.ClientSet(),
-		k8sConfig: k8s.Config(),
-	}, nil
+	return a, nil
 }
 
 func (a *ArgoClient) createRepoServerClient() (repoapiclient.RepoServerServiceClient, *grpc.ClientConn, error) {
@@ -75,6 +120,16 @@ func (a *ArgoClient) createRepoServerClient() (repoapiclient.RepoServerServiceCl
 	return repoapiclient.NewRepoServerServiceClient(conn), conn, nil
 }
 
+// AcquireCluster waits until a live state call may be made to the destination cluster of an app, and returns the
+// function that must be called once it is done.
+func (a *ArgoClient) AcquireCluster(ctx context.Context, destination v1alpha1.ApplicationDestination) (func(), error) {
+	key := destination.Server
+	if key == "" {
+		key = destination.Name
+	}
+	return a.clusterLimit.Acquire(ctx, key)
+}
+
 // GetApplicationClient has related argocd diff code https://github.com/argoproj/argo-cd/blob/d3ff9757c460ae1a6a11e1231251b5d27aadcdd1/cmd/argocd/commands/app.go#L899
 func (a *ArgoClient) GetApplicationClient() (io.Closer, application.ApplicationServiceClient) {
 	closer, appClient, err := a.client.NewApplicationClient()
@@ -0,0 +1,237 @@
+package argo_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	argohelm "github.com/argoproj/argo-cd/v3/util/helm"
+	"github.com/argoproj/argo-cd/v3/util/versions"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zapier/kubechecks/pkg/helmchart"
+	"github.com/zapier/kubechecks/pkg/kustomize"
+	"github.com/zapier/kubechecks/pkg/manifestcache"
+)
+
+// renderCached renders a packaged source, unless manifests rendered from the same inputs are in the manifest cache.
+func (a *ArgoClient) renderCached(ctx context.Context, renderer manifestRenderer, req renderRequest) ([]string, error) {
+	if a.manifestCache == nil {
+		return renderer.renderManifests(ctx, req)
+	}
+
+	// in full repository mode, the files of $ref sources are not part of the package, so changes to them would be missed
+	if a.cfg.ArgoCDSendFullRepository && len(req.refs) > 0 {
+		return renderer.renderManifests(ctx, req)
+	}
+
+	// the packaged files don't tell when inputs that are fetched while rendering change
+	if input, err := unpinnedInput(req); err != nil || input != "" {
+		log.Debug().Caller().Err(err).Str("app", req.app.Name).Str("input", input).Msg("source fetches inputs that may change, rendering without the cache")
+		return renderer.renderManifests(ctx, req)
+	}
+
+	key, err := a.manifestCacheKey(ctx, renderer, req)
+	if err != nil {
+		log.Warn().Err(err).Str("app", req.app.Name).Msg("failed to compute manifest cache key, rendering without the cache")
+		return renderer.renderManifests(ctx, req)
+	}
+
+	if manifests, ok := a.manifestCache.Get(key); ok {
+		log.Debug().Caller().Str("app", req.app.Name).Str("key", key).Msg("using cached manifests")
+		return manifests, nil
+	}
+
+	manifests, err := renderer.renderManifests(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = a.manifestCache.Put(key, manifests); err != nil {
+		log.Warn().Err(err).Str("app", req.app.Name).Msg("failed to cache manifests")
+	}
+	return manifests, nil
+}
+
+// manifestCacheKey hashes everything rendering a packaged source depends on: the packaged files, the manifest request,
+// which holds the helm and kustomize parameters and the kubernetes version of the destination cluster, and the
+// version of the renderer.
+func (a *ArgoClient) manifestCacheKey(ctx context.Context, renderer manifestRenderer, req renderRequest) (string, error) {
+	version, err := renderer.version(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var exclude []string
+	if !a.cfg.ArgoCDIncludeDotGit {
+		exclude = append(exclude, ".git")
+	}
+	files, err := manifestcache.HashDir(req.packageDir, exclude)
+	if err != nil {
+		return "", err
+	}
+
+	request, err := json.Marshal(req.request)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode manifest request")
+	}
+
+	return manifestcache.Key([]byte(version), []byte(files), request), nil
+}
+
+// unpinnedInput describes an input of a source that is fetched while rendering, and may change while it is referred
+// to the same way: a helm chart by a version range or an OCI tag, or a remote kustomize base on a branch or tag. It
+// returns "" when there is none.
+func unpinnedInput(req renderRequest) (string, error) {
+	source := req.source
+	if source.IsHelm() && !pinnedChart(source.RepoURL, source.TargetRevision) {
+		return fmt.Sprintf("helm chart %s %s", source.Chart, source.TargetRevision), nil
+	}
+	if source.IsOCI() && !source.IsHelm() && !pinnedOCI(source.TargetRevision) {
+		return fmt.Sprintf("oci artifact %s", source.TargetRevision), nil
+	}
+
+	appPath := filepath.Join(req.packageDir, source.Path)
+
+	// helm builds dependencies from the versions in Chart.lock when there is one
+	for _, name := range []string{"Chart.lock", "Chart.yaml"} {
+		if _, err := os.Stat(filepath.Join(appPath, name)); err != nil {
+			continue
+		}
+
+		dependencies, err := helmchart.ParseDependencies(filepath.Join(appPath, name))
+		if err != nil {
+			return "", err
+		}
+		for _, dependency := range dependencies {
+			if dependency.Repository == "" || strings.HasPrefix(dependency.Repository, "file://") {
+				continue
+			}
+			if !pinnedChart(dependency.Repository, dependency.Version) {
+				return fmt.Sprintf("helm dependency %s %s", dependency.Name, dependency.Version), nil
+			}
+		}
+		break
+	}
+
+	relKustPath := filepath.Join(source.Path, "kustomization.yaml")
+	if _, err := os.Stat(filepath.Join(req.packageDir, relKustPath)); err == nil {
+		resources, charts, err := kustomize.RemoteResources(os.DirFS(req.packageDir), relKustPath)
+		if err != nil {
+			return "", err
+		}
+		for _, resource := range resources {
+			if !pinnedRemoteBase(resource) {
+				return fmt.Sprintf("kustomize resource %s", resource), nil
+			}
+		}
+		for _, chart := range charts {
+			if !pinnedChart(chart.Repo, chart.Version) {
+				return fmt.Sprintf("kustomize helm chart %s %s", chart.Name, chart.Version), nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// pinnedChart reports whether a helm chart always resolves to the same chart: an exact version from a chart
+// repository, or a digest from an OCI registry, whose tags can be moved.
+func pinnedChart(repoURL, version string) bool {
+	if strings.HasPrefix(repoURL, "oci://") || argohelm.IsHelmOciRepo(repoURL) {
+		return pinnedOCI(version)
+	}
+	return versions.IsVersion(version)
+}
+
+func pinnedOCI(revision string) bool {
+	return strings.Contains(revision, "sha256:")
+}
+
+var commitSHA = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// pinnedRemoteBase reports whether a remote kustomize resource refers to a commit, rather than a branch or tag.
+func pinnedRemoteBase(resource string) bool {
+	_, rawQuery, ok := strings.Cut(resource, "?")
+	if !ok {
+		return false
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return false
+	}
+
+	ref := query.Get("ref")
+	if ref == "" {
+		ref = query.Get("version")
+	}
+	return commitSHA.MatchString(ref)
+}
+
+// repoServerVersionTTL is how long the version of the repo server is remembered, so that upgrades are noticed.
+const repoServerVersionTTL = time.Minute
+
+// repoServerVersion remembers the version of the repo server once it has been looked up.
+type repoServerVersion struct {
+	mu        sync.Mutex
+	value     string
+	checkedAt time.Time
+}
+
+// repoServerVersion identifies the repo server by the images its running pods run, which pin the version of Argo CD
+// and of the helm and kustomize binaries it ships, or of the custom image that replaced them. It is looked up again
+// after repoServerVersionTTL.
+func (a *ArgoClient) repoServerVersion(ctx context.Context) (string, error) {
+	if a.k8s == nil || a.version == nil {
+		return "", errors.New("the repo server version is unknown without a kubernetes client")
+	}
+	if a.cfg.ArgoCDRepositorySelector == "" {
+		return "", errors.New("the repo server version is unknown without a repo server selector")
+	}
+
+	a.version.mu.Lock()
+	defer a.version.mu.Unlock()
+
+	if a.version.value != "" && time.Since(a.version.checkedAt) < repoServerVersionTTL {
+		return a.version.value, nil
+	}
+
+	pods, err := a.k8s.CoreV1().Pods(a.cfg.ArgoCDNamespace).List(ctx, metav1.ListOptions{LabelSelector: a.cfg.ArgoCDRepositorySelector})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list repo server pods")
+	}
+
+	var images []string
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			image := status.ImageID
+			if image == "" {
+				image = status.Image
+			}
+			images = append(images, image)
+		}
+	}
+	if len(images) == 0 {
+		return "", errors.Errorf("no running repo server pods match %q in %q", a.cfg.ArgoCDRepositorySelector, a.cfg.ArgoCDNamespace)
+	}
+
+	slices.Sort(images)
+	a.version.value = strings.Join(slices.Compact(images), ",")
+	a.version.checkedAt = time.Now()
+	return a.version.value, nil
+}
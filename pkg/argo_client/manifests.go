@@ -19,10 +19,10 @@ import (
 	"github.com/argoproj/argo-cd/v3/util/argo"
 	"github.com/argoproj/argo-cd/v3/util/db"
 	argosettings "github.com/argoproj/argo-cd/v3/util/settings"
-	"github.com/argoproj/argo-cd/v3/util/tgzstream"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/cmp"
 	"github.com/zapier/kubechecks/pkg/git"
 	"github.com/zapier/kubechecks/pkg/kustomize"
 	"github.com/zapier/kubechecks/pkg/vcs"
@@ -109,28 +109,21 @@ func (a *ArgoClient) generateManifests(ctx context.Context, app v1alpha1.Applica
 	// 2. there must be one and only one non-ref source
 	// 3. ref sources that match the pull requests' repo and target branch need to have their target branch swapped to the head branch of the pull request
 	log.Info().Str("app", app.Name).Msg("generating manifests")
-	clusterCloser, clusterClient := a.GetClusterClient()
-	defer pkg.WithErrorLogging(clusterCloser.Close, "failed to close connection")
 
-	clusterData, err := clusterClient.Get(ctx, &cluster.ClusterQuery{Name: app.Spec.Destination.Name, Server: app.Spec.Destination.Server})
-	if err != nil {
-		getManifestsFailed.WithLabelValues(app.Name).Inc()
-		return nil, errors.Wrap(err, "failed to get cluster")
+	var (
+		ms  manifestSettings
+		err error
+	)
+	if a.IsOffline() {
+		err = a.setOfflineManifestSettings(ctx, app, &ms)
+	} else {
+		err = a.setManifestSettings(ctx, app, &ms)
 	}
-
-	settingsCloser, settingsClient := a.GetSettingsClient()
-	defer pkg.WithErrorLogging(settingsCloser.Close, "failed to close connection")
-
-	log.Debug().Caller().Str("app", app.Name).Msg("get settings")
-	argoSettings, err := settingsClient.Get(ctx, &settings.SettingsQuery{})
 	if err != nil {
 		getManifestsFailed.WithLabelValues(app.Name).Inc()
-		return nil, errors.Wrap(err, "failed to get settings")
+		return nil, err
 	}
 
-	settingsMgr := argosettings.NewSettingsManager(ctx, a.k8s, a.cfg.ArgoCDNamespace)
-	argoDB := db.NewDB(a.cfg.ArgoCDNamespace, settingsMgr, a.k8s)
-
 	repoTarget := source.TargetRevision
 	if pkg.AreSameRepos(source.RepoURL, pullRequest.CloneURL) && areSameTargetRef(source.TargetRevision, pullRequest.BaseRef) {
 		repoTarget = pullRequest.HeadRef
@@ -148,139 +141,157 @@ func (a *ArgoClient) generateManifests(ctx context.Context, app v1alpha1.Applica
 		packageDir = repo.Directory
 	} else {
 		log.Debug().Caller().Str("app", app.Name).Msg("packaging app")
-		packageDir, err = packageApp(ctx, source, refs, repo, getRepo)
+		packageDir, err = packageApp(ctx, source, refs, repo, getRepo, a.pluginFiles(source))
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to package application")
 		}
 	}
 
-	log.Debug().Caller().Str("app", app.Name).Msg("compressing files")
+	app.Spec.Sources = append([]v1alpha1.ApplicationSource{source}, refs...)
+
+	q := repoapiclient.ManifestRequest{
+		Repo:               &v1alpha1.Repository{Repo: source.RepoURL},
+		Revision:           source.TargetRevision,
+		AppLabelKey:        ms.appLabelKey,
+		AppName:            app.Name,
+		Namespace:          app.Spec.Destination.Namespace,
+		ApplicationSource:  &source,
+		Repos:              ms.helmRepos,
+		KustomizeOptions:   ms.kustomizeOptions,
+		KubeVersion:        ms.kubeVersion,
+		ApiVersions:        ms.apiVersions,
+		HelmRepoCreds:      ms.helmRepoCreds,
+		HelmOptions:        ms.helmOptions,
+		TrackingMethod:     ms.trackingMethod,
+		EnabledSourceTypes: ms.enabledSourceTypes,
+		ProjectName:        ms.projectName,
+		ProjectSourceRepos: ms.projectSourceRepos,
+		HasMultipleSources: app.Spec.HasMultipleSources(),
+		RefSources:         ms.refSources,
+
+		// config management plugins only receive these paths when the repo server is configured to use them
+		AnnotationManifestGeneratePaths: app.GetAnnotation(v1alpha1.AnnotationKeyManifestGeneratePaths),
+	}
+
+	renderer, err := a.rendererFor(app)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.renderCached(ctx, renderer, renderRequest{
+		app:        app,
+		source:     source,
+		refs:       refs,
+		packageDir: packageDir,
+		request:    &q,
+		getRepo:    getRepo,
+	})
+}
 
-	exclude := []string{}
-	if !a.cfg.ArgoCDIncludeDotGit {
-		exclude = append(exclude, ".git")
+// pluginFiles returns the configured glob patterns of the files a config management plugin source depends on.
+func (a *ArgoClient) pluginFiles(source v1alpha1.ApplicationSource) []string {
+	if source.Plugin == nil {
+		return nil
+	}
+	return a.cfg.CMPPluginFileGlobs[source.Plugin.Name]
+}
+
+// manifestSettings holds everything the repo server needs to know about the destination cluster,
+// the project and the Argo CD settings in order to render an app.
+type manifestSettings struct {
+	appLabelKey        string
+	trackingMethod     string
+	kustomizeOptions   *v1alpha1.KustomizeOptions
+	kubeVersion        string
+	apiVersions        []string
+	helmRepos          []*v1alpha1.Repository
+	helmRepoCreds      []*v1alpha1.RepoCreds
+	helmOptions        *v1alpha1.HelmOptions
+	enabledSourceTypes map[string]bool
+	projectName        string
+	projectSourceRepos []string
+	refSources         v1alpha1.RefTargetRevisionMapping
+}
+
+// setManifestSettings fills in the manifest settings from the Argo CD API and the Argo CD configuration in the cluster.
+func (a *ArgoClient) setManifestSettings(ctx context.Context, app v1alpha1.Application, ms *manifestSettings) error {
+	clusterCloser, clusterClient := a.GetClusterClient()
+	defer pkg.WithErrorLogging(clusterCloser.Close, "failed to close connection")
+
+	clusterData, err := clusterClient.Get(ctx, &cluster.ClusterQuery{Name: app.Spec.Destination.Name, Server: app.Spec.Destination.Server})
+	if err != nil {
+		return errors.Wrap(err, "failed to get cluster")
 	}
 
-	f, filesWritten, checksum, err := tgzstream.CompressFiles(packageDir, []string{"*"}, exclude)
+	settingsCloser, settingsClient := a.GetSettingsClient()
+	defer pkg.WithErrorLogging(settingsCloser.Close, "failed to close connection")
+
+	log.Debug().Caller().Str("app", app.Name).Msg("get settings")
+	argoSettings, err := settingsClient.Get(ctx, &settings.SettingsQuery{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to compress files: %w", err)
+		return errors.Wrap(err, "failed to get settings")
 	}
-	log.Debug().Caller().Str("app", app.Name).Msgf("%d files compressed", filesWritten)
-	//if filesWritten == 0 {
-	//	return nil, fmt.Errorf("no files to send")
-	//}
+
+	settingsMgr := argosettings.NewSettingsManager(ctx, a.k8s, a.cfg.ArgoCDNamespace)
+	argoDB := db.NewDB(a.cfg.ArgoCDNamespace, settingsMgr, a.k8s)
 
 	closer, projectClient, err := a.client.NewProjectClient()
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get project client")
+		return errors.Wrap(err, "failed to get project client")
 	}
 	defer pkg.WithErrorLogging(closer.Close, "failed to close connection")
 
 	proj, err := projectClient.Get(ctx, &project.ProjectQuery{Name: app.Spec.Project})
 	if err != nil {
-		return nil, fmt.Errorf("error getting app project: %w", err)
+		return fmt.Errorf("error getting app project: %w", err)
 	}
 
 	helmRepos, err := argoDB.ListHelmRepositories(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error listing helm repositories: %w", err)
+		return fmt.Errorf("error listing helm repositories: %w", err)
 	}
 	permittedHelmRepos, err := argo.GetPermittedRepos(proj, helmRepos)
 	if err != nil {
-		return nil, fmt.Errorf("error retrieving permitted repos: %w", err)
+		return fmt.Errorf("error retrieving permitted repos: %w", err)
 	}
 	helmRepositoryCredentials, err := argoDB.GetAllHelmRepositoryCredentials(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("error getting helm repository credentials: %w", err)
+		return fmt.Errorf("error getting helm repository credentials: %w", err)
 	}
 	helmOptions, err := settingsMgr.GetHelmSettings()
 	if err != nil {
-		return nil, fmt.Errorf("error getting helm settings: %w", err)
+		return fmt.Errorf("error getting helm settings: %w", err)
 	}
 	permittedHelmCredentials, err := argo.GetPermittedReposCredentials(proj, helmRepositoryCredentials)
 	if err != nil {
-		return nil, fmt.Errorf("error getting permitted repos credentials: %w", err)
+		return fmt.Errorf("error getting permitted repos credentials: %w", err)
 	}
 	enabledSourceTypes, err := settingsMgr.GetEnabledSourceTypes()
 	if err != nil {
-		return nil, fmt.Errorf("error getting settings enabled source types: %w", err)
+		return fmt.Errorf("error getting settings enabled source types: %w", err)
 	}
 
 	refSources, err := argo.GetRefSources(context.Background(), app.Spec.Sources, app.Spec.Project, argoDB.GetRepository, []string{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get ref sources: %w", err)
-	}
-
-	app.Spec.Sources = append([]v1alpha1.ApplicationSource{source}, refs...)
-
-	q := repoapiclient.ManifestRequest{
-		Repo:               &v1alpha1.Repository{Repo: source.RepoURL},
-		Revision:           source.TargetRevision,
-		AppLabelKey:        argoSettings.AppLabelKey,
-		AppName:            app.Name,
-		Namespace:          app.Spec.Destination.Namespace,
-		ApplicationSource:  &source,
-		Repos:              permittedHelmRepos,
-		KustomizeOptions:   argoSettings.KustomizeOptions,
-		KubeVersion:        clusterData.Info.ServerVersion,
-		ApiVersions:        clusterData.Info.APIVersions,
-		HelmRepoCreds:      permittedHelmCredentials,
-		HelmOptions:        helmOptions,
-		TrackingMethod:     argoSettings.TrackingMethod,
-		EnabledSourceTypes: enabledSourceTypes,
-		ProjectName:        proj.Name,
-		ProjectSourceRepos: proj.Spec.SourceRepos,
-		HasMultipleSources: app.Spec.HasMultipleSources(),
-		RefSources:         refSources,
-	}
-
-	// creating a new client forces grpc to create a new connection, which causes
-	//the k8s load balancer to select a new pod, balancing requests among all repo-server pods.
-	repoClient, conn, err := a.createRepoServerClient()
-	if err != nil {
-		return nil, errors.Wrap(err, "error creating repo client")
-	}
-	defer pkg.WithErrorLogging(conn.Close, "failed to close connection")
-
-	log.Debug().Caller().Str("app", app.Name).Msg("generating manifest with files")
-	stream, err := repoClient.GenerateManifestWithFiles(ctx)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get manifests with files")
-	}
-	defer pkg.WithErrorLogging(stream.CloseSend, "failed to close stream")
-
-	log.Debug().Caller().Str("app", app.Name).Msg("sending request to repo server")
-	if err := stream.Send(&repoapiclient.ManifestRequestWithFiles{
-		Part: &repoapiclient.ManifestRequestWithFiles_Request{
-			Request: &q,
-		},
-	}); err != nil {
-		return nil, errors.Wrap(err, "failed to send request")
+		return fmt.Errorf("failed to get ref sources: %w", err)
 	}
 
-	log.Debug().Caller().Str("app", app.Name).Msg("sending metadata to repo server")
-	if err := stream.Send(&repoapiclient.ManifestRequestWithFiles{
-		Part: &repoapiclient.ManifestRequestWithFiles_Metadata{
-			Metadata: &repoapiclient.ManifestFileMetadata{
-				Checksum: checksum,
-			},
-		},
-	}); err != nil {
-		return nil, errors.Wrap(err, "failed to send metadata")
+	*ms = manifestSettings{
+		appLabelKey:        argoSettings.AppLabelKey,
+		trackingMethod:     argoSettings.TrackingMethod,
+		kustomizeOptions:   argoSettings.KustomizeOptions,
+		kubeVersion:        clusterData.Info.ServerVersion,
+		apiVersions:        clusterData.Info.APIVersions,
+		helmRepos:          permittedHelmRepos,
+		helmRepoCreds:      permittedHelmCredentials,
+		helmOptions:        helmOptions,
+		enabledSourceTypes: enabledSourceTypes,
+		projectName:        proj.Name,
+		projectSourceRepos: proj.Spec.SourceRepos,
+		refSources:         refSources,
 	}
 
-	err = sendFile(ctx, stream, f)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send manifest stream file: %w", err)
-	}
-
-	response, err := stream.CloseAndRecv()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get response")
-	}
-
-	log.Debug().Caller().Str("app", app.Name).Msg("finished generating manifests")
-	return response.Manifests, nil
+	return nil
 }
 
 func copyDir(fs filesys.FileSystem, src, dst string) error {
@@ -408,13 +419,15 @@ func parseChartYAML(chartPath string) ([]struct {
 }
 
 // packageApp packages an Argo CD application source and its dependencies into a temporary directory.
-// It copies the source files and processes both Kustomize and Helm dependencies.
+// It copies the source files and processes Kustomize and Helm dependencies, and the files matching pluginFiles
+// for config management plugin sources.
 func packageApp(
 	ctx context.Context,
 	source v1alpha1.ApplicationSource,
 	refs []v1alpha1.ApplicationSource,
 	repo *git.Repo,
 	getRepo getRepo,
+	pluginFiles []string,
 ) (string, error) {
 	destDir, err := os.MkdirTemp("", "package-*")
 	if err != nil {
@@ -448,6 +461,19 @@ func packageApp(
 		}
 	}
 
+	// Process config management plugin files
+	if source.Plugin != nil {
+		files, err := cmp.ProcessPatterns(sourceFS, source.Path, pluginFiles)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to process config management plugin files")
+		}
+		for _, file := range files {
+			if err := addFile(repo.Directory, destDir, file); err != nil {
+				return "", errors.Wrap(err, "failed to add file")
+			}
+		}
+	}
+
 	// Process helm dependencies
 	if source.Helm != nil {
 		// Handle local helm dependencies from Chart.yaml
@@ -186,8 +186,39 @@ func TestPackageApp(t *testing.T) {
 		pullRequest            vcs.PullRequest
 		filesByRepo            map[repoTarget]set[string]
 		filesByRepoWithContent map[repoTarget]map[string]string
+		pluginFiles            []string
 		expectedFiles          map[string]repoTargetPath
 	}{
+		"cmp-plugin-files-are-copied": {
+			app: v1alpha1.Application{
+				Spec: v1alpha1.ApplicationSpec{
+					Source: &v1alpha1.ApplicationSource{
+						RepoURL:        "git@github.com:testuser/testrepo.git",
+						Path:           "apps/app1",
+						TargetRevision: "main",
+						Plugin:         &v1alpha1.ApplicationSourcePlugin{Name: "jsonnet"},
+					},
+				},
+			},
+			pluginFiles: []string{"../jsonnetfile.json", "/lib/**"},
+			filesByRepo: map[repoTarget]set[string]{
+				repoTarget{"git@github.com:testuser/testrepo.git", "main"}: newSet[string](
+					"apps/app1/main.jsonnet",
+					"apps/jsonnetfile.json",
+					"apps/app2/main.jsonnet",
+					"lib/k8s.libsonnet",
+					"lib/util/strings.libsonnet",
+					"vendor/unused.libsonnet",
+				),
+			},
+			expectedFiles: map[string]repoTargetPath{
+				"apps/app1/main.jsonnet":     {"git@github.com:testuser/testrepo.git", "main", "apps/app1/main.jsonnet"},
+				"apps/jsonnetfile.json":      {"git@github.com:testuser/testrepo.git", "main", "apps/jsonnetfile.json"},
+				"lib/k8s.libsonnet":          {"git@github.com:testuser/testrepo.git", "main", "lib/k8s.libsonnet"},
+				"lib/util/strings.libsonnet": {"git@github.com:testuser/testrepo.git", "main", "lib/util/strings.libsonnet"},
+			},
+		},
+
 		"unused-paths-are-ignored": {
 			app: v1alpha1.Application{
 				Spec: v1alpha1.ApplicationSpec{
@@ -528,7 +559,7 @@ dependencies:
 			require.NoError(t, err)
 
 			// FUNCTION UNDER TEST: package the app
-			path, err := packageApp(ctx, source, refs, repo, getRepo)
+			path, err := packageApp(ctx, source, refs, repo, getRepo, tc.pluginFiles)
 			require.NoError(t, err)
 
 			// ensure that only the expected files were copied
@@ -0,0 +1,208 @@
+package argo_client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/common"
+	"github.com/argoproj/argo-cd/v3/pkg/apiclient/settings"
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v3/util/argo"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/zapier/kubechecks/pkg"
+)
+
+// offlineApps holds the Applications and ApplicationSets that are used instead of the Argo CD API in offline mode.
+type offlineApps struct {
+	apps    v1alpha1.ApplicationList
+	appSets v1alpha1.ApplicationSetList
+}
+
+// IsOffline returns true when Applications and ApplicationSets are read from files rather than the Argo CD API.
+// There is no live state in offline mode, so every resource is treated as new.
+func (a *ArgoClient) IsOffline() bool {
+	return a.cfg.ArgoCDOfflineAppsPath != ""
+}
+
+// WithApplications returns a copy of the client that serves the given Applications and ApplicationSets,
+// e.g. the ones that were loaded from the repository being checked.
+func (a *ArgoClient) WithApplications(apps v1alpha1.ApplicationList, appSets v1alpha1.ApplicationSetList) *ArgoClient {
+	clone := *a
+	clone.offline = &offlineApps{apps: apps, appSets: appSets}
+	return &clone
+}
+
+// OfflineSettings returns the Argo CD settings that are assumed when there is no Argo CD server to ask.
+func OfflineSettings() *settings.Settings {
+	return &settings.Settings{
+		AppLabelKey:    common.LabelKeyAppInstance,
+		TrackingMethod: string(v1alpha1.TrackingMethodLabel),
+	}
+}
+
+// HasApplications returns true when offline Applications and ApplicationSets have been loaded.
+func (a *ArgoClient) HasApplications() bool {
+	return a.offline != nil
+}
+
+func (a *ArgoClient) getOfflineApps() (*offlineApps, error) {
+	if a.offline == nil {
+		return nil, errors.New("offline applications have not been loaded")
+	}
+	return a.offline, nil
+}
+
+func (a *ArgoClient) getOfflineApplicationByName(name string) (*v1alpha1.Application, error) {
+	offline, err := a.getOfflineApps()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, app := range offline.apps.Items {
+		if app.Name == name {
+			return app.DeepCopy(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to retrieve the application: %s not found", name)
+}
+
+func (a *ArgoClient) getOfflineApplicationsByLabels(selector string) (*v1alpha1.ApplicationList, error) {
+	offline, err := a.getOfflineApps()
+	if err != nil {
+		return nil, err
+	}
+
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse label selector")
+	}
+
+	var result v1alpha1.ApplicationList
+	for _, app := range offline.apps.Items {
+		if sel.Matches(labels.Set(app.Labels)) {
+			result.Items = append(result.Items, app)
+		}
+	}
+
+	return &result, nil
+}
+
+// setOfflineManifestSettings fills in a manifest request with defaults, instead of the cluster, project and settings
+// that would otherwise come from the Argo CD API.
+func (a *ArgoClient) setOfflineManifestSettings(ctx context.Context, app v1alpha1.Application, q *manifestSettings) error {
+	argoSettings := OfflineSettings()
+
+	q.appLabelKey = argoSettings.AppLabelKey
+	q.trackingMethod = argoSettings.TrackingMethod
+	q.kubeVersion = a.cfg.FallbackK8sVersion
+	q.projectName = app.Spec.Project
+	q.projectSourceRepos = []string{"*"}
+
+	refSources, err := argo.GetRefSources(ctx, app.Spec.Sources, app.Spec.Project, func(_ context.Context, url string, _ string) (*v1alpha1.Repository, error) {
+		return &v1alpha1.Repository{Repo: url}, nil
+	}, []string{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get ref sources")
+	}
+	q.refSources = refSources
+
+	return nil
+}
+
+// LoadApplications reads every Application and ApplicationSet from the YAML and JSON files in dir and its subdirectories.
+// Other kinds of objects are ignored, so the directory can be a regular Argo CD config repository.
+func LoadApplications(dir string) (v1alpha1.ApplicationList, v1alpha1.ApplicationSetList, error) {
+	var (
+		apps    v1alpha1.ApplicationList
+		appSets v1alpha1.ApplicationSetList
+	)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		return loadApplicationsFromFile(path, &apps, &appSets)
+	})
+	if err != nil {
+		return apps, appSets, errors.Wrapf(err, "failed to load applications from %s", dir)
+	}
+
+	log.Info().
+		Str("path", dir).
+		Int("applications", len(apps.Items)).
+		Int("applicationsets", len(appSets.Items)).
+		Msg("loaded offline applications")
+
+	return apps, appSets, nil
+}
+
+func loadApplicationsFromFile(path string, apps *v1alpha1.ApplicationList, appSets *v1alpha1.ApplicationSetList) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to open file")
+	}
+	defer pkg.WithErrorLogging(f.Close, "failed to close file")
+
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(f))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err = yaml.Unmarshal(doc, &typeMeta); err != nil {
+			// not every yaml file in a repository is a kubernetes object
+			log.Debug().Caller().Err(err).Str("path", path).Msg("skipping document")
+			continue
+		}
+
+		if !strings.HasPrefix(typeMeta.APIVersion, "argoproj.io/") {
+			continue
+		}
+
+		switch typeMeta.Kind {
+		case "Application":
+			var app v1alpha1.Application
+			if err = yaml.Unmarshal(doc, &app); err != nil {
+				return errors.Wrapf(err, "failed to parse application in %s", path)
+			}
+			apps.Items = append(apps.Items, app)
+		case "ApplicationSet":
+			var appSet v1alpha1.ApplicationSet
+			if err = yaml.Unmarshal(doc, &appSet); err != nil {
+				return errors.Wrapf(err, "failed to parse application set in %s", path)
+			}
+			appSets.Items = append(appSets.Items, appSet)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package argo_client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zapier/kubechecks/pkg/config"
+)
+
+const offlineManifests = `
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: app-1
+  labels:
+    argocd.argoproj.io/application-set-name: appset-1
+spec:
+  source:
+    repoURL: https://github.com/zapier/kubechecks.git
+    path: apps/app-1
+---
+apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: appset-1
+spec:
+  template:
+    spec:
+      source:
+        repoURL: https://github.com/zapier/kubechecks.git
+        path: apps/{{name}}
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-an-app
+`
+
+func writeOfflineFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	return dir
+}
+
+func TestLoadApplications(t *testing.T) {
+	dir := writeOfflineFiles(t, map[string]string{
+		"apps/all.yaml":    offlineManifests,
+		"apps/app-2.yml":   "apiVersion: argoproj.io/v1alpha1\nkind: Application\nmetadata:\n  name: app-2\n",
+		"apps/README.md":   "apiVersion: argoproj.io/v1alpha1\nkind: Application\n",
+		"values.yaml":      "replicas: 3\n",
+		".git/config.yaml": "apiVersion: argoproj.io/v1alpha1\nkind: Application\nmetadata:\n  name: ignored\n",
+	})
+
+	apps, appSets, err := LoadApplications(dir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, app := range apps.Items {
+		names = append(names, app.Name)
+	}
+	assert.ElementsMatch(t, []string{"app-1", "app-2"}, names)
+
+	require.Len(t, appSets.Items, 1)
+	assert.Equal(t, "appset-1", appSets.Items[0].Name)
+}
+
+func TestLoadApplications_InvalidApplication(t *testing.T) {
+	dir := writeOfflineFiles(t, map[string]string{
+		"app.yaml": "apiVersion: argoproj.io/v1alpha1\nkind: Application\nspec: [1, 2]\n",
+	})
+
+	_, _, err := LoadApplications(dir)
+	assert.Error(t, err)
+}
+
+func TestOfflineArgoClient(t *testing.T) {
+	ctx := context.Background()
+	dir := writeOfflineFiles(t, map[string]string{"apps.yaml": offlineManifests})
+
+	a, err := NewArgoClient(config.ServerConfig{ArgoCDOfflineAppsPath: dir}, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, a.IsOffline())
+	assert.True(t, a.HasApplications())
+
+	apps, err := a.GetApplications(ctx)
+	require.NoError(t, err)
+	assert.Len(t, apps.Items, 1)
+
+	appSets, err := a.GetApplicationSets(ctx)
+	require.NoError(t, err)
+	assert.Len(t, appSets.Items, 1)
+
+	byAppSet, err := a.GetApplicationsByAppset(ctx, "appset-1")
+	require.NoError(t, err)
+	require.Len(t, byAppSet.Items, 1)
+	assert.Equal(t, "app-1", byAppSet.Items[0].Name)
+
+	byAppSet, err = a.GetApplicationsByAppset(ctx, "appset-2")
+	require.NoError(t, err)
+	assert.Empty(t, byAppSet.Items)
+
+	app, err := a.GetApplicationByName(ctx, "app-1")
+	require.NoError(t, err)
+	assert.Equal(t, "apps/app-1", app.Spec.Source.Path)
+
+	_, err = a.GetApplicationByName(ctx, "missing")
+	assert.Error(t, err)
+
+	_, err = a.GetKubernetesVersionByApplication(ctx, *app)
+	assert.ErrorIs(t, err, ErrNoVersionFound)
+}
+
+func TestOfflineArgoClient_RelativePath(t *testing.T) {
+	ctx := context.Background()
+
+	a, err := NewArgoClient(config.ServerConfig{ArgoCDOfflineAppsPath: "deploy/argocd"}, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, a.IsOffline())
+	assert.False(t, a.HasApplications())
+
+	_, err = a.GetApplications(ctx)
+	assert.Error(t, err)
+
+	apps, appSets, err := LoadApplications(writeOfflineFiles(t, map[string]string{"apps.yaml": offlineManifests}))
+	require.NoError(t, err)
+
+	withApps := a.WithApplications(apps, appSets)
+	assert.True(t, withApps.HasApplications())
+	assert.False(t, a.HasApplications(), "the original client must not be modified")
+
+	result, err := withApps.GetApplications(ctx)
+	require.NoError(t, err)
+	assert.Len(t, result.Items, 1)
+}
@@ -0,0 +1,222 @@
+package argo_client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	repoapiclient "github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+	"github.com/argoproj/argo-cd/v3/reposerver/repository"
+	argogit "github.com/argoproj/argo-cd/v3/util/git"
+	argohelm "github.com/argoproj/argo-cd/v3/util/helm"
+	utilio "github.com/argoproj/argo-cd/v3/util/io"
+	argokustomize "github.com/argoproj/argo-cd/v3/util/kustomize"
+	"github.com/argoproj/argo-cd/v3/util/tgzstream"
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	"github.com/zapier/kubechecks/pkg"
+)
+
+const (
+	// RendererRepoServer sends the packaged app to the Argo CD repo server.
+	RendererRepoServer = "repo-server"
+	// RendererLocal renders the packaged app inside the kubechecks process. It needs the helm and kustomize
+	// binaries on the PATH, but no Argo CD at all.
+	RendererLocal = "local"
+
+	// RendererAnnotation overrides the configured manifest renderer for a single application, with one of the
+	// renderers the operator allowed with manifest-renderer-overrides.
+	RendererAnnotation = "kubechecks.io/manifest-renderer"
+)
+
+// localMaxCombinedManifestsSize matches the default of the repo server's --max-combined-directory-manifests-size.
+var localMaxCombinedManifestsSize = resource.MustParse("10M")
+
+// renderRequest is a single source of an application, packaged and ready to be rendered.
+type renderRequest struct {
+	app        v1alpha1.Application
+	source     v1alpha1.ApplicationSource
+	refs       []v1alpha1.ApplicationSource
+	packageDir string
+	request    *repoapiclient.ManifestRequest
+	getRepo    getRepo
+}
+
+// manifestRenderer turns a packaged application source into kubernetes manifests.
+type manifestRenderer interface {
+	renderManifests(ctx context.Context, req renderRequest) ([]string, error)
+	// version identifies the renderer and its version, so that manifests it rendered are not reused by another one.
+	version(ctx context.Context) (string, error)
+}
+
+// rendererFor returns the renderer for an app, taking the per-app annotation into account. Apps are changed by pull
+// requests, so the annotation may only select the renderers the operator allowed.
+func (a *ArgoClient) rendererFor(app v1alpha1.Application) (manifestRenderer, error) {
+	name := a.cfg.ManifestRenderer
+	if name == "" {
+		name = RendererRepoServer
+	}
+	if override, ok := app.Annotations[RendererAnnotation]; ok && override != name {
+		if !slices.Contains(a.cfg.RendererOverrides, override) {
+			return nil, fmt.Errorf("manifest renderer %q of the %s annotation is not allowed by manifest-renderer-overrides", override, RendererAnnotation)
+		}
+		name = override
+	}
+
+	switch name {
+	case RendererRepoServer:
+		return &repoServerRenderer{a}, nil
+	case RendererLocal:
+		return &localRenderer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown manifest renderer: %q", name)
+	}
+}
+
+type repoServerRenderer struct {
+	a *ArgoClient
+}
+
+func (r *repoServerRenderer) renderManifests(ctx context.Context, req renderRequest) ([]string, error) {
+	app := req.app
+
+	log.Debug().Caller().Str("app", app.Name).Msg("compressing files")
+
+	exclude := []string{}
+	if !r.a.cfg.ArgoCDIncludeDotGit {
+		exclude = append(exclude, ".git")
+	}
+
+	f, filesWritten, checksum, err := tgzstream.CompressFiles(req.packageDir, []string{"*"}, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress files: %w", err)
+	}
+	log.Debug().Caller().Str("app", app.Name).Msgf("%d files compressed", filesWritten)
+	//if filesWritten == 0 {
+	//	return nil, fmt.Errorf("no files to send")
+	//}
+
+	release, err := r.a.repoServerLimit.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// creating a new client forces grpc to create a new connection, which causes
+	//the k8s load balancer to select a new pod, balancing requests among all repo-server pods.
+	repoClient, conn, err := r.a.createRepoServerClient()
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating repo client")
+	}
+	defer pkg.WithErrorLogging(conn.Close, "failed to close connection")
+
+	log.Debug().Caller().Str("app", app.Name).Msg("generating manifest with files")
+	stream, err := repoClient.GenerateManifestWithFiles(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get manifests with files")
+	}
+	defer pkg.WithErrorLogging(stream.CloseSend, "failed to close stream")
+
+	log.Debug().Caller().Str("app", app.Name).Msg("sending request to repo server")
+	if err := stream.Send(&repoapiclient.ManifestRequestWithFiles{
+		Part: &repoapiclient.ManifestRequestWithFiles_Request{
+			Request: req.request,
+		},
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to send request")
+	}
+
+	log.Debug().Caller().Str("app", app.Name).Msg("sending metadata to repo server")
+	if err := stream.Send(&repoapiclient.ManifestRequestWithFiles{
+		Part: &repoapiclient.ManifestRequestWithFiles_Metadata{
+			Metadata: &repoapiclient.ManifestFileMetadata{
+				Checksum: checksum,
+			},
+		},
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to send metadata")
+	}
+
+	err = sendFile(ctx, stream, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send manifest stream file: %w", err)
+	}
+
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get response")
+	}
+
+	log.Debug().Caller().Str("app", app.Name).Msg("finished generating manifests")
+	return response.Manifests, nil
+}
+
+func (r *repoServerRenderer) version(ctx context.Context) (string, error) {
+	version, err := r.a.repoServerVersion(ctx)
+	if err != nil {
+		return "", err
+	}
+	return RendererRepoServer + "/" + version, nil
+}
+
+// localRenderer runs the same helm, kustomize and directory rendering as the repo server, in process.
+type localRenderer struct{}
+
+func (r *localRenderer) renderManifests(ctx context.Context, req renderRequest) ([]string, error) {
+	app := req.app
+
+	// most $ref value files have been copied into the package already, but the full repository mode leaves them
+	// in place. repos are cached by getRepo, so looking them up again is cheap.
+	refPaths := utilio.NewRandomizedTempPaths(os.TempDir())
+	for _, ref := range req.refs {
+		refRepo, err := req.getRepo(ctx, ref.RepoURL, ref.TargetRevision)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to clone repo: %q", ref.RepoURL)
+		}
+		refPaths.Add(argogit.NormalizeGitURL(ref.RepoURL), refRepo.Directory)
+	}
+
+	appPath := filepath.Join(req.packageDir, req.source.Path)
+
+	log.Debug().Caller().Str("app", app.Name).Str("path", appPath).Msg("rendering manifests locally")
+	response, err := repository.GenerateManifests(
+		ctx, appPath, req.packageDir, req.request.Revision, req.request, true,
+		argogit.NoopCredsStore{}, localMaxCombinedManifestsSize, refPaths,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render manifests")
+	}
+
+	log.Debug().Caller().Str("app", app.Name).Msg("finished generating manifests")
+	return response.Manifests, nil
+}
+
+// version is the version of kubechecks, which the rendering code is compiled into, and of the helm and kustomize
+// binaries it runs.
+func (r *localRenderer) version(context.Context) (string, error) {
+	helmVersion, kustomizeVersion := localToolVersions()
+	return strings.Join([]string{RendererLocal, pkg.GitTag, pkg.GitCommit, helmVersion, kustomizeVersion}, "/"), nil
+}
+
+// localToolVersions looks up the versions of the helm and kustomize binaries once. A binary that is missing has no
+// version, rendering sources that need it fails anyway.
+var localToolVersions = sync.OnceValues(func() (string, string) {
+	helmVersion, err := argohelm.Version()
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to get the helm version")
+	}
+
+	kustomizeVersion, err := argokustomize.Version()
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to get the kustomize version")
+	}
+
+	return helmVersion, kustomizeVersion
+})
@@ -0,0 +1,279 @@
+package argo_client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	repoapiclient "github.com/argoproj/argo-cd/v3/reposerver/apiclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/git"
+	"github.com/zapier/kubechecks/pkg/manifestcache"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func TestRendererFor(t *testing.T) {
+	testcases := map[string]struct {
+		configured string
+		overrides  []string
+		annotation *string
+		expected   manifestRenderer
+		expectErr  bool
+	}{
+		"default":               {expected: &repoServerRenderer{}},
+		"repo server":           {configured: RendererRepoServer, expected: &repoServerRenderer{}},
+		"local":                 {configured: RendererLocal, expected: &localRenderer{}},
+		"annotation override":   {configured: RendererRepoServer, overrides: []string{RendererLocal}, annotation: strPtr(RendererLocal), expected: &localRenderer{}},
+		"override not allowed":  {configured: RendererRepoServer, annotation: strPtr(RendererLocal), expectErr: true},
+		"configured annotation": {annotation: strPtr(RendererRepoServer), expected: &repoServerRenderer{}},
+		"unknown annotation":    {overrides: []string{RendererLocal}, annotation: strPtr("helm"), expectErr: true},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			a := &ArgoClient{cfg: config.ServerConfig{ManifestRenderer: tc.configured, RendererOverrides: tc.overrides}}
+
+			var app v1alpha1.Application
+			if tc.annotation != nil {
+				app.Annotations = map[string]string{RendererAnnotation: *tc.annotation}
+			}
+
+			renderer, err := a.rendererFor(app)
+			if tc.expectErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.IsType(t, tc.expected, renderer)
+		})
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestLocalRenderer_Directory(t *testing.T) {
+	ctx := context.Background()
+
+	repoDir := writeOfflineFiles(t, map[string]string{
+		"apps/app-1/deployment.yaml": "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app-1\n",
+		"apps/app-1/service.yaml":    "apiVersion: v1\nkind: Service\nmetadata:\n  name: app-1\n",
+		"apps/app-2/configmap.yaml":  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app-2\n",
+	})
+
+	a, err := NewArgoClient(config.ServerConfig{
+		ArgoCDOfflineAppsPath: "argocd",
+		ManifestRenderer:      RendererLocal,
+		FallbackK8sVersion:    "1.30.0",
+	}, nil, nil)
+	require.NoError(t, err)
+
+	app := v1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "app-1"},
+		Spec: v1alpha1.ApplicationSpec{
+			Source: &v1alpha1.ApplicationSource{
+				RepoURL:        "https://github.com/zapier/kubechecks.git",
+				Path:           "apps/app-1",
+				TargetRevision: "main",
+			},
+			Destination: v1alpha1.ApplicationDestination{Namespace: "default"},
+		},
+	}
+
+	pullRequest := vcs.PullRequest{CloneURL: "https://github.com/zapier/kubechecks.git", BaseRef: "main", HeadRef: "feature"}
+	getRepo := func(ctx context.Context, cloneURL string, branchName string) (*git.Repo, error) {
+		assert.Equal(t, "feature", branchName)
+		return &git.Repo{CloneURL: cloneURL, Directory: repoDir, BranchName: branchName}, nil
+	}
+
+	manifests, err := a.GetManifests(ctx, app.Name, app, pullRequest, getRepo)
+	require.NoError(t, err)
+	require.Len(t, manifests, 2)
+
+	var kinds []string
+	for _, manifest := range manifests {
+		var obj struct {
+			Kind     string `json:"kind"`
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(manifest), &obj))
+		kinds = append(kinds, obj.Kind)
+		assert.Equal(t, "app-1", obj.Metadata.Labels[OfflineSettings().AppLabelKey])
+	}
+	assert.ElementsMatch(t, []string{"Deployment", "Service"}, kinds)
+}
+
+type countingRenderer struct {
+	calls int
+}
+
+func (r *countingRenderer) renderManifests(context.Context, renderRequest) ([]string, error) {
+	r.calls++
+	return []string{"kind: ConfigMap"}, nil
+}
+
+func (r *countingRenderer) version(context.Context) (string, error) {
+	return "counting", nil
+}
+
+func TestRenderCached(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := manifestcache.NewCache(manifestcache.Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+	a := &ArgoClient{manifestCache: cache}
+	renderer := &countingRenderer{}
+
+	packageDir := writeOfflineFiles(t, map[string]string{"app/values.yaml": "replicas: 1"})
+	req := renderRequest{
+		packageDir: packageDir,
+		request:    &repoapiclient.ManifestRequest{AppName: "app", KubeVersion: "1.30"},
+	}
+
+	for i := 0; i < 2; i++ {
+		manifests, err := a.renderCached(ctx, renderer, req)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"kind: ConfigMap"}, manifests)
+	}
+	assert.Equal(t, 1, renderer.calls, "unchanged sources are rendered once")
+
+	req.request = &repoapiclient.ManifestRequest{AppName: "app", KubeVersion: "1.31"}
+	_, err = a.renderCached(ctx, renderer, req)
+	require.NoError(t, err)
+	assert.Equal(t, 2, renderer.calls, "another kubernetes version is rendered again")
+
+	require.NoError(t, os.WriteFile(filepath.Join(packageDir, "app/values.yaml"), []byte("replicas: 2"), 0o644))
+	_, err = a.renderCached(ctx, renderer, req)
+	require.NoError(t, err)
+	assert.Equal(t, 3, renderer.calls, "changed files are rendered again")
+}
+
+func TestRenderCached_UnpinnedInputs(t *testing.T) {
+	ctx := context.Background()
+
+	cache, err := manifestcache.NewCache(manifestcache.Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+	a := &ArgoClient{manifestCache: cache}
+	renderer := &countingRenderer{}
+
+	packageDir := writeOfflineFiles(t, map[string]string{
+		"app/kustomization.yaml": "resources:\n- https://github.com/zapier/kubechecks//deploy?ref=main\n",
+	})
+	req := renderRequest{
+		source:     v1alpha1.ApplicationSource{Path: "app"},
+		packageDir: packageDir,
+		request:    &repoapiclient.ManifestRequest{AppName: "app", KubeVersion: "1.30"},
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := a.renderCached(ctx, renderer, req)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, renderer.calls, "sources with remote bases on a branch are always rendered")
+}
+
+func TestUnpinnedInput(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+
+	testcases := map[string]struct {
+		source   v1alpha1.ApplicationSource
+		files    map[string]string
+		unpinned bool
+	}{
+		"plain directory": {
+			files: map[string]string{"app/deployment.yaml": "kind: Deployment"},
+		},
+		"exact chart version": {
+			source: v1alpha1.ApplicationSource{RepoURL: "https://charts.example.com", Chart: "redis", TargetRevision: "1.2.3"},
+		},
+		"chart version range": {
+			source:   v1alpha1.ApplicationSource{RepoURL: "https://charts.example.com", Chart: "redis", TargetRevision: "1.2.*"},
+			unpinned: true,
+		},
+		"oci chart tag": {
+			source:   v1alpha1.ApplicationSource{RepoURL: "registry.example.com/charts", Chart: "redis", TargetRevision: "1.2.3"},
+			unpinned: true,
+		},
+		"oci chart digest": {
+			source: v1alpha1.ApplicationSource{RepoURL: "oci://registry.example.com/charts", Chart: "redis", TargetRevision: "1.2.3@sha256:abc"},
+		},
+		"local chart dependency": {
+			files: map[string]string{"app/Chart.yaml": "dependencies:\n- name: common\n  repository: file://../common\n  version: '*'\n"},
+		},
+		"chart dependency range": {
+			files:    map[string]string{"app/Chart.yaml": "dependencies:\n- name: redis\n  repository: https://charts.example.com\n  version: ^1.2.0\n"},
+			unpinned: true,
+		},
+		"locked chart dependency": {
+			files: map[string]string{
+				"app/Chart.yaml": "dependencies:\n- name: redis\n  repository: https://charts.example.com\n  version: ^1.2.0\n",
+				"app/Chart.lock": "dependencies:\n- name: redis\n  repository: https://charts.example.com\n  version: 1.2.3\n",
+			},
+		},
+		"remote base on a branch": {
+			files:    map[string]string{"app/kustomization.yaml": "resources:\n- github.com/zapier/kubechecks/deploy?ref=main\n"},
+			unpinned: true,
+		},
+		"remote base on a commit": {
+			files: map[string]string{"app/kustomization.yaml": "resources:\n- github.com/zapier/kubechecks/deploy?ref=" + sha + "\n"},
+		},
+		"kustomize chart": {
+			files:    map[string]string{"app/kustomization.yaml": "helmCharts:\n- name: redis\n  repo: oci://registry.example.com/charts\n  version: 1.2.3\n"},
+			unpinned: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			tc.source.Path = "app"
+			input, err := unpinnedInput(renderRequest{source: tc.source, packageDir: writeOfflineFiles(t, tc.files)})
+			require.NoError(t, err)
+			assert.Equal(t, tc.unpinned, input != "", input)
+		})
+	}
+}
+
+func TestRepoServerVersion(t *testing.T) {
+	ctx := context.Background()
+
+	pod := func(name, imageID string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "argocd", Labels: map[string]string{"app.kubernetes.io/name": "argocd-repo-server"}},
+			Status: corev1.PodStatus{
+				Phase:             phase,
+				ContainerStatuses: []corev1.ContainerStatus{{Image: "argocd:v3.2.11", ImageID: imageID}},
+			},
+		}
+	}
+
+	k8s := fake.NewClientset(
+		pod("repo-server-1", "argocd@sha256:aaa", corev1.PodRunning),
+		pod("repo-server-2", "argocd@sha256:aaa", corev1.PodRunning),
+		pod("repo-server-3", "argocd@sha256:bbb", corev1.PodPending),
+	)
+	a := &ArgoClient{
+		k8s:     k8s,
+		cfg:     config.ServerConfig{ArgoCDNamespace: "argocd", ArgoCDRepositorySelector: "app.kubernetes.io/name=argocd-repo-server"},
+		version: &repoServerVersion{},
+	}
+
+	version, err := a.repoServerVersion(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "argocd@sha256:aaa", version)
+
+	a = &ArgoClient{k8s: fake.NewClientset(), cfg: a.cfg, version: &repoServerVersion{}}
+	_, err = a.repoServerVersion(ctx)
+	assert.Error(t, err, "the version is unknown without running pods")
+}
@@ -0,0 +1,40 @@
+package pkg
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ArgoCDInstanceAnnotation records which Argo CD instance an affected application or application set was found in,
+// when kubechecks talks to more than one.
+const ArgoCDInstanceAnnotation = "kubechecks.io/argocd-instance"
+
+// ArgoCDInstanceOf returns the name of the Argo CD instance an object was found in, or "" for the default one.
+func ArgoCDInstanceOf(meta metav1.ObjectMeta) string {
+	return meta.Annotations[ArgoCDInstanceAnnotation]
+}
+
+// WithArgoCDInstance returns a copy of the metadata that records the instance, without modifying the original
+// annotations, which may be shared with the cached app.
+func WithArgoCDInstance(meta metav1.ObjectMeta, instance string) metav1.ObjectMeta {
+	if instance == "" {
+		return meta
+	}
+
+	annotations := make(map[string]string, len(meta.Annotations)+1)
+	for key, value := range meta.Annotations {
+		annotations[key] = value
+	}
+	annotations[ArgoCDInstanceAnnotation] = instance
+	meta.Annotations = annotations
+
+	return meta
+}
+
+// QualifiedName returns the name of an object prefixed with its Argo CD instance, so that apps with the same name
+// in different instances can be told apart. Objects of the default instance keep their plain name.
+func QualifiedName(meta metav1.ObjectMeta) string {
+	if instance := ArgoCDInstanceOf(meta); instance != "" {
+		return instance + "/" + meta.Name
+	}
+	return meta.Name
+}
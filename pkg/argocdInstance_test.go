@@ -0,0 +1,22 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWithArgoCDInstance(t *testing.T) {
+	original := metav1.ObjectMeta{Name: "app-1", Annotations: map[string]string{"a": "b"}}
+
+	assert.Equal(t, "app-1", QualifiedName(original))
+	assert.Equal(t, "", ArgoCDInstanceOf(original))
+	assert.Equal(t, original, WithArgoCDInstance(original, ""))
+
+	tagged := WithArgoCDInstance(original, "us-east")
+	assert.Equal(t, "us-east", ArgoCDInstanceOf(tagged))
+	assert.Equal(t, "us-east/app-1", QualifiedName(tagged))
+	assert.Equal(t, "b", tagged.Annotations["a"])
+	assert.NotContains(t, original.Annotations, ArgoCDInstanceAnnotation, "the original annotations must not be modified")
+}
@@ -0,0 +1,182 @@
+package checkcache
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/pkg/errors"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/manifestcache"
+)
+
+// Inputs is everything the checks of an app depend on.
+type Inputs struct {
+	// Manifests are the rendered manifests of the app, in any order.
+	Manifests         []string
+	KubernetesVersion string
+	// LiveState identifies the state of the app in the cluster, see LiveStateVersion.
+	LiveState string
+	// Policies identifies the checks and the policies and schemas they use, see PolicyVersion.
+	Policies string
+}
+
+// Fingerprint hashes the inputs of the checks of an app. Two runs with the same fingerprint have the same results.
+func Fingerprint(in Inputs) string {
+	manifests := slices.Clone(in.Manifests)
+	slices.Sort(manifests)
+
+	parts := [][]byte{[]byte(in.KubernetesVersion), []byte(in.LiveState), []byte(in.Policies)}
+	for _, manifest := range manifests {
+		parts = append(parts, []byte(manifest))
+	}
+	return manifestcache.Key(parts...)
+}
+
+// resourceState is the part of the status of a resource that LiveStateVersion identifies.
+type resourceState struct {
+	Group, Kind, Namespace, Name string
+	Sync                         v1alpha1.SyncStatusCode
+	Health                       string
+	// ResourceVersion changes whenever the live object is written, including when an app that is already out of
+	// sync drifts again.
+	ResourceVersion string
+}
+
+// LiveApps reads the current state of apps from Argo CD.
+type LiveApps interface {
+	GetApplicationByName(ctx context.Context, name string) (*v1alpha1.Application, error)
+	GetManagedResources(ctx context.Context, app v1alpha1.Application) ([]*v1alpha1.ResourceDiff, error)
+}
+
+// LiveState identifies the current state of an app in the cluster, see LiveStateVersion. The app is read from Argo CD
+// again, as copies of apps kept elsewhere are only updated when their spec changes.
+func LiveState(ctx context.Context, apps LiveApps, name string) (string, error) {
+	app, err := apps.GetApplicationByName(ctx, name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get app")
+	}
+
+	resources, err := apps.GetManagedResources(ctx, *app)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get managed resources")
+	}
+
+	return LiveStateVersion(*app, resources)
+}
+
+// LiveStateVersion identifies the state of an app in the cluster, as last reconciled by Argo CD: the sync and health
+// status of the app and of each of its resources, and the resourceVersion of each of its live objects. It leaves out
+// the revisions the app is compared to and synced to, which change on every commit to the branch it tracks, even when
+// none of its resources change, and the reconciliation times, which change without the state changing.
+func LiveStateVersion(app v1alpha1.Application, managed []*v1alpha1.ResourceDiff) (string, error) {
+	resources := make([]resourceState, 0, len(app.Status.Resources))
+	for _, res := range app.Status.Resources {
+		state := resourceState{Group: res.Group, Kind: res.Kind, Namespace: res.Namespace, Name: res.Name, Sync: res.Status}
+		if res.Health != nil {
+			state.Health = string(res.Health.Status)
+		}
+		resources = append(resources, state)
+	}
+
+	versions := make(map[string]string, len(managed))
+	for _, res := range managed {
+		version, err := resourceVersion(res.LiveState)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read the live state of %s/%s", res.Kind, res.Name)
+		}
+		versions[strings.Join([]string{res.Group, res.Kind, res.Namespace, res.Name}, "/")] = version
+	}
+	for i, res := range resources {
+		resources[i].ResourceVersion = versions[strings.Join([]string{res.Group, res.Kind, res.Namespace, res.Name}, "/")]
+	}
+
+	sort.Slice(resources, func(i, j int) bool {
+		a, b := resources[i], resources[j]
+		return strings.Join([]string{a.Group, a.Kind, a.Namespace, a.Name}, "/") < strings.Join([]string{b.Group, b.Kind, b.Namespace, b.Name}, "/")
+	})
+
+	data, err := json.Marshal(struct {
+		Sync      v1alpha1.SyncStatusCode
+		Health    string
+		Resources []resourceState
+	}{app.Status.Sync.Status, string(app.Status.Health.Status), resources})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode app status")
+	}
+
+	return manifestcache.Key(data), nil
+}
+
+// resourceVersion returns the resourceVersion of a live object, or "" when the object does not exist.
+func resourceVersion(liveState string) (string, error) {
+	if liveState == "" || liveState == "null" {
+		return "", nil
+	}
+
+	var live struct {
+		Metadata struct {
+			ResourceVersion string `json:"resourceVersion"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal([]byte(liveState), &live); err != nil {
+		return "", err
+	}
+	return live.Metadata.ResourceVersion, nil
+}
+
+// PolicyVersion identifies the checks that run, the policies and schemas they use, and the version of kubechecks
+// that runs them. Locations that are URLs, or the schemas included in kubechecks, are identified by themselves. Other
+// locations are paths that the checks read from disk, relative to the working directory (git urls were cloned by
+// then), and their files are hashed there. It fails when such a path can't be read, so that results are not reused
+// with policies that can't be told apart.
+func PolicyVersion(checks []string, locations []string) (string, error) {
+	parts := [][]byte{[]byte(pkg.GitCommit)}
+	for _, check := range checks {
+		parts = append(parts, []byte(check))
+	}
+
+	for _, location := range locations {
+		parts = append(parts, []byte(location))
+
+		path, ok := localPath(location)
+		if !ok {
+			continue
+		}
+
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to resolve %q", location)
+		}
+
+		hash, err := manifestcache.HashDir(path, []string{".git"})
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to hash %q", location)
+		}
+		parts = append(parts, []byte(path), []byte(hash))
+	}
+
+	return manifestcache.Key(parts...), nil
+}
+
+// localPath returns the path on disk that a location is read from. Schema locations may be templates, whose files
+// are all under the directory in front of the first template action.
+func localPath(location string) (string, bool) {
+	if location == "default" {
+		return "", false
+	}
+	if u, err := url.Parse(location); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		return "", false
+	}
+
+	if index := strings.Index(location, "{{"); index >= 0 {
+		return filepath.Dir(location[:index]), true
+	}
+	return location, true
+}
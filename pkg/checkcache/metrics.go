@@ -0,0 +1,29 @@
+package checkcache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	checkCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "kubechecks",
+			Subsystem: "check_cache",
+			Name:      "hits_total",
+			Help:      "Number of app checks skipped because their inputs did not change since the last run",
+		},
+	)
+	checkCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "kubechecks",
+			Subsystem: "check_cache",
+			Name:      "misses_total",
+			Help:      "Number of app checks that ran because their inputs changed or were not checked before",
+		},
+	)
+)
+
+func init() {
+	r := prometheus.DefaultRegisterer
+
+	r.MustRegister(checkCacheHits)
+	r.MustRegister(checkCacheMisses)
+}
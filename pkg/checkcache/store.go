@@ -0,0 +1,230 @@
+// Package checkcache remembers the results of the checks of each app of a pull request, along with a fingerprint of
+// everything the checks depended on, so that a new push only re-checks the apps whose inputs changed.
+package checkcache
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zapier/kubechecks/pkg/manifestcache"
+	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// Config holds store configuration
+type Config struct {
+	Dir string // Directory the results are stored in
+	// TTL is how long results are reused after they were stored, there is no limit when 0.
+	TTL time.Duration
+}
+
+// Entry is the outcome of checking an app.
+type Entry struct {
+	// Fingerprint identifies the inputs of the checks, see Fingerprint.
+	Fingerprint string `json:"fingerprint"`
+	// SHA is the commit that was checked.
+	SHA     string       `json:"sha"`
+	Created time.Time    `json:"created"`
+	Results []msg.Result `json:"results"`
+}
+
+// Store keeps the latest entry of each app of each pull request in a file.
+type Store struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewStore creates the store directory if needed, removes the entries that expired, and returns a store that uses it.
+func NewStore(cfg Config) (*Store, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create check cache directory")
+	}
+
+	s := &Store{dir: cfg.Dir, ttl: cfg.TTL}
+	if err := s.prune(); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("dir", cfg.Dir).
+		Str("ttl", cfg.TTL.String()).
+		Msg("check cache enabled")
+
+	return s, nil
+}
+
+// path stores the apps of a pull request in one directory, so that they are easy to find and remove together.
+func (s *Store) path(pr vcs.PullRequest, app string) string {
+	prKey := manifestcache.Key([]byte(pr.FullName), []byte(strconv.Itoa(pr.CheckID)))
+	return filepath.Join(s.dir, prKey, manifestcache.Key([]byte(app))+".json")
+}
+
+// Lookup returns the latest entry of an app of a pull request if it was checked with the same fingerprint, and false
+// if it was not, or the entry expired.
+func (s *Store) Lookup(pr vcs.PullRequest, app, fingerprint string) (Entry, bool) {
+	e, ok := s.get(pr, app)
+	if !ok || e.Fingerprint != fingerprint {
+		checkCacheMisses.Inc()
+		return Entry{}, false
+	}
+
+	checkCacheHits.Inc()
+	return e, true
+}
+
+func (s *Store) get(pr vcs.PullRequest, app string) (Entry, bool) {
+	path := s.path(pr, app)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("path", path).Msg("failed to read cached check results")
+		}
+		return Entry{}, false
+	}
+
+	var e Entry
+	if err = json.Unmarshal(data, &e); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("failed to parse cached check results, removing them")
+		_ = os.Remove(path)
+		return Entry{}, false
+	}
+
+	if s.expired(e.Created) {
+		_ = os.Remove(path)
+		return Entry{}, false
+	}
+
+	return e, true
+}
+
+// Put replaces the entry of an app of a pull request.
+func (s *Store) Put(pr vcs.PullRequest, app string, e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode check results")
+	}
+
+	path := s.path(pr, app)
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create check cache directory")
+	}
+
+	// write to a temporary file first, so that readers never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create check cache file")
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return errors.Wrap(err, "failed to write check cache file")
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to write check cache file")
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "failed to store check cache file")
+	}
+
+	return nil
+}
+
+// Stats returns the number of stored entries and their size in bytes.
+func (s *Store) Stats() (entries int, size int64, err error) {
+	err = filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		entries++
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "failed to list cached check results")
+	}
+
+	return entries, size, nil
+}
+
+// Purge removes every entry, and returns how many it removed.
+func (s *Store) Purge() (int, error) {
+	entries, _, err := s.Stats()
+	if err != nil {
+		return 0, err
+	}
+
+	prDirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list cached check results")
+	}
+	for _, prDir := range prDirs {
+		if err = os.RemoveAll(filepath.Join(s.dir, prDir.Name())); err != nil {
+			return 0, errors.Wrap(err, "failed to remove cached check results")
+		}
+	}
+
+	log.Info().Int("removed", entries).Msg("purged check cache")
+	return entries, nil
+}
+
+func (s *Store) expired(created time.Time) bool {
+	return s.ttl > 0 && time.Since(created) > s.ttl
+}
+
+// prune removes the entries that were stored longer than the TTL ago, and the directories of pull requests that are
+// left without any.
+func (s *Store) prune() error {
+	if s.ttl == 0 {
+		return nil
+	}
+
+	prDirs, err := os.ReadDir(s.dir)
+	if err != nil {
+		return errors.Wrap(err, "failed to list cached check results")
+	}
+
+	for _, prDir := range prDirs {
+		if !prDir.IsDir() {
+			continue
+		}
+
+		dir := filepath.Join(s.dir, prDir.Name())
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if s.expired(info.ModTime()) {
+				_ = os.Remove(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to prune cached check results")
+		}
+
+		// only succeeds once the directory is empty
+		_ = os.Remove(dir)
+	}
+
+	return nil
+}
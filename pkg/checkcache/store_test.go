@@ -0,0 +1,188 @@
+package checkcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func TestStore(t *testing.T) {
+	store, err := NewStore(Config{Dir: t.TempDir(), TTL: time.Hour})
+	require.NoError(t, err)
+
+	pr := vcs.PullRequest{FullName: "zapier/kubechecks", CheckID: 1, SHA: "abc"}
+	_, ok := store.Lookup(pr, "app", "fingerprint")
+	assert.False(t, ok)
+
+	results := []msg.Result{{State: pkg.StateSuccess, Check: "diff", Summary: "Diff", Details: "details"}}
+	require.NoError(t, store.Put(pr, "app", Entry{Fingerprint: "fingerprint", SHA: pr.SHA, Created: time.Now(), Results: results}))
+
+	entry, ok := store.Lookup(pr, "app", "fingerprint")
+	require.True(t, ok)
+	assert.Equal(t, "abc", entry.SHA)
+	assert.Equal(t, results, entry.Results)
+
+	// a different fingerprint, app or pull request has no results
+	_, ok = store.Lookup(pr, "app", "other")
+	assert.False(t, ok)
+	_, ok = store.Lookup(pr, "other", "fingerprint")
+	assert.False(t, ok)
+	_, ok = store.Lookup(vcs.PullRequest{FullName: "zapier/kubechecks", CheckID: 2}, "app", "fingerprint")
+	assert.False(t, ok)
+}
+
+func TestStore_TTL(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(Config{Dir: dir, TTL: time.Millisecond})
+	require.NoError(t, err)
+
+	pr := vcs.PullRequest{FullName: "zapier/kubechecks", CheckID: 1}
+	require.NoError(t, store.Put(pr, "app", Entry{Fingerprint: "fingerprint", Created: time.Now()}))
+	time.Sleep(10 * time.Millisecond)
+
+	_, ok := store.Lookup(pr, "app", "fingerprint")
+	assert.False(t, ok)
+	assert.NoFileExists(t, store.path(pr, "app"))
+
+	// expired entries of previous runs are removed, along with the directory of their pull request
+	require.NoError(t, store.Put(pr, "app", Entry{Fingerprint: "fingerprint", Created: time.Now()}))
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(store.path(pr, "app"), past, past))
+
+	_, err = NewStore(Config{Dir: dir, TTL: time.Millisecond})
+	require.NoError(t, err)
+	assert.NoDirExists(t, filepath.Dir(store.path(pr, "app")))
+}
+
+func TestFingerprint(t *testing.T) {
+	inputs := Inputs{
+		Manifests:         []string{`{"kind":"ConfigMap"}`, `{"kind":"Secret"}`},
+		KubernetesVersion: "1.30.0",
+		LiveState:         "live",
+		Policies:          "policies",
+	}
+	fingerprint := Fingerprint(inputs)
+
+	reordered := inputs
+	reordered.Manifests = []string{`{"kind":"Secret"}`, `{"kind":"ConfigMap"}`}
+	assert.Equal(t, fingerprint, Fingerprint(reordered), "the order of the manifests does not matter")
+
+	for name, change := range map[string]func(*Inputs){
+		"manifests":  func(in *Inputs) { in.Manifests = in.Manifests[:1] },
+		"kubernetes": func(in *Inputs) { in.KubernetesVersion = "1.31.0" },
+		"live state": func(in *Inputs) { in.LiveState = "changed" },
+		"policies":   func(in *Inputs) { in.Policies = "changed" },
+	} {
+		changed := inputs
+		change(&changed)
+		assert.NotEqual(t, fingerprint, Fingerprint(changed), name)
+	}
+}
+
+func TestLiveStateVersion(t *testing.T) {
+	app := v1alpha1.Application{}
+	app.Status.Sync = v1alpha1.SyncStatus{Status: v1alpha1.SyncStatusCodeSynced, Revision: "abc"}
+	version, err := LiveStateVersion(app, nil)
+	require.NoError(t, err)
+
+	// reconciling again without changes does not change the version
+	now := metav1.Now()
+	app.Status.ReconciledAt = &now
+	again, err := LiveStateVersion(app, nil)
+	require.NoError(t, err)
+	assert.Equal(t, version, again)
+
+	// nor does a commit to the tracked branch that leaves the app synced
+	app.Status.Sync.Revision = "def"
+	app.Status.Sync.ComparedTo.Source.TargetRevision = "def"
+	again, err = LiveStateVersion(app, nil)
+	require.NoError(t, err)
+	assert.Equal(t, version, again)
+
+	app.Status.Resources = []v1alpha1.ResourceStatus{{Kind: "Deployment", Name: "api", Status: v1alpha1.SyncStatusCodeSynced, Health: &v1alpha1.HealthStatus{Status: "Healthy"}}}
+	withResource, err := LiveStateVersion(app, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, version, withResource)
+
+	app.Status.Resources[0].Health.Status = "Degraded"
+	degraded, err := LiveStateVersion(app, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, withResource, degraded)
+
+	app.Status.Sync.Status = v1alpha1.SyncStatusCodeOutOfSync
+	outOfSync, err := LiveStateVersion(app, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, version, outOfSync)
+
+	// an app that is already out of sync and drifts again only changes the version of its live objects
+	live := []*v1alpha1.ResourceDiff{{Kind: "Deployment", Name: "api", LiveState: `{"metadata":{"name":"api","resourceVersion":"1"}}`}}
+	before, err := LiveStateVersion(app, live)
+	require.NoError(t, err)
+	assert.NotEqual(t, outOfSync, before)
+
+	live[0].LiveState = `{"metadata":{"name":"api","resourceVersion":"2"}}`
+	drifted, err := LiveStateVersion(app, live)
+	require.NoError(t, err)
+	assert.NotEqual(t, before, drifted)
+
+	live[0].LiveState = "null"
+	missing, err := LiveStateVersion(app, live)
+	require.NoError(t, err)
+	assert.Equal(t, outOfSync, missing, "objects that don't exist have no version")
+}
+
+func TestPolicyVersion(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.rego"), []byte("package main"), 0o644))
+
+	version, err := PolicyVersion([]string{"rego"}, []string{dir, "https://example.com/schemas"})
+	require.NoError(t, err)
+
+	again, err := PolicyVersion([]string{"rego"}, []string{dir, "https://example.com/schemas"})
+	require.NoError(t, err)
+	assert.Equal(t, version, again)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.rego"), []byte("package other"), 0o644))
+	changed, err := PolicyVersion([]string{"rego"}, []string{dir, "https://example.com/schemas"})
+	require.NoError(t, err)
+	assert.NotEqual(t, version, changed)
+
+	otherChecks, err := PolicyVersion([]string{"rego", "diff"}, []string{dir, "https://example.com/schemas"})
+	require.NoError(t, err)
+	assert.NotEqual(t, changed, otherChecks)
+
+	_, err = PolicyVersion([]string{"rego"}, []string{filepath.Join(dir, "missing")})
+	assert.Error(t, err, "policies that can't be read can't be told apart")
+}
+
+func TestPolicyVersion_Locations(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "schemas", "v1.30.0"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "schemas", "v1.30.0", "deployment.json"), []byte("{}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.rego"), []byte("package main"), 0o644))
+	t.Chdir(dir)
+
+	locations := []string{"policy.rego", "schemas/{{ .NormalizedKubernetesVersion }}/{{ .ResourceKind }}.json", "default", "https://example.com/schemas"}
+	version, err := PolicyVersion([]string{"rego"}, locations)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "schemas", "v1.30.0", "deployment.json"), []byte(`{"type": "object"}`), 0o644))
+	schemaChanged, err := PolicyVersion([]string{"rego"}, locations)
+	require.NoError(t, err)
+	assert.NotEqual(t, version, schemaChanged, "the files of templates are hashed")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "policy.rego"), []byte("package other"), 0o644))
+	policyChanged, err := PolicyVersion([]string{"rego"}, locations)
+	require.NoError(t, err)
+	assert.NotEqual(t, schemaChanged, policyChanged, "relative paths are read from the working directory")
+}
@@ -160,7 +160,7 @@ func (c *Checker) Check(ctx context.Context, request checks.Request) (retResult
 	reviewTools := []aireview.Tool{diffTool, manifestsTool, appInfoTool}
 
 	// Add ArgoCD-backed resource tools — queries live state via ArgoCD API (works across all clusters)
-	if request.Container.ArgoClient != nil {
+	if request.Container.ArgoClient != nil && !request.Container.ArgoClient.IsOffline() {
 		reviewTools = append(reviewTools,
 			tools.QueryAppResourcesTool(request.Container.ArgoClient),
 			tools.GetAppResourceTool(request.Container.ArgoClient),
@@ -14,12 +14,15 @@ import (
 	"github.com/zapier/kubechecks/pkg/aiproviders/anthropic"
 	"github.com/zapier/kubechecks/pkg/aiproviders/openai"
 	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/limiter"
 	"github.com/zapier/kubechecks/pkg/msg"
 	"github.com/zapier/kubechecks/telemetry"
 )
 
 var tracer = otel.Tracer("pkg/checks/diff")
 
+const aiSummaryCheckName = "summarizing diff"
+
 const diffSummarySystemPrompt = `You are a helpful Kubernetes expert.
 You can summarize Kubernetes YAML manifests for application developers that may not be familiar with all Kubernetes resource types.
 Answer as concisely as possible.`
@@ -34,7 +37,7 @@ Describe the impact of each change.
 #***
 `
 
-func aiDiffSummary(ctx context.Context, mrNote *msg.Message, cfg config.ServerConfig, name, diff string) {
+func aiDiffSummary(ctx context.Context, mrNote *msg.Message, cfg config.ServerConfig, limit *limiter.Semaphore, name, diff string) {
 	ctx, span := tracer.Start(ctx, "aiDiffSummary")
 	defer span.End()
 
@@ -48,6 +51,7 @@ func aiDiffSummary(ctx context.Context, mrNote *msg.Message, cfg config.ServerCo
 		log.Debug().Caller().Err(err).Msg("AI diff summary provider not configured, skipping")
 		return
 	}
+	provider = aiproviders.WithLimit(provider, limit)
 
 	resp, err := provider.Chat(ctx, aiproviders.ChatRequest{
 		Model:        cfg.AIReviewModel,
@@ -64,7 +68,7 @@ func aiDiffSummary(ctx context.Context, mrNote *msg.Message, cfg config.ServerCo
 	if err != nil {
 		telemetry.SetError(span, err, "AI SummarizeDiff")
 		log.Error().Err(err).Msg("failed to summarize diff")
-		cr := msg.Result{State: pkg.StateNone, Summary: "failed to summarize diff", Details: err.Error()}
+		cr := msg.Result{State: pkg.StateNone, Check: aiSummaryCheckName, Summary: "failed to summarize diff", Details: err.Error()}
 		mrNote.AddToAppMessage(ctx, name, cr)
 		return
 	}
@@ -74,7 +78,7 @@ func aiDiffSummary(ctx context.Context, mrNote *msg.Message, cfg config.ServerCo
 		return
 	}
 
-	cr := msg.Result{State: pkg.StateNone, Summary: "<b>Show AI Summary Diff</b>", Details: aiSummary}
+	cr := msg.Result{State: pkg.StateNone, Check: aiSummaryCheckName, Summary: "<b>Show AI Summary Diff</b>", Details: aiSummary}
 	mrNote.AddToAppMessage(ctx, name, cr)
 }
 
@@ -10,7 +10,6 @@ import (
 
 	cmdutil "github.com/argoproj/argo-cd/v3/cmd/util"
 	"github.com/argoproj/argo-cd/v3/controller"
-	"github.com/argoproj/argo-cd/v3/pkg/apiclient/application"
 	"github.com/argoproj/argo-cd/v3/pkg/apiclient/settings"
 	argoappv1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/argoproj/argo-cd/v3/util/argo"
@@ -30,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/argo_client"
 	"github.com/zapier/kubechecks/pkg/checks"
 	"github.com/zapier/kubechecks/pkg/msg"
 	"github.com/zapier/kubechecks/telemetry"
@@ -145,7 +145,7 @@ func Check(ctx context.Context, request checks.Request) (msg.Result, error) {
 	cr.Details = fmt.Sprintf("```diff\n%s\n```", renderedDiff)
 
 	if request.Container.Config.EnableAIDiffSummary {
-		aiDiffSummary(ctx, request.Note, request.Container.Config, request.AppName, renderedDiff)
+		aiDiffSummary(ctx, request.Note, request.Container.Config, request.Container.AILimit, request.AppName, renderedDiff)
 	}
 
 	return cr, nil
@@ -161,12 +161,12 @@ func processResources(item objKeyLiveTarget, diffRes diff.DiffResult, request ch
 	case item.live == nil:
 		*added++
 		if app, ok := isApp(item, diffRes.PredictedLive); ok {
-			request.QueueApp(app)
+			request.QueueApp(app, checks.ChildAdded)
 		}
 	case diffRes.Modified:
 		*modified++
 		if app, ok := isApp(item, diffRes.PredictedLive); ok {
-			request.QueueApp(app)
+			request.QueueApp(app, checks.ChildChanged)
 		}
 	}
 }
@@ -237,13 +237,8 @@ func getResources(ctx context.Context, request checks.Request) ([]*argoappv1.Res
 	ctx, span := tracer.Start(ctx, "getResources")
 	defer span.End()
 
-	closer, appClient := request.Container.ArgoClient.GetApplicationClient()
-	defer pkg.WithErrorLogging(closer.Close, "failed to close application connection")
-
-	resources, err := appClient.ManagedResources(ctx, &application.ResourcesQuery{
-		ApplicationName: &request.App.Name,
-		AppNamespace:    &request.App.Namespace,
-	})
+	// there is no live state in offline mode, so every resource is new
+	resources, err := request.Container.ArgoClient.GetManagedResources(ctx, request.App)
 	if err != nil {
 		if isAppMissingErr(err) {
 			span.RecordError(err)
@@ -252,13 +247,17 @@ func getResources(ctx context.Context, request checks.Request) ([]*argoappv1.Res
 
 		return nil, err
 	}
-	return resources.Items, nil
+	return resources, nil
 }
 
 func getArgoSettings(ctx context.Context, request checks.Request) (*settings.Settings, error) {
 	ctx, span := tracer.Start(ctx, "getArgoSettings")
 	defer span.End()
 
+	if request.Container.ArgoClient.IsOffline() {
+		return argo_client.OfflineSettings(), nil
+	}
+
 	settingsCloser, settingsClient := request.Container.ArgoClient.GetSettingsClient()
 	defer pkg.WithErrorLogging(settingsCloser.Close, "failed to close connection")
 
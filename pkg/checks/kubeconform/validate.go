@@ -94,7 +94,10 @@ func argoCdAppValidate(ctx context.Context, ctr container.Container, appName, ta
 		return msg.Result{}, fmt.Errorf("could not create kubeconform validator: %v", err)
 	}
 	result := v.Validate("-", io.NopCloser(strings.NewReader(strings.Join(appManifests, "\n"))))
-	var invalid, failedValidation bool
+	var (
+		invalid, failedValidation bool
+		findings                  []msg.Finding
+	)
 	for _, res := range result {
 		sigData, _ := res.Resource.Signature()
 		sig := fmt.Sprintf("%s %s %s", sigData.Version, sigData.Kind, sigData.Name)
@@ -104,9 +107,11 @@ func argoCdAppValidate(ctx context.Context, ctr container.Container, appName, ta
 			outputString = append(outputString, fmt.Sprintf(" * :warning: **Invalid**: %s", sig))
 			outputString = append(outputString, fmt.Sprintf("   * %s ", res.Err))
 			invalid = true
+			findings = append(findings, newFinding("invalid-resource", pkg.StateWarning, sig, res.Err))
 		case validator.Error:
 			outputString = append(outputString, fmt.Sprintf(" * :red_circle: **Error**: %s - %v", sig, res.Err))
 			failedValidation = true
+			findings = append(findings, newFinding("validation-error", pkg.StateFailure, sig, res.Err))
 		case validator.Empty:
 			// noop
 		case validator.Skipped:
@@ -125,8 +130,22 @@ func argoCdAppValidate(ctx context.Context, ctr container.Container, appName, ta
 		cr.State = pkg.StateSuccess
 	}
 
+	cr.Findings = findings
 	cr.Summary = "<b>Show kubeconform report:</b>"
 	cr.Details = fmt.Sprintf(">Validated against Kubernetes Version: %s\n\n%s", targetKubernetesVersion, strings.Join(outputString, "\n"))
 
 	return cr, nil
 }
+
+func newFinding(rule string, level pkg.CommitState, resource string, err error) msg.Finding {
+	finding := msg.Finding{
+		Tool:     "kubeconform",
+		RuleID:   "kubeconform/" + rule,
+		Level:    level,
+		Resource: resource,
+	}
+	if err != nil {
+		finding.Message = err.Error()
+	}
+	return finding
+}
@@ -171,8 +171,9 @@ func checkApp(ctx context.Context, ctr container.Container, appName, targetKuber
 	}
 
 	return msg.Result{
-		State:   checkStatus(result),
-		Summary: "<b>Show kubepug report:</b>",
+		State:    checkStatus(result),
+		Findings: getFindings(result, nextVersion),
+		Summary:  "<b>Show kubepug report:</b>",
 		Details: fmt.Sprintf(
 			"> This provides a list of Kubernetes resources in this application that are either deprecated or deleted from the **next** version (v%s) of Kubernetes.\n\n%s",
 			nextVersion.String(),
@@ -193,6 +194,31 @@ func checkStatus(result *results.Result) pkg.CommitState {
 	}
 }
 
+// getFindings lists every object that uses an API that is deprecated in, or deleted from, the next Kubernetes version.
+func getFindings(result *results.Result, nextVersion *semver.Version) []msg.Finding {
+	var findings []msg.Finding
+
+	add := func(rule, verb string, apis []results.ResultItem) {
+		for _, api := range apis {
+			apiVersion := fmt.Sprintf("%s/%s", api.Group, api.Version)
+			for _, item := range api.Items {
+				findings = append(findings, msg.Finding{
+					Tool:     "kubepug",
+					RuleID:   "kubepug/" + rule,
+					Level:    pkg.StateWarning,
+					Message:  fmt.Sprintf("%s %s is %s in Kubernetes v%s", apiVersion, api.Kind, verb, nextVersion.String()),
+					Resource: fmt.Sprintf("%s %s %s", apiVersion, api.Kind, item.ObjectName),
+				})
+			}
+		}
+	}
+
+	add("deprecated-api", "deprecated", result.DeprecatedAPIs)
+	add("deleted-api", "deleted", result.DeletedAPIs)
+
+	return findings
+}
+
 func nextKubernetesVersion(current string) (*semver.Version, error) {
 	sv, err := semver.NewVersion(current)
 	if err != nil {
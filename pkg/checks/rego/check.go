@@ -181,15 +181,20 @@ func (c *Checker) Check(ctx context.Context, request checks.Request) (msg.Result
 
 	failures := false
 	warnings := false
+	var findings []msg.Finding
 	for _, r := range results {
+		// exceptions are failures that a policy exempted on purpose, so they are not findings
+		resource := strings.TrimPrefix(r.FileName, fmt.Sprintf("%s/", manifestsPath))
 		for _, f := range r.Warnings {
 			if !f.Passed() {
 				warnings = true
+				findings = append(findings, newFinding(r.Namespace, pkg.StateWarning, resource, f.Message))
 			}
 		}
 		for _, f := range r.Failures {
 			if !f.Passed() {
 				failures = true
+				findings = append(findings, newFinding(r.Namespace, pkg.StateFailure, resource, f.Message))
 			}
 		}
 	}
@@ -207,6 +212,7 @@ func (c *Checker) Check(ctx context.Context, request checks.Request) (msg.Result
 		cr.State = pkg.StateSuccess
 	}
 
+	cr.Findings = findings
 	cr.Summary = "<b>Show Conftest Validation result</b>"
 	cr.Details = resultsMessage
 
@@ -269,6 +275,21 @@ func formatConftestResults(w io.Writer, checkResults []output.CheckResult, vcs e
 	return nil
 }
 
+// newFinding creates a finding for a single conftest result. Policies are identified by their rego namespace.
+func newFinding(namespace string, level pkg.CommitState, resource, message string) msg.Finding {
+	if namespace == "" {
+		namespace = "main"
+	}
+
+	return msg.Finding{
+		Tool:     "conftest",
+		RuleID:   "conftest/" + namespace,
+		Level:    level,
+		Message:  message,
+		Resource: resource,
+	}
+}
+
 func code(s string) string {
 	return "`" + s + "`"
 }
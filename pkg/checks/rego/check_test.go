@@ -191,6 +191,48 @@ warn contains msg if {
 	}
 }
 
+func TestExceptions(t *testing.T) {
+	t.Parallel()
+
+	policiesPath := t.TempDir()
+	mustWrite(t, filepath.Join(policiesPath, "policy.rego"), `package tests
+
+import rego.v1
+
+deny_root contains msg if {
+  input.kind == "Deployment"
+  not input.spec.template.spec.securityContext.runAsNonRoot
+
+  msg := "Containers must not run as root"
+}
+
+exception contains rules if {
+  input.metadata.name == "test-deployment"
+  rules := ["root"]
+}
+`)
+
+	cfg := config.ServerConfig{PoliciesLocation: []string{policiesPath}}
+	c, err := NewChecker(cfg)
+	require.NoError(t, err)
+
+	manifestBytes, err := yaml.Marshal(yamlMap{
+		"kind":     "Deployment",
+		"metadata": yamlMap{"name": "test-deployment", "namespace": "test-namespace"},
+	})
+	require.NoError(t, err)
+
+	cr, err := c.Check(context.TODO(), checks.Request{
+		Container:     container.Container{Config: cfg, VcsClient: new(gitlab_client.Client)},
+		YamlManifests: []string{string(manifestBytes)},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, pkg.StateSuccess, cr.State, "%s\n\n%s", cr.Summary, cr.Details)
+	assert.Contains(t, cr.Details, `data.tests.exception[_][_] == "root"`, "exceptions are still listed")
+	assert.Empty(t, cr.Findings, "exempted failures are not findings")
+}
+
 func TestEmptyManifests(t *testing.T) {
 	t.Parallel()
 
@@ -18,6 +18,14 @@ type ProcessorEntry struct {
 	WorstState pkg.CommitState
 }
 
+// ChildChange is how an app-of-apps changes one of the apps it renders.
+type ChildChange string
+
+const (
+	ChildAdded   ChildChange = "added"
+	ChildChanged ChildChange = "changed"
+)
+
 type Processor interface {
 	Name() string
 	Command()
@@ -30,7 +38,7 @@ type Request struct {
 	Repo      *git.Repo
 	Container container.Container
 
-	QueueApp  func(app v1alpha1.Application)
+	QueueApp  func(app v1alpha1.Application, change ChildChange)
 	RemoveApp func(app v1alpha1.Application)
 
 	AppName           string
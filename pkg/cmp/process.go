@@ -0,0 +1,53 @@
+// Package cmp resolves the files that affect the manifests rendered by Argo CD config management plugins.
+package cmp
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/pkg/errors"
+)
+
+// ResolvePattern returns a plugin file glob relative to the root of the repository. Patterns that start with a
+// slash are already relative to the root, all others are relative to the source path of the app. It returns false
+// if the pattern points outside of the repository, or is not a valid glob.
+func ResolvePattern(sourcePath, pattern string) (string, bool) {
+	var resolved string
+	if strings.HasPrefix(pattern, "/") {
+		resolved = filepath.Clean(strings.TrimPrefix(pattern, "/"))
+	} else {
+		resolved = filepath.Join(sourcePath, pattern)
+	}
+
+	if resolved == ".." || strings.HasPrefix(resolved, "../") || !doublestar.ValidatePattern(resolved) {
+		return "", false
+	}
+
+	return resolved, true
+}
+
+// Match returns true if the path, relative to the root of the repository, matches a resolved pattern.
+func Match(resolvedPattern, path string) bool {
+	return doublestar.MatchUnvalidated(resolvedPattern, path)
+}
+
+// ProcessPatterns returns the files in sourceFS that match any of the patterns of a source.
+func ProcessPatterns(sourceFS fs.FS, sourcePath string, patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		resolved, ok := ResolvePattern(sourcePath, pattern)
+		if !ok {
+			continue
+		}
+
+		matches, err := doublestar.Glob(sourceFS, resolved, doublestar.WithFilesOnly())
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to expand %q", pattern)
+		}
+		files = append(files, matches...)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,56 @@
+package cmp
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePattern(t *testing.T) {
+	testcases := map[string]struct {
+		pattern  string
+		expected string
+		ok       bool
+	}{
+		"relative to the source": {pattern: "jsonnetfile.json", expected: "apps/app-1/jsonnetfile.json", ok: true},
+		"parent directory":       {pattern: "../lib/**", expected: "apps/lib/**", ok: true},
+		"repository root":        {pattern: "/lib/**/*.libsonnet", expected: "lib/**/*.libsonnet", ok: true},
+		"outside the repository": {pattern: "../../../lib/**"},
+		"invalid glob":           {pattern: "lib/[a"},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			resolved, ok := ResolvePattern("apps/app-1", tc.pattern)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, resolved)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	assert.True(t, Match("lib/**", "lib/a/b.libsonnet"))
+	assert.True(t, Match("apps/app-1/*.jsonnet", "apps/app-1/main.jsonnet"))
+	assert.False(t, Match("apps/app-1/*.jsonnet", "apps/app-1/sub/main.jsonnet"))
+	assert.False(t, Match("lib/**", "vendor/lib/a.libsonnet"))
+}
+
+func TestProcessPatterns(t *testing.T) {
+	sourceFS := fstest.MapFS{
+		"apps/app-1/main.jsonnet":       {},
+		"apps/app-1/jsonnetfile.json":   {},
+		"lib/k8s.libsonnet":             {},
+		"lib/util/strings.libsonnet":    {},
+		"vendor/github.com/x/x.jsonnet": {},
+	}
+
+	files, err := ProcessPatterns(sourceFS, "apps/app-1", []string{"jsonnetfile.json", "/lib/**", "/missing/*"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"apps/app-1/jsonnetfile.json",
+		"lib/k8s.libsonnet",
+		"lib/util/strings.libsonnet",
+	}, files)
+}
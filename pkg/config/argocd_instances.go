@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// ArgoCDInstance is one of several Argo CD installations that kubechecks checks changes against. Settings that are
+// empty or unset fall back to the matching global argocd-* and kubernetes-* settings.
+type ArgoCDInstance struct {
+	Name string `json:"name"`
+
+	ServerAddr         string `json:"apiServerAddr"`
+	Token              string `json:"token"`
+	TokenEnv           string `json:"tokenEnv"`
+	PathPrefix         string `json:"pathPrefix"`
+	Insecure           *bool  `json:"insecure"`
+	PlainText          *bool  `json:"plainText"`
+	Namespace          string `json:"namespace"`
+	RepositoryEndpoint string `json:"repositoryEndpoint"`
+	RepositoryInsecure *bool  `json:"repositoryInsecure"`
+
+	KubernetesConfig    string `json:"kubernetesConfig"`
+	KubernetesType      string `json:"kubernetesType"`
+	KubernetesClusterID string `json:"kubernetesClusterID"`
+}
+
+type argoCDInstancesFile struct {
+	Instances []ArgoCDInstance `json:"instances"`
+}
+
+// LoadArgoCDInstances reads the list of Argo CD instances from a yaml file.
+func LoadArgoCDInstances(path string) ([]ArgoCDInstance, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %q", path)
+	}
+
+	var file argoCDInstancesFile
+	if err = yaml.UnmarshalStrict(content, &file); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q", path)
+	}
+
+	if len(file.Instances) == 0 {
+		return nil, fmt.Errorf("%q does not define any instances", path)
+	}
+
+	names := make(map[string]struct{}, len(file.Instances))
+	for i, instance := range file.Instances {
+		if instance.Name == "" {
+			return nil, fmt.Errorf("instance %d in %q has no name", i, path)
+		}
+		if strings.Contains(instance.Name, "/") {
+			return nil, fmt.Errorf("instance name %q must not contain a slash", instance.Name)
+		}
+		if _, ok := names[instance.Name]; ok {
+			return nil, fmt.Errorf("instance name %q is used more than once", instance.Name)
+		}
+		names[instance.Name] = struct{}{}
+
+		if instance.TokenEnv != "" {
+			file.Instances[i].Token = os.Getenv(instance.TokenEnv)
+		}
+	}
+
+	return file.Instances, nil
+}
+
+// ForArgoCDInstance returns a copy of the configuration that points at a single Argo CD instance.
+func (cfg ServerConfig) ForArgoCDInstance(instance ArgoCDInstance) ServerConfig {
+	override := func(setting *string, value string) {
+		if value != "" {
+			*setting = value
+		}
+	}
+	overrideBool := func(setting *bool, value *bool) {
+		if value != nil {
+			*setting = *value
+		}
+	}
+
+	override(&cfg.ArgoCDServerAddr, instance.ServerAddr)
+	override(&cfg.ArgoCDToken, instance.Token)
+	override(&cfg.ArgoCDPathPrefix, instance.PathPrefix)
+	override(&cfg.ArgoCDNamespace, instance.Namespace)
+	override(&cfg.ArgoCDRepositoryEndpoint, instance.RepositoryEndpoint)
+	override(&cfg.KubernetesConfig, instance.KubernetesConfig)
+	override(&cfg.KubernetesType, instance.KubernetesType)
+	override(&cfg.KubernetesClusterID, instance.KubernetesClusterID)
+	overrideBool(&cfg.ArgoCDInsecure, instance.Insecure)
+	overrideBool(&cfg.ArgoCDPlainText, instance.PlainText)
+	overrideBool(&cfg.ArgoCDRepositoryInsecure, instance.RepositoryInsecure)
+
+	return cfg
+}
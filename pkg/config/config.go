@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"time"
@@ -18,19 +19,30 @@ import (
 
 type ServerConfig struct {
 	// argocd
-	ArgoCDServerAddr         string `mapstructure:"argocd-api-server-addr"`
-	ArgoCDToken              string `mapstructure:"argocd-api-token"`
-	ArgoCDPathPrefix         string `mapstructure:"argocd-api-path-prefix"`
-	ArgoCDInsecure           bool   `mapstructure:"argocd-api-insecure"`
-	ArgoCDNamespace          string `mapstructure:"argocd-api-namespace"`
-	ArgoCDPlainText          bool   `mapstructure:"argocd-api-plaintext"`
-	ArgoCDRepositoryEndpoint string `mapstructure:"argocd-repository-endpoint"`
-	ArgoCDRepositoryInsecure bool   `mapstructure:"argocd-repository-insecure"`
-	ArgoCDSendFullRepository bool   `mapstructure:"argocd-send-full-repository"`
-	ArgoCDIncludeDotGit      bool   `mapstructure:"argocd-include-dot-git"`
-	KubernetesConfig         string `mapstructure:"kubernetes-config"`
-	KubernetesType           string `mapstructure:"kubernetes-type"`
-	KubernetesClusterID      string `mapstructure:"kubernetes-clusterid"`
+	ArgoCDServerAddr         string   `mapstructure:"argocd-api-server-addr"`
+	ArgoCDToken              string   `mapstructure:"argocd-api-token"`
+	ArgoCDPathPrefix         string   `mapstructure:"argocd-api-path-prefix"`
+	ArgoCDInsecure           bool     `mapstructure:"argocd-api-insecure"`
+	ArgoCDNamespace          string   `mapstructure:"argocd-api-namespace"`
+	ArgoCDPlainText          bool     `mapstructure:"argocd-api-plaintext"`
+	ArgoCDRepositoryEndpoint string   `mapstructure:"argocd-repository-endpoint"`
+	ArgoCDRepositoryInsecure bool     `mapstructure:"argocd-repository-insecure"`
+	ArgoCDRepositorySelector string   `mapstructure:"argocd-repository-selector"`
+	ArgoCDSendFullRepository bool     `mapstructure:"argocd-send-full-repository"`
+	ArgoCDIncludeDotGit      bool     `mapstructure:"argocd-include-dot-git"`
+	ArgoCDOfflineAppsPath    string   `mapstructure:"argocd-offline-apps-path"`
+	ArgoCDInstancesFile      string   `mapstructure:"argocd-instances-file"`
+	ManifestRenderer         string   `mapstructure:"manifest-renderer"`
+	RendererOverrides        []string `mapstructure:"manifest-renderer-overrides"`
+	CMPPluginFiles           []string `mapstructure:"cmp-plugin-files"`
+	KubernetesConfig         string   `mapstructure:"kubernetes-config"`
+	KubernetesType           string   `mapstructure:"kubernetes-type"`
+	KubernetesClusterID      string   `mapstructure:"kubernetes-clusterid"`
+
+	// appset generators
+	EnableAppSetSCMProviders  bool     `mapstructure:"enable-appset-scm-providers"`
+	AppSetAllowedSCMProviders []string `mapstructure:"appset-allowed-scm-providers"`
+	AppSetSCMRootCAPath       string   `mapstructure:"appset-scm-root-ca-path"`
 
 	// otel
 	EnableOtel        bool   `mapstructure:"otel-enabled"`
@@ -45,6 +57,10 @@ type ServerConfig struct {
 	VcsToken     string `mapstructure:"vcs-token"`
 	VcsType      string `mapstructure:"vcs-type"`
 
+	// local vcs
+	LocalVcsOutputDir  string `mapstructure:"local-vcs-output-dir"`
+	LocalVcsArchiveDir string `mapstructure:"local-vcs-archive-dir"`
+
 	//github
 	GithubPrivateKey     string `mapstructure:"github-private-key"`
 	GithubAppID          int64  `mapstructure:"github-app-id"`
@@ -56,6 +72,20 @@ type ServerConfig struct {
 	WebhookUrlBase string `mapstructure:"webhook-url-base"`
 	UrlPrefix      string `mapstructure:"webhook-url-prefix"`
 
+	// admin api
+	AdminToken string `mapstructure:"admin-token"`
+
+	// dashboard
+	HistoryStorePath string        `mapstructure:"history-store-path"`
+	HistoryRetention time.Duration `mapstructure:"history-retention"`
+	DashboardAddr    string        `mapstructure:"dashboard-addr"`
+	DashboardUrlBase string        `mapstructure:"dashboard-url-base"`
+
+	// re-validation
+	RevalidateInterval  time.Duration `mapstructure:"revalidate-interval"`
+	RevalidateLiveState bool          `mapstructure:"revalidate-live-state"`
+	RevalidateMaxAge    time.Duration `mapstructure:"revalidate-max-age"`
+
 	// checks
 	// -- conftest
 	EnableConfTest     bool            `mapstructure:"enable-conftest"`
@@ -100,14 +130,88 @@ type ServerConfig struct {
 	RepoCacheTTL             time.Duration `mapstructure:"repo-cache-ttl"`
 	ArchiveCacheDir          string        `mapstructure:"archive-cache-dir"`
 	ArchiveCacheTTL          time.Duration `mapstructure:"archive-cache-ttl"`
+	ManifestCacheDir         string        `mapstructure:"manifest-cache-dir"`
+	ManifestCacheTTL         time.Duration `mapstructure:"manifest-cache-ttl"`
+	ManifestCacheMaxSizeMB   int64         `mapstructure:"manifest-cache-max-size-mb"`
+	CheckCacheDir            string        `mapstructure:"check-cache-dir"`
+	CheckCacheTTL            time.Duration `mapstructure:"check-cache-ttl"`
 	SchemasLocations         []string      `mapstructure:"schemas-location"`
 	ShowDebugInfo            bool          `mapstructure:"show-debug-info"`
 	TidyOutdatedCommentsMode string        `mapstructure:"tidy-outdated-comments-mode"`
 	MaxQueueSize             int64         `mapstructure:"max-queue-size"`
 	MaxConcurrentChecks      int           `mapstructure:"max-concurrent-checks"`
+	MaxRepoServerCalls       int           `mapstructure:"max-repo-server-calls"`
+	MaxClusterCalls          int           `mapstructure:"max-cluster-calls"`
+	MaxAICalls               int           `mapstructure:"max-ai-calls"`
 	MaxRepoWorkerQueueSize   int           `mapstructure:"max-repo-worker-queue-size"`
+	QueueStorePath           string        `mapstructure:"queue-store-path"`
+	MaxConcurrentRepoChecks  int           `mapstructure:"max-concurrent-repo-checks"`
+	RepoPriorities           []string      `mapstructure:"repo-priorities"`
+	LabelPriorities          []string      `mapstructure:"label-priorities"`
+	RedisAddr                string        `mapstructure:"redis-addr"`
+	RedisPassword            string        `mapstructure:"redis-password"`
+	RedisQueueWorkers        int           `mapstructure:"redis-queue-workers"`
+	LeaderElection           bool          `mapstructure:"leader-election"`
+	LeaderElectionNamespace  string        `mapstructure:"leader-election-namespace"`
+	LeaderElectionLeaseName  string        `mapstructure:"leader-election-lease-name"`
+	MaxAppOfAppsDepth        int           `mapstructure:"max-app-of-apps-depth"`
 	ReplanCommentMessage     string        `mapstructure:"replan-comment-msg"`
 	Identifier               string        `mapstructure:"identifier"`
+
+	// reports
+	ReportJSONFile  string `mapstructure:"report-json-file"`
+	ReportSARIFFile string `mapstructure:"report-sarif-file"`
+	ReportJUnitFile string `mapstructure:"report-junit-file"`
+
+	// CMPPluginFileGlobs is parsed from CMPPluginFiles, keyed by plugin name.
+	CMPPluginFileGlobs map[string][]string `mapstructure:"-"`
+	// RepoPriorityLevels is parsed from RepoPriorities, keyed by the full name of the repository.
+	RepoPriorityLevels map[string]string `mapstructure:"-"`
+	// ArgoCDInstances is read from ArgoCDInstancesFile, and is empty when only one instance is used.
+	ArgoCDInstances []ArgoCDInstance `mapstructure:"-"`
+}
+
+// ParseCMPPluginFiles parses entries like "jsonnet=lib/**" into the glob patterns of each config management plugin.
+func ParseCMPPluginFiles(entries []string) (map[string][]string, error) {
+	result := make(map[string][]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		plugin, pattern, ok := strings.Cut(entry, "=")
+		if !ok || plugin == "" || pattern == "" {
+			return nil, fmt.Errorf("invalid cmp plugin files entry %q, must look like <plugin>=<glob>", entry)
+		}
+		result[plugin] = append(result[plugin], pattern)
+	}
+
+	return result, nil
+}
+
+// ParseRepoPriorities parses entries like "zapier/kubechecks=high" into the check priority of each repository.
+func ParseRepoPriorities(entries []string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		repo, priority, ok := strings.Cut(entry, "=")
+		if !ok || repo == "" {
+			return nil, fmt.Errorf("invalid repo priorities entry %q, must look like <owner>/<name>=<priority>", entry)
+		}
+		switch priority {
+		case "low", "normal", "high":
+		default:
+			return nil, fmt.Errorf("invalid priority %q for repo %s, must be one of low, normal or high", priority, repo)
+		}
+		result[repo] = priority
+	}
+
+	return result, nil
 }
 
 func (cfg ServerConfig) IsGithubApp() bool {
@@ -120,7 +224,8 @@ func New() (ServerConfig, error) {
 
 func NewWithViper(v *viper.Viper) (ServerConfig, error) {
 	var cfg ServerConfig
-	if err := v.Unmarshal(&cfg, viper.DecodeHook(func(in reflect.Type, out reflect.Type, value interface{}) (interface{}, error) {
+	var err error
+	if err = v.Unmarshal(&cfg, viper.DecodeHook(func(in reflect.Type, out reflect.Type, value interface{}) (interface{}, error) {
 		if in.String() == "string" && out.String() == "zerolog.Level" {
 			input := value.(string)
 			return zerolog.ParseLevel(input)
@@ -147,6 +252,66 @@ func NewWithViper(v *viper.Viper) (ServerConfig, error) {
 		return cfg, errors.Wrap(err, "failed to read configuration")
 	}
 
+	if cfg.CMPPluginFileGlobs, err = ParseCMPPluginFiles(cfg.CMPPluginFiles); err != nil {
+		return cfg, errors.Wrap(err, "failed to read configuration")
+	}
+
+	if cfg.RepoPriorityLevels, err = ParseRepoPriorities(cfg.RepoPriorities); err != nil {
+		return cfg, errors.Wrap(err, "failed to read configuration")
+	}
+	for _, priority := range cfg.LabelPriorities {
+		switch priority {
+		case "low", "normal", "high":
+		default:
+			return cfg, fmt.Errorf("invalid label priority %q, must be one of low, normal or high", priority)
+		}
+	}
+
+	for _, renderer := range cfg.RendererOverrides {
+		switch renderer {
+		case "repo-server", "local":
+		default:
+			return cfg, fmt.Errorf("invalid manifest renderer override %q, must be one of repo-server or local", renderer)
+		}
+	}
+
+	if cfg.QueueStorePath != "" && cfg.RedisAddr != "" {
+		return cfg, errors.New("queue-store-path cannot be combined with redis-addr, the shared queue is already persistent")
+	}
+
+	if cfg.DashboardAddr != "" && cfg.HistoryStorePath == "" {
+		return cfg, errors.New("dashboard-addr requires history-store-path, which the dashboard shows the runs of")
+	}
+
+	if cfg.EnableAppSetSCMProviders && len(cfg.AppSetAllowedSCMProviders) == 0 {
+		return cfg, errors.New("enable-appset-scm-providers requires appset-allowed-scm-providers, since appsets in pull requests choose the URLs that credentials are sent to")
+	}
+
+	reports := make(map[string]string)
+	for _, report := range [][2]string{
+		{"report-json-file", cfg.ReportJSONFile},
+		{"report-sarif-file", cfg.ReportSARIFFile},
+		{"report-junit-file", cfg.ReportJUnitFile},
+	} {
+		flag, path := report[0], report[1]
+		if path == "" {
+			continue
+		}
+		if other, ok := reports[filepath.Clean(path)]; ok {
+			return cfg, fmt.Errorf("%s and %s are both %q, each report needs its own file", other, flag, path)
+		}
+		reports[filepath.Clean(path)] = flag
+	}
+
+	if cfg.ArgoCDInstancesFile != "" {
+		if cfg.ArgoCDOfflineAppsPath != "" {
+			return cfg, errors.New("argocd-instances-file cannot be combined with argocd-offline-apps-path")
+		}
+		if cfg.ArgoCDInstances, err = LoadArgoCDInstances(cfg.ArgoCDInstancesFile); err != nil {
+			return cfg, errors.Wrap(err, "failed to read configuration")
+		}
+	}
+
 	if cfg.VcsBaseUrl == "" {
 		cfg.VcsBaseUrl = fmt.Sprintf("https://%s.com", cfg.VcsType)
 	}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -30,3 +32,107 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, time.Minute*10, cfg.RepoRefreshInterval)
 	assert.Equal(t, []string{"default", "kube-system"}, cfg.AdditionalAppsNamespaces)
 }
+
+func TestNew_Invalid(t *testing.T) {
+	tests := map[string]map[string]string{
+		"scm providers without allow-list":   {"enable-appset-scm-providers": "true"},
+		"dashboard without history":          {"dashboard-addr": ":8081"},
+		"unknown label priority":             {"label-priorities": "low,urgent"},
+		"unknown manifest renderer override": {"manifest-renderer-overrides": "helm"},
+		"queue store with redis":             {"queue-store-path": "/tmp/queue.db", "redis-addr": "redis:6379"},
+		"duplicate report paths":             {"report-json-file": "report.json", "report-sarif-file": "./report.json"},
+	}
+
+	for name, settings := range tests {
+		t.Run(name, func(t *testing.T) {
+			v := viper.New()
+			v.Set("log-level", "info")
+			for key, value := range settings {
+				v.Set(key, value)
+			}
+
+			_, err := NewWithViper(v)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseCMPPluginFiles(t *testing.T) {
+	globs, err := ParseCMPPluginFiles([]string{"jsonnet=jsonnetfile.json", " jsonnet=/lib/**", "", "cue=*.cue"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"jsonnet": {"jsonnetfile.json", "/lib/**"},
+		"cue":     {"*.cue"},
+	}, globs)
+
+	_, err = ParseCMPPluginFiles([]string{"lib/**"})
+	assert.Error(t, err)
+
+	_, err = ParseCMPPluginFiles([]string{"jsonnet="})
+	assert.Error(t, err)
+}
+
+func TestParseRepoPriorities(t *testing.T) {
+	priorities, err := ParseRepoPriorities([]string{"zapier/kubechecks=high", " zapier/docs=low", ""})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"zapier/kubechecks": "high",
+		"zapier/docs":       "low",
+	}, priorities)
+
+	_, err = ParseRepoPriorities([]string{"zapier/kubechecks"})
+	assert.Error(t, err)
+
+	_, err = ParseRepoPriorities([]string{"zapier/kubechecks=urgent"})
+	assert.Error(t, err)
+}
+
+func TestLoadArgoCDInstances(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "instances.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	t.Setenv("EU_TOKEN", "secret")
+	instances, err := LoadArgoCDInstances(write(t, `
+instances:
+  - name: us
+    apiServerAddr: argocd.us.example.com
+    token: us-token
+    insecure: false
+  - name: eu
+    apiServerAddr: argocd.eu.example.com
+    tokenEnv: EU_TOKEN
+    namespace: gitops
+    plainText: true
+`))
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, "us-token", instances[0].Token)
+	assert.Equal(t, "secret", instances[1].Token)
+
+	global := ServerConfig{ArgoCDServerAddr: "argocd.example.com", ArgoCDNamespace: "argocd", ArgoCDInsecure: true, KubernetesType: "local"}
+	cfg := global.ForArgoCDInstance(instances[1])
+	assert.Equal(t, "argocd.eu.example.com", cfg.ArgoCDServerAddr)
+	assert.Equal(t, "secret", cfg.ArgoCDToken)
+	assert.Equal(t, "gitops", cfg.ArgoCDNamespace)
+	assert.Equal(t, "local", cfg.KubernetesType, "empty settings fall back to the global ones")
+	assert.True(t, cfg.ArgoCDPlainText)
+	assert.True(t, cfg.ArgoCDInsecure, "unset settings fall back to the global ones")
+	assert.False(t, global.ForArgoCDInstance(instances[0]).ArgoCDInsecure, "settings that are set override the global ones")
+	assert.Equal(t, "argocd.example.com", global.ArgoCDServerAddr, "the global config must not be modified")
+
+	for name, content := range map[string]string{
+		"empty":          "instances: []\n",
+		"no name":        "instances:\n  - apiServerAddr: argocd\n",
+		"slash in name":  "instances:\n  - name: us/east\n",
+		"duplicate name": "instances:\n  - name: us\n  - name: us\n",
+		"unknown field":  "instances:\n  - name: us\n    server: argocd\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadArgoCDInstances(write(t, content))
+			assert.Error(t, err)
+		})
+	}
+}
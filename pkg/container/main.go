@@ -9,13 +9,19 @@ import (
 	client "github.com/zapier/kubechecks/pkg/kubernetes"
 	"github.com/zapier/kubechecks/pkg/vcs/github_client"
 	"github.com/zapier/kubechecks/pkg/vcs/gitlab_client"
+	"github.com/zapier/kubechecks/pkg/vcs/local_client"
 	"go.opentelemetry.io/otel"
 
 	"github.com/zapier/kubechecks/pkg/appdir"
 	"github.com/zapier/kubechecks/pkg/archive"
 	"github.com/zapier/kubechecks/pkg/argo_client"
+	"github.com/zapier/kubechecks/pkg/checkcache"
 	"github.com/zapier/kubechecks/pkg/config"
 	"github.com/zapier/kubechecks/pkg/git"
+	"github.com/zapier/kubechecks/pkg/history"
+	"github.com/zapier/kubechecks/pkg/limiter"
+	"github.com/zapier/kubechecks/pkg/manifestcache"
+	"github.com/zapier/kubechecks/pkg/revalidate"
 	"github.com/zapier/kubechecks/pkg/vcs"
 )
 
@@ -33,6 +39,83 @@ type Container struct {
 	VcsToArgoMap appdir.VcsToArgoMap
 
 	KubeClientSet client.Interface
+
+	// AILimit bounds the calls to the AI provider across every check.
+	AILimit *limiter.Semaphore
+
+	// ManifestCache holds the rendered manifests of every Argo CD instance, or is nil when they are not cached.
+	ManifestCache *manifestcache.Cache
+
+	// CheckCache holds the check results of the apps of each PR, or nil when they are not reused.
+	CheckCache *checkcache.Store
+
+	// History records every run for the dashboard, or is nil when runs are not recorded.
+	History *history.Store
+
+	// Revalidation watches the PRs that were checked and queues them again when their report got stale, or is nil
+	// when they are not re-validated.
+	Revalidation *revalidate.Scheduler
+
+	// ArgoInstances lists every Argo CD instance when more than one is configured. ArgoClient, KubeClientSet and
+	// VcsToArgoMap are those of the first one.
+	ArgoInstances []ArgoInstance
+
+	instanceName string
+}
+
+// ArgoInstance holds everything that is specific to a single Argo CD installation.
+type ArgoInstance struct {
+	Name   string
+	Config config.ServerConfig
+
+	ArgoClient    *argo_client.ArgoClient
+	KubeClientSet client.Interface
+	VcsToArgoMap  appdir.VcsToArgoMap
+}
+
+// ForInstance returns a copy of the container that talks to the named Argo CD instance. The container itself is
+// returned when the instance is unknown, which includes the default instance's empty name.
+func (ctr Container) ForInstance(name string) Container {
+	for _, instance := range ctr.ArgoInstances {
+		if instance.Name == name {
+			ctr.useInstance(instance)
+			break
+		}
+	}
+	return ctr
+}
+
+// InstanceContainers returns a container for each Argo CD instance, or just the container itself when there is only
+// one instance.
+func (ctr Container) InstanceContainers() []Container {
+	if len(ctr.ArgoInstances) == 0 {
+		return []Container{ctr}
+	}
+
+	containers := make([]Container, 0, len(ctr.ArgoInstances))
+	for _, instance := range ctr.ArgoInstances {
+		containers = append(containers, ctr.ForInstance(instance.Name))
+	}
+	return containers
+}
+
+// InstanceName returns the name of the Argo CD instance the container talks to, or "" when there is only one.
+func (ctr Container) InstanceName() string {
+	return ctr.instanceName
+}
+
+func (ctr *Container) useInstance(instance ArgoInstance) {
+	ctr.instanceName = instance.Name
+	ctr.ArgoClient = instance.ArgoClient
+	ctr.KubeClientSet = instance.KubeClientSet
+	ctr.VcsToArgoMap = instance.VcsToArgoMap
+
+	// only the argocd and kubernetes settings differ between instances
+	if instance.Name != "" {
+		argoInstances := ctr.Config.ArgoCDInstances
+		ctr.Config = instance.Config
+		ctr.Config.ArgoCDInstances = argoInstances
+	}
 }
 
 type ReposCache interface {
@@ -49,6 +132,7 @@ func New(ctx context.Context, cfg config.ServerConfig) (Container, error) {
 	var ctr = Container{
 		Config:      cfg,
 		RepoManager: git.NewRepoManager(cfg),
+		AILimit:     limiter.New("ai-provider", cfg.AIReviewProvider, cfg.MaxAICalls),
 	}
 
 	// create vcs client
@@ -57,6 +141,8 @@ func New(ctx context.Context, cfg config.ServerConfig) (Container, error) {
 		ctr.VcsClient, err = gitlab_client.CreateGitlabClient(ctx, cfg)
 	case "github":
 		ctr.VcsClient, err = github_client.CreateGithubClient(ctx, cfg)
+	case "local":
+		ctr.VcsClient = local_client.CreateLocalClient(cfg)
 	default:
 		err = fmt.Errorf("unknown vcs-type: %q", cfg.VcsType)
 	}
@@ -64,11 +150,78 @@ func New(ctx context.Context, cfg config.ServerConfig) (Container, error) {
 		return ctr, errors.Wrap(err, "failed to create vcs client")
 	}
 
+	if cfg.ManifestCacheDir != "" {
+		if ctr.ManifestCache, err = manifestcache.NewCache(manifestcache.Config{
+			Dir:     cfg.ManifestCacheDir,
+			TTL:     cfg.ManifestCacheTTL,
+			MaxSize: cfg.ManifestCacheMaxSizeMB * 1024 * 1024,
+		}); err != nil {
+			return ctr, errors.Wrap(err, "failed to create manifest cache")
+		}
+	}
+
+	if cfg.CheckCacheDir != "" {
+		if ctr.CheckCache, err = checkcache.NewStore(checkcache.Config{
+			Dir: cfg.CheckCacheDir,
+			TTL: cfg.CheckCacheTTL,
+		}); err != nil {
+			return ctr, errors.Wrap(err, "failed to create check cache")
+		}
+	}
+
+	if cfg.HistoryStorePath != "" {
+		if ctr.History, err = history.NewStore(history.Config{
+			Path:      cfg.HistoryStorePath,
+			Retention: cfg.HistoryRetention,
+		}); err != nil {
+			return ctr, errors.Wrap(err, "failed to open history store")
+		}
+	}
+
+	if cfg.RevalidateInterval > 0 {
+		ctr.Revalidation = revalidate.New(revalidate.Config{
+			Interval:  cfg.RevalidateInterval,
+			LiveState: cfg.RevalidateLiveState,
+			MaxAge:    cfg.RevalidateMaxAge,
+		})
+	}
+
 	// Initialize archive manager for VCS archive downloads
 	log.Info().Msg("initializing archive manager for VCS archive downloads")
 	ctr.ArchiveManager = archive.NewManager(cfg, ctr.VcsClient)
 
+	if len(cfg.ArgoCDInstances) == 0 {
+		instance, err := newArgoInstance(ctx, "", cfg, ctr.VcsClient.Username(), ctr.ManifestCache)
+		if err != nil {
+			return ctr, err
+		}
+		ctr.useInstance(instance)
+		return ctr, nil
+	}
+
+	for _, settings := range cfg.ArgoCDInstances {
+		log.Info().Str("instance", settings.Name).Msg("connecting to argocd instance")
+		instance, err := newArgoInstance(ctx, settings.Name, cfg.ForArgoCDInstance(settings), ctr.VcsClient.Username(), ctr.ManifestCache)
+		if err != nil {
+			return ctr, errors.Wrapf(err, "argocd instance %q", settings.Name)
+		}
+		ctr.ArgoInstances = append(ctr.ArgoInstances, instance)
+	}
+
+	// the first instance is the default, for everything that is not specific to an app
+	ctr.useInstance(ctr.ArgoInstances[0])
+
+	return ctr, nil
+}
+
+// newArgoInstance connects to one Argo CD installation, and builds its map of apps when they are monitored.
+func newArgoInstance(
+	ctx context.Context, name string, cfg config.ServerConfig, vcsUsername string, manifestCache *manifestcache.Cache,
+) (ArgoInstance, error) {
+	instance := ArgoInstance{Name: name, Config: cfg}
+
 	var kubeClient client.Interface
+	var err error
 
 	switch cfg.KubernetesType {
 	// TODO: expand with other cluster types
@@ -78,7 +231,7 @@ func New(ctx context.Context, cfg config.ServerConfig) (Container, error) {
 			ClusterType:          cfg.KubernetesType,
 		})
 		if err != nil {
-			return ctr, errors.Wrap(err, "failed to create kube client")
+			return instance, errors.Wrap(err, "failed to create kube client")
 		}
 	case client.ClusterTypeEKS:
 		kubeClient, err = client.New(&client.NewClientInput{
@@ -88,30 +241,35 @@ func New(ctx context.Context, cfg config.ServerConfig) (Container, error) {
 			client.EKSClientOption(ctx, cfg.KubernetesClusterID),
 		)
 		if err != nil {
-			return ctr, errors.Wrap(err, "failed to create kube client")
+			return instance, errors.Wrap(err, "failed to create kube client")
 		}
 	}
-	ctr.KubeClientSet = kubeClient
+	instance.KubeClientSet = kubeClient
 	// create argo client
-	if ctr.ArgoClient, err = argo_client.NewArgoClient(cfg, kubeClient); err != nil {
-		return ctr, errors.Wrap(err, "failed to create argo client")
+	if instance.ArgoClient, err = argo_client.NewArgoClient(cfg, kubeClient, manifestCache); err != nil {
+		return instance, errors.Wrap(err, "failed to create argo client")
 	}
 
 	// create vcs to argo map
-	vcsToArgoMap := appdir.NewVcsToArgoMap(ctr.VcsClient.Username())
-	ctr.VcsToArgoMap = vcsToArgoMap
+	instance.VcsToArgoMap = appdir.NewVcsToArgoMap(vcsUsername)
 
-	if cfg.MonitorAllApplications {
-		if err = buildAppsMap(ctx, ctr.ArgoClient, ctr.VcsToArgoMap); err != nil {
+	// offline applications are static: they are added to the map when they were loaded at startup,
+	// and otherwise are loaded from each repository as it is checked
+	buildMaps := cfg.MonitorAllApplications
+	if instance.ArgoClient.IsOffline() {
+		buildMaps = instance.ArgoClient.HasApplications()
+	}
+	if buildMaps {
+		if err = buildAppsMap(ctx, instance.ArgoClient, instance.VcsToArgoMap); err != nil {
 			log.Fatal().Err(err).Msg("failed to build apps map")
 		}
 
-		if err = buildAppSetsMap(ctx, ctr.ArgoClient, ctr.VcsToArgoMap); err != nil {
+		if err = buildAppSetsMap(ctx, instance.ArgoClient, instance.VcsToArgoMap); err != nil {
 			log.Fatal().Err(err).Msg("failed to build appsets map")
 		}
 	}
 
-	return ctr, nil
+	return instance, nil
 }
 
 func buildAppsMap(ctx context.Context, argoClient *argo_client.ArgoClient, result appdir.VcsToArgoMap) error {
@@ -144,4 +302,9 @@ func (c *Container) Shutdown() {
 	if c.ArchiveManager != nil {
 		c.ArchiveManager.Shutdown()
 	}
+	if c.History != nil {
+		if err := c.History.Close(); err != nil {
+			log.Error().Err(err).Msg("failed to close history store")
+		}
+	}
 }
@@ -0,0 +1,46 @@
+package container
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zapier/kubechecks/pkg/appdir"
+	"github.com/zapier/kubechecks/pkg/config"
+)
+
+func TestContainer_ForInstance(t *testing.T) {
+	us := ArgoInstance{
+		Name:         "us",
+		Config:       config.ServerConfig{ArgoCDServerAddr: "argocd.us", Identifier: "us"},
+		VcsToArgoMap: appdir.NewVcsToArgoMap("us"),
+	}
+	eu := ArgoInstance{
+		Name:         "eu",
+		Config:       config.ServerConfig{ArgoCDServerAddr: "argocd.eu", Identifier: "eu"},
+		VcsToArgoMap: appdir.NewVcsToArgoMap("eu"),
+	}
+
+	ctr := Container{
+		Config:        config.ServerConfig{ArgoCDInstances: []config.ArgoCDInstance{{Name: "us"}, {Name: "eu"}}},
+		ArgoInstances: []ArgoInstance{us, eu},
+	}
+	ctr.useInstance(us)
+	assert.Equal(t, "us", ctr.InstanceName())
+
+	euCtr := ctr.ForInstance("eu")
+	assert.Equal(t, "eu", euCtr.InstanceName())
+	assert.Equal(t, "argocd.eu", euCtr.Config.ArgoCDServerAddr)
+	assert.Len(t, euCtr.Config.ArgoCDInstances, 2)
+	assert.Equal(t, "us", ctr.InstanceName(), "the original container must not be modified")
+
+	assert.Equal(t, "us", ctr.ForInstance("").InstanceName(), "unknown instances use the container itself")
+
+	var names []string
+	for _, c := range ctr.InstanceContainers() {
+		names = append(names, c.InstanceName())
+	}
+	assert.Equal(t, []string{"us", "eu"}, names)
+
+	assert.Len(t, Container{}.InstanceContainers(), 1)
+}
@@ -3,7 +3,10 @@ package events
 import (
 	"context"
 	"fmt"
+	"io"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -21,10 +24,14 @@ import (
 
 	"github.com/zapier/kubechecks/pkg"
 	"github.com/zapier/kubechecks/pkg/affected_apps"
+	"github.com/zapier/kubechecks/pkg/appdir"
+	"github.com/zapier/kubechecks/pkg/argo_client"
 	"github.com/zapier/kubechecks/pkg/checks"
 	"github.com/zapier/kubechecks/pkg/container"
 	"github.com/zapier/kubechecks/pkg/git"
 	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/report"
+	"github.com/zapier/kubechecks/pkg/revalidate"
 	"github.com/zapier/kubechecks/pkg/vcs"
 	"github.com/zapier/kubechecks/telemetry"
 )
@@ -59,6 +66,8 @@ type CheckEvent struct {
 	aiNote      *msg.Message // separate comment for AI review
 
 	affectedItems affected_apps.AffectedItems
+	// appSetPreviews lists the apps each affected appset will create, delete and update, keyed by appset name
+	appSetPreviews map[string]generator.AppSetPreview
 
 	ctr             container.Container
 	repoManager     repoManager
@@ -75,7 +84,18 @@ type CheckEvent struct {
 	aiReviewCount       int32 // atomic counter for AI reviews claimed
 	aiReviewSkipped     int32 // atomic counter for AI reviews skipped due to cap
 
+	policyVersionOnce sync.Once
+	policyVersion     string
+	policyVersionErr  error
+
+	// baseline is what the check saw of the inputs that are re-validated, see startRevalidation.
+	baseline     revalidate.Baseline
+	baselineLock sync.Mutex
+
 	appsSent   int32
+	appsDone   int32
+	started    time.Time
+	runID      uint64 // the ID of the run in the history, 0 when it is not recorded
 	appChannel chan *v1alpha1.Application
 	wg         sync.WaitGroup
 	generator  generator.AppsGenerator
@@ -87,20 +107,63 @@ type repoManager interface {
 }
 
 func generateMatcher(ce *CheckEvent, repo *git.Repo) error {
-	log.Debug().Caller().Msg("using the argocd matcher")
-	m, err := affected_apps.NewArgocdMatcher(ce.ctr.VcsToArgoMap, repo)
-	if err != nil {
-		return errors.Wrap(err, "failed to create argocd matcher")
+	if err := ce.loadOfflineApplications(repo); err != nil {
+		return err
+	}
+
+	var matchers []affected_apps.Matcher
+	for _, ctr := range ce.ctr.InstanceContainers() {
+		log.Debug().Caller().Str("instance", ctr.InstanceName()).Msg("using the argocd matcher")
+		m, err := affected_apps.NewArgocdMatcher(ctr.VcsToArgoMap, repo, ctr.Config.CMPPluginFileGlobs)
+		if err != nil {
+			return errors.Wrap(err, "failed to create argocd matcher")
+		}
+		matchers = append(matchers, instanceMatcher(ctr, m))
 	}
-	ce.matcher = m
+
 	cfg, err := repo_config.LoadRepoConfig(repo.Directory)
 	if err != nil {
 		return errors.Wrap(err, "failed to load repo config")
 	} else if cfg != nil {
 		log.Debug().Caller().Msg("using the config matcher")
-		configMatcher := affected_apps.NewConfigMatcher(cfg, ce.ctr)
-		ce.matcher = affected_apps.NewMultiMatcher(ce.matcher, configMatcher)
+		matchers = append(matchers, instanceMatcher(ce.ctr, affected_apps.NewConfigMatcher(cfg, ce.ctr)))
+	}
+
+	ce.matcher = affected_apps.NewMultiMatcher(matchers...)
+	return nil
+}
+
+// instanceMatcher tags the items found by a matcher with the Argo CD instance of the container, if there is more than one.
+func instanceMatcher(ctr container.Container, m affected_apps.Matcher) affected_apps.Matcher {
+	if ctr.InstanceName() == "" {
+		return m
+	}
+	return affected_apps.NewInstanceMatcher(ctr.InstanceName(), m)
+}
+
+// loadOfflineApplications replaces the Argo CD applications of this event with the ones committed to the repository,
+// when kubechecks runs in offline mode with a path that is relative to the repository.
+func (ce *CheckEvent) loadOfflineApplications(repo *git.Repo) error {
+	path := ce.ctr.Config.ArgoCDOfflineAppsPath
+	if path == "" || filepath.IsAbs(path) {
+		return nil
+	}
+
+	apps, appSets, err := argo_client.LoadApplications(filepath.Join(repo.Directory, path))
+	if err != nil {
+		return errors.Wrap(err, "failed to load offline applications")
+	}
+
+	// ce.ctr is a copy, so this does not leak into other events
+	ce.ctr.ArgoClient = ce.ctr.ArgoClient.WithApplications(apps, appSets)
+	ce.ctr.VcsToArgoMap = appdir.NewVcsToArgoMap(ce.ctr.VcsClient.Username())
+	for i := range apps.Items {
+		ce.ctr.VcsToArgoMap.AddApp(&apps.Items[i])
+	}
+	for i := range appSets.Items {
+		ce.ctr.VcsToArgoMap.AddAppSet(&appSets.Items[i])
 	}
+
 	return nil
 }
 
@@ -115,16 +178,42 @@ func NewCheckEvent(pullRequest vcs.PullRequest, ctr container.Container, repoMan
 		aiReviewChecker: aiReviewChecker,
 		pullRequest:     pullRequest,
 		repoManager:     repoManager,
-		generator:       generator.New(),
+		started:         time.Now(),
 		logger: log.Logger.With().
 			Str("repo", pullRequest.Name).
 			Int("event_id", pullRequest.CheckID).
 			Logger(),
 	}
+	ce.generator = generator.New(generator.NewLocalRepos(ce.getGeneratorRepo))
 
 	return ce
 }
 
+// getGeneratorRepo returns the checkout that appset git generators read from. Generators that read the target
+// branch of the pull request read its merged contents instead, so that appsets preview the change.
+func (ce *CheckEvent) getGeneratorRepo(ctx context.Context, repoURL, revision string) (*git.Repo, error) {
+	if isPullRequestRepo(repoURL, ce.pullRequest.CloneURL) {
+		switch strings.TrimSpace(revision) {
+		case "", "HEAD", ce.pullRequest.BaseRef:
+			return ce.getRepo(ctx, ce.pullRequest.CloneURL, ce.pullRequest.HeadRef)
+		}
+	}
+
+	return ce.getRepo(ctx, repoURL, revision)
+}
+
+func isPullRequestRepo(repoURL, cloneURL string) bool {
+	repo, err := canonicalize(repoURL)
+	if err != nil {
+		return false
+	}
+	pr, err := canonicalize(cloneURL)
+	if err != nil {
+		return false
+	}
+	return repo == pr
+}
+
 func (ce *CheckEvent) UpdateListOfChangedFiles(ctx context.Context, repo *git.Repo) error {
 	ctx, span := tracer.Start(ctx, "CheckEventGetListOfChangedFiles")
 	defer span.End()
@@ -160,23 +249,36 @@ func (ce *CheckEvent) GenerateListOfAffectedApps(ctx context.Context, repo *git.
 		ce.logger.Error().Caller().Err(err).Msg("could not get list of affected apps and appsets")
 	}
 	for _, appSet := range ce.affectedItems.ApplicationSets {
-		apps, err := ce.generator.GenerateApplicationSetApps(ctx, appSet, &ce.ctr)
+		// appsets are expanded by the Argo CD instance they were found in
+		instance := pkg.ArgoCDInstanceOf(appSet.ObjectMeta)
+		ctr := ce.ctr.ForInstance(instance)
+		apps, err := ce.generator.GenerateApplicationSetApps(ctx, appSet, &ctr)
 		if err != nil {
 			ce.logger.Error().Caller().Err(err).Msg("could not generate apps from appSet")
 			continue
 		}
 
 		// Build a set of appset-generated app names for fast lookup.
+		appSetName := pkg.QualifiedName(appSet.ObjectMeta)
+		ce.previewAppSet(ctx, ctr, appSetName, appSet, apps)
+
 		generatedNames := make(map[string]struct{}, len(apps))
-		for _, a := range apps {
-			generatedNames[a.Name] = struct{}{}
+		for i := range apps {
+			apps[i].ObjectMeta = pkg.WithArgoCDInstance(apps[i].ObjectMeta, instance)
+			name := pkg.QualifiedName(apps[i].ObjectMeta)
+			generatedNames[name] = struct{}{}
+
+			ce.affectedItems.AddAppReason(name, fmt.Sprintf("the app is generated by appset `%s`", appSet.Name))
+			for _, reason := range ce.affectedItems.AppSetReasons[appSetName] {
+				ce.affectedItems.AddAppReason(name, fmt.Sprintf("appset `%s`: %s", appSet.Name, reason))
+			}
 		}
 
 		// Remove matcher-found apps that the appset generator also produced,
 		// so the appset-generated version (which reflects PR template changes) wins.
 		filtered := ce.affectedItems.Applications[:0]
 		for _, existing := range ce.affectedItems.Applications {
-			if _, ok := generatedNames[existing.Name]; ok {
+			if _, ok := generatedNames[pkg.QualifiedName(existing.ObjectMeta)]; ok {
 				ce.logger.Debug().Caller().Msgf("replacing matcher app %s with appset-generated version", existing.Name)
 				continue
 			}
@@ -192,15 +294,53 @@ func (ce *CheckEvent) GenerateListOfAffectedApps(ctx context.Context, repo *git.
 		attribute.String("affectedAppSets", fmt.Sprintf("%+v", ce.affectedItems.ApplicationSets)),
 	)
 	for _, app := range ce.affectedItems.Applications {
-		ce.logger.Debug().Caller().Msgf("Affected apps: %+v", app.Name)
+		name := pkg.QualifiedName(app.ObjectMeta)
+		ce.logger.Debug().Caller().Strs("reasons", ce.affectedItems.AppReasons[name]).Msgf("Affected apps: %+v", name)
 	}
 	for _, appset := range ce.affectedItems.ApplicationSets {
-		ce.logger.Debug().Caller().Msgf("Affected appSets: %+v", appset.Name)
+		name := pkg.QualifiedName(appset.ObjectMeta)
+		ce.logger.Debug().Caller().Strs("reasons", ce.affectedItems.AppSetReasons[name]).Msgf("Affected appSets: %+v", name)
 	}
 
 	return err
 }
 
+// previewAppSet compares the apps generated by an appset with the apps it owns in Argo CD. Failing to do so is logged,
+// as the generated apps are still checked.
+func (ce *CheckEvent) previewAppSet(ctx context.Context, ctr container.Container, name string, appSet v1alpha1.ApplicationSet, generated []v1alpha1.Application) {
+	if ctr.ArgoClient == nil {
+		return
+	}
+
+	live, err := ctr.ArgoClient.GetApplicationsByAppset(ctx, appSet.Name)
+	if err != nil {
+		ce.logger.Warn().Caller().Err(err).Str("appset", name).Msg("could not get the apps of appSet, skipping the preview")
+		return
+	}
+
+	preview, err := generator.PreviewAppSet(appSet, generated, live.Items)
+	if err != nil {
+		ce.logger.Warn().Caller().Err(err).Str("appset", name).Msg("could not preview appSet")
+		return
+	}
+
+	if !preview.HasChanges() {
+		return
+	}
+
+	if ce.appSetPreviews == nil {
+		ce.appSetPreviews = make(map[string]generator.AppSetPreview)
+	}
+	ce.appSetPreviews[name] = preview
+}
+
+// addAppSetPreviews adds the appset previews to the comment, above the apps.
+func (ce *CheckEvent) addAppSetPreviews() {
+	for name, preview := range ce.appSetPreviews {
+		ce.vcsNote.AddAppSetPreview(name, preview.Result())
+	}
+}
+
 func canonicalize(cloneURL string) (pkg.RepoURL, error) {
 	parsed, _, err := pkg.NormalizeRepoUrl(cloneURL)
 	if err != nil {
@@ -276,14 +416,18 @@ func (ce *CheckEvent) getRepo(ctx context.Context, cloneURL, branchName string)
 	return repo, nil
 }
 
-func (ce *CheckEvent) Process(ctx context.Context) error {
+func (ce *CheckEvent) Process(ctx context.Context) (err error) {
 	start := time.Now()
 
+	// runs that fail or are interrupted leave a report too, with the results they got to
+	defer func() { ce.writeReports(ctx, start, err) }()
+
+	ce.startRevalidation(ctx)
+
 	_, span := tracer.Start(ctx, "GenerateListOfAffectedApps")
 	defer span.End()
 
 	var repo *git.Repo
-	var err error
 
 	// Archive mode: Download merge commit archive from VCS
 	ce.logger.Info().Msg("using archive mode for PR processing")
@@ -366,6 +510,9 @@ func (ce *CheckEvent) Process(ctx context.Context) error {
 		return errors.Wrap(err, "failed to create note")
 	}
 
+	ce.startRun(start)
+	defer func() { ce.finishRun(ctx, start, err) }()
+
 	// Create a separate placeholder comment for AI review
 	if ce.ctr.Config.EnableAIReview {
 		ce.aiNote, err = ce.createAIReviewNote(ctx)
@@ -375,42 +522,21 @@ func (ce *CheckEvent) Process(ctx context.Context) error {
 		}
 	}
 
-	for num := 0; num <= ce.ctr.Config.MaxConcurrentChecks; num++ {
-
-		w := worker{
-			appChannel:      ce.appChannel,
-			ctr:             ce.ctr,
-			logger:          ce.logger.With().Int("workerID", num).Logger(),
-			pullRequest:     ce.pullRequest,
-			processors:      ce.processors,
-			aiReviewChecker: ce.aiReviewChecker,
-			vcsNote:         ce.vcsNote,
-			changedFiles:    ce.fileList,
+	ce.addAppSetPreviews()
+	ce.checkApps(ctx)
 
-			done:              ce.wg.Done,
-			getRepo:           ce.getRepo,
-			queueApp:          ce.queueApp,
-			removeApp:         ce.removeApp,
-			addAIReviewResult: ce.addAIReviewResult,
-			claimAIReviewSlot: ce.claimAIReviewSlot,
-		}
-		go w.run(ctx)
+	if sha, ok := supersededBy(ctx); ok {
+		ce.logger.Info().Str("superseded_by", sha).Msg("Cancelled, a newer commit was pushed")
+		return ce.markSuperseded(ctx, sha)
 	}
-	ce.logger.Info().Msgf("adding %d apps to the queue", len(ce.affectedItems.Applications))
-	// Produce apps onto channel
-	for _, app := range ce.affectedItems.Applications {
-		ce.queueApp(app)
+	if cancelled(ctx) {
+		ce.logger.Info().Msg("Cancelled on request")
+		return ce.markCancelled(ctx)
+	}
+	if lockLost(ctx) {
+		ce.logger.Warn().Msg("Cancelled, another replica took over the pull request")
+		return ce.markInterrupted(ctx, ":arrows_counterclockwise: handed over to another replica")
 	}
-
-	ce.wg.Wait()
-
-	close(ce.appChannel)
-
-	ce.logger.Debug().
-		Caller().
-		Int("all apps", len(ce.addedAppsSet)).
-		Int32("sent apps", ce.appsSent).
-		Msg("completed apps")
 
 	ce.logger.Info().Msg("Finished")
 
@@ -423,6 +549,7 @@ func (ce *CheckEvent) Process(ctx context.Context) error {
 	if err = ce.ctr.VcsClient.UpdateMessage(ctx, ce.vcsNote, comment); err != nil {
 		return errors.Wrap(err, "failed to push comment")
 	}
+	ce.trackRevalidation(comment)
 
 	worstStatus := ce.vcsNote.WorstState()
 
@@ -450,17 +577,233 @@ func (ce *CheckEvent) Process(ctx context.Context) error {
 	return nil
 }
 
+// ProcessLocal runs every check against a local checkout, comparing its HEAD to the base ref of the pull request.
+// Nothing is posted to the VCS, the results are returned as a report instead.
+func (ce *CheckEvent) ProcessLocal(ctx context.Context, repo *git.Repo) (*report.Report, error) {
+	start := time.Now()
+
+	ctx, span := tracer.Start(ctx, "ProcessLocal")
+	defer span.End()
+
+	if err := ce.useLocalCheckout(repo); err != nil {
+		return nil, err
+	}
+
+	if err := ce.UpdateListOfChangedFiles(ctx, repo); err != nil {
+		return nil, errors.Wrap(err, "failed to get list of changed files")
+	}
+
+	if err := ce.GenerateListOfAffectedApps(ctx, repo, ce.pullRequest.BaseRef, generateMatcher); err != nil {
+		return nil, errors.Wrap(err, "failed to generate a list of affected apps")
+	}
+
+	ce.vcsNote = msg.NewMessage(ce.pullRequest.FullName, ce.pullRequest.CheckID, 0, ce.ctr.VcsClient)
+	ce.addAppSetPreviews()
+	if len(ce.affectedItems.Applications) > 0 {
+		ce.checkApps(ctx)
+	} else {
+		ce.logger.Info().Msg("No affected apps, skipping")
+	}
+
+	r := report.New(
+		ce.pullRequest, ce.ctr.Config.Identifier, ce.affectedItems,
+		ce.vcsNote.Snapshot(), start, time.Now(),
+	)
+	r.SetParents(ce.vcsNote.Parents())
+
+	return r, nil
+}
+
+// AffectedLocal finds the apps and appsets in a local checkout that are affected by the given files, using the
+// same matchers as a pull request would. No checks are run.
+func (ce *CheckEvent) AffectedLocal(ctx context.Context, repo *git.Repo, files []string) (affected_apps.AffectedItems, error) {
+	ctx, span := tracer.Start(ctx, "AffectedLocal")
+	defer span.End()
+
+	if err := ce.useLocalCheckout(repo); err != nil {
+		return affected_apps.AffectedItems{}, err
+	}
+
+	ce.logger.Debug().Msgf("Changed files: %s", strings.Join(files, ","))
+	ce.fileList = files
+
+	if err := ce.GenerateListOfAffectedApps(ctx, repo, ce.pullRequest.BaseRef, generateMatcher); err != nil {
+		return affected_apps.AffectedItems{}, errors.Wrap(err, "failed to generate a list of affected apps")
+	}
+
+	return ce.affectedItems, nil
+}
+
+// useLocalCheckout stores the checkout under every ref it can be asked for, so that getRepo() never clones it.
+func (ce *CheckEvent) useLocalCheckout(repo *git.Repo) error {
+	parsed, err := canonicalize(ce.pullRequest.CloneURL)
+	if err != nil {
+		return errors.Wrap(err, "failed to canonicalize clone URL")
+	}
+
+	ce.repoLock.Lock()
+	defer ce.repoLock.Unlock()
+
+	for _, ref := range []string{ce.pullRequest.HeadRef, ce.pullRequest.BaseRef, "HEAD"} {
+		ce.clonedRepos[generateRepoKey(parsed, ref)] = repo
+	}
+
+	return nil
+}
+
+// writeReports writes the machine-readable reports that have been requested. Failing to write
+// a report is logged, but does not change the outcome of the check.
+func (ce *CheckEvent) writeReports(ctx context.Context, start time.Time, err error) {
+	cfg := ce.ctr.Config
+	if cfg.ReportJSONFile == "" && cfg.ReportSARIFFile == "" && cfg.ReportJUnitFile == "" {
+		return
+	}
+
+	var results map[string][]msg.Result
+	if ce.vcsNote != nil {
+		results = ce.vcsNote.Snapshot()
+	}
+
+	r := report.New(ce.pullRequest, cfg.Identifier, ce.affectedItems, results, start, time.Now())
+	if ce.vcsNote != nil {
+		r.SetParents(ce.vcsNote.Parents())
+	}
+	r.SetStatus(string(runStatus(ctx, err)), err)
+
+	for _, out := range []struct {
+		path  string
+		write func(*report.Report, io.Writer) error
+	}{
+		{cfg.ReportJSONFile, (*report.Report).WriteJSON},
+		{cfg.ReportSARIFFile, (*report.Report).WriteSARIF},
+		{cfg.ReportJUnitFile, (*report.Report).WriteJUnit},
+	} {
+		path := out.path
+		if path == "" {
+			continue
+		}
+
+		if err := r.WriteFile(path, out.write); err != nil {
+			ce.logger.Error().Caller().Err(err).Str("path", path).Msg("failed to write report")
+			continue
+		}
+		ce.logger.Info().Str("path", path).Msg("wrote report")
+	}
+}
+
+// checkApps runs every processor against the affected apps, and any apps they queue, until all of them are done.
+func (ce *CheckEvent) checkApps(ctx context.Context) {
+	for num := 0; num <= ce.ctr.Config.MaxConcurrentChecks; num++ {
+
+		w := worker{
+			appChannel:      ce.appChannel,
+			ctr:             ce.ctr,
+			logger:          ce.logger.With().Int("workerID", num).Logger(),
+			pullRequest:     ce.pullRequest,
+			processors:      ce.processors,
+			aiReviewChecker: ce.aiReviewChecker,
+			vcsNote:         ce.vcsNote,
+			changedFiles:    ce.fileList,
+
+			done:              ce.appDone,
+			getRepo:           ce.getRepo,
+			queueChildApp:     ce.queueChildApp,
+			removeApp:         ce.removeApp,
+			addAIReviewResult: ce.addAIReviewResult,
+			claimAIReviewSlot: ce.claimAIReviewSlot,
+			policyVersion:     ce.checkPolicyVersion,
+			liveState:         liveStateOf,
+			recordLiveState:   ce.recordLiveState,
+		}
+		go w.run(ctx)
+	}
+	for name, reasons := range ce.affectedItems.AppReasons {
+		ce.vcsNote.AddReasons(name, reasons...)
+	}
+
+	ce.logger.Info().Msgf("adding %d apps to the queue", len(ce.affectedItems.Applications))
+	// Produce apps onto channel
+	for _, app := range ce.affectedItems.Applications {
+		ce.queueApp(app)
+	}
+
+	ce.wg.Wait()
+
+	close(ce.appChannel)
+
+	ce.logger.Debug().
+		Caller().
+		Int("all apps", len(ce.addedAppsSet)).
+		Int32("sent apps", ce.appsSent).
+		Msg("completed apps")
+}
+
 func (ce *CheckEvent) removeApp(app v1alpha1.Application) {
-	ce.logger.Info().Str("app", app.Name).Msg("removing app")
+	name := pkg.QualifiedName(app.ObjectMeta)
+	ce.logger.Info().Str("app", name).Msg("removing app")
+
+	ce.vcsNote.RemoveApp(name)
+}
+
+// queueChildApp checks an app that an app-of-apps added or changed, and shows it as a child of that app. Apps nested
+// deeper than the configured limit are listed without being checked, and apps that would render one of their own
+// parents are not checked again.
+func (ce *CheckEvent) queueChildApp(parent string, child v1alpha1.Application, change checks.ChildChange) {
+	name := pkg.QualifiedName(child.ObjectMeta)
+
+	ancestors := []string{parent}
+	for current := parent; ; {
+		next, ok := ce.vcsNote.Parent(current)
+		if !ok || slices.Contains(ancestors, next) {
+			break
+		}
+		ancestors = append(ancestors, next)
+		current = next
+	}
+
+	if name == parent {
+		// apps that manage themselves are common, and have been checked already
+		ce.logger.Debug().Caller().Str("app", name).Msg("app renders itself, not checking it again")
+		return
+	}
+
+	if slices.Contains(ancestors, name) {
+		path := append([]string{name}, ancestors[:slices.Index(ancestors, name)+1]...)
+		slices.Reverse(path)
+		ce.logger.Warn().Str("app", name).Str("parent", parent).Msg("app-of-apps cycle, not checking the app again")
+		ce.vcsNote.AddToAppMessage(context.Background(), parent, msg.Result{
+			State:   pkg.StateWarning,
+			Check:   "app-of-apps",
+			Summary: "App-of-apps cycle",
+			Details: fmt.Sprintf("app `%s` renders app `%s`, which is one of its parents: `%s`", parent, name, strings.Join(path, "` → `")),
+		})
+		return
+	}
+
+	ce.vcsNote.SetParent(name, parent)
+	ce.vcsNote.AddReasons(name, fmt.Sprintf("the app is rendered by app `%s`, and was %s", parent, change))
+
+	if maxDepth := ce.ctr.Config.MaxAppOfAppsDepth; maxDepth > 0 && len(ancestors) > maxDepth {
+		ce.logger.Info().Str("app", name).Int("depth", len(ancestors)).Msg("app-of-apps is nested too deeply, not checking the app")
+		ctx := context.Background()
+		ce.vcsNote.AddNewApp(ctx, name)
+		ce.vcsNote.AddToAppMessage(ctx, name, msg.Result{
+			State:   pkg.StateNone,
+			Check:   "app-of-apps",
+			Summary: fmt.Sprintf("Not checked, the app is nested more than %d levels deep", maxDepth),
+			Details: fmt.Sprintf("Child apps are checked up to `max-app-of-apps-depth` (%d) levels below the changed app.", maxDepth),
+		})
+		return
+	}
 
-	ce.vcsNote.RemoveApp(app.Name)
+	ce.queueApp(child)
 }
 
 func (ce *CheckEvent) queueApp(app v1alpha1.Application) {
 	ce.addedAppsSetLock.Lock()
 	defer ce.addedAppsSetLock.Unlock()
 
-	name := app.Name
+	name := pkg.QualifiedName(app.ObjectMeta)
 	dir := app.Spec.GetSource().Path
 
 	if old, ok := ce.addedAppsSet[name]; ok {
@@ -487,6 +830,35 @@ func (ce *CheckEvent) queueApp(app v1alpha1.Application) {
 	logger.Debug().Caller().Msg("finished producing app")
 }
 
+// appDone records that a worker finished checking an app.
+func (ce *CheckEvent) appDone() {
+	atomic.AddInt32(&ce.appsDone, 1)
+	ce.wg.Done()
+}
+
+// Progress is how far a check run has come.
+type Progress struct {
+	Repo      string    `json:"repo"`
+	CheckID   int       `json:"check_id"`
+	SHA       string    `json:"sha"`
+	StartedAt time.Time `json:"started_at"`
+	// Apps is the number of apps queued for checking so far, including the child apps found while checking.
+	Apps    int `json:"apps"`
+	Checked int `json:"checked"`
+}
+
+// Progress returns how far the run has come. It is safe to call while the run is processed.
+func (ce *CheckEvent) Progress() Progress {
+	return Progress{
+		Repo:      ce.pullRequest.FullName,
+		CheckID:   ce.pullRequest.CheckID,
+		SHA:       ce.pullRequest.SHA,
+		StartedAt: ce.started,
+		Apps:      int(atomic.LoadInt32(&ce.appsSent)),
+		Checked:   int(atomic.LoadInt32(&ce.appsDone)),
+	}
+}
+
 // CommitStatus sets the commit status on the MR
 // To set the PR/MR status
 func (ce *CheckEvent) CommitStatus(ctx context.Context, status pkg.CommitState) {
@@ -519,6 +891,55 @@ func (ce *CheckEvent) createNote(ctx context.Context) (*msg.Message, error) {
 	return ce.ctr.VcsClient.PostMessage(ctx, ce.pullRequest, fmt.Sprintf("## Kubechecks %s Report\n:hourglass: kubechecks running...", ce.ctr.Config.Identifier))
 }
 
+// supersededBy returns the commit that replaced this run, when the queue cancelled it because of a newer push.
+func supersededBy(ctx context.Context) (string, bool) {
+	var superseded pkg.SupersededError
+	if errors.As(context.Cause(ctx), &superseded) {
+		return superseded.SHA, true
+	}
+	return "", false
+}
+
+// cancelled is true when the run was cancelled on request, rather than because of a newer push.
+func cancelled(ctx context.Context) bool {
+	return errors.As(context.Cause(ctx), &pkg.CancelledError{})
+}
+
+// lockLost is true when the queue cancelled the run because another replica may be checking the pull request now.
+func lockLost(ctx context.Context) bool {
+	return errors.As(context.Cause(ctx), &pkg.LockLostError{})
+}
+
+// markSuperseded replaces the comments of a cancelled run, so that its partial results are not mistaken for a
+// complete check.
+func (ce *CheckEvent) markSuperseded(ctx context.Context, sha string) error {
+	return ce.markInterrupted(ctx, fmt.Sprintf(":fast_forward: superseded by %s", sha))
+}
+
+// markCancelled replaces the comments of a run that was cancelled on request.
+func (ce *CheckEvent) markCancelled(ctx context.Context) error {
+	return ce.markInterrupted(ctx, ":stop_sign: cancelled")
+}
+
+func (ce *CheckEvent) markInterrupted(ctx context.Context, status string) error {
+	// the run's context is cancelled, but the comments still need to be updated
+	ctx = context.WithoutCancel(ctx)
+
+	comment := fmt.Sprintf("## Kubechecks %s Report\n%s", ce.ctr.Config.Identifier, status)
+	if err := ce.ctr.VcsClient.UpdateMessage(ctx, ce.vcsNote, comment); err != nil {
+		return errors.Wrap(err, "failed to mark comment as interrupted")
+	}
+
+	if ce.aiNote != nil {
+		aiComment := fmt.Sprintf("## Kubechecks %s Report — AI Review\n%s", ce.ctr.Config.Identifier, status)
+		if err := ce.ctr.VcsClient.UpdateMessage(ctx, ce.aiNote, aiComment); err != nil {
+			ce.logger.Error().Caller().Err(err).Msg("failed to mark AI review comment as interrupted")
+		}
+	}
+
+	return nil
+}
+
 // createAIReviewNote creates the initial placeholder comment for the AI review.
 func (ce *CheckEvent) createAIReviewNote(ctx context.Context) (*msg.Message, error) {
 	ctx, span := otel.Tracer("check").Start(ctx, "createAIReviewNote")
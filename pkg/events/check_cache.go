@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/rs/zerolog"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/checkcache"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/msg"
+)
+
+// unchangedCheck is the check name of the result that marks the results of an app as reused.
+const unchangedCheck = "unchanged"
+
+// checkPolicyVersion identifies the checks of this run and the policies and schemas they use. It is computed once
+// per run, as policy repositories are refreshed in the background.
+func (ce *CheckEvent) checkPolicyVersion() (string, error) {
+	ce.policyVersionOnce.Do(func() {
+		var names []string
+		for _, processor := range ce.processors {
+			names = append(names, fmt.Sprintf("%s=%s", processor.Name, processor.WorstState.BareString()))
+		}
+
+		// locations of checks that don't run are not read, and may not exist
+		cfg := ce.ctr.Config
+		var locations []string
+		if cfg.EnableConfTest {
+			locations = append(locations, cfg.PoliciesLocation...)
+		}
+		if cfg.EnableKubeConform {
+			locations = append(locations, cfg.SchemasLocations...)
+		}
+		ce.policyVersion, ce.policyVersionErr = checkcache.PolicyVersion(names, locations)
+	})
+
+	return ce.policyVersion, ce.policyVersionErr
+}
+
+// liveStateOf identifies the current state of an app in the Argo CD instance of the container. The apps that were
+// found affected are not used, as their status is not kept up to date.
+func liveStateOf(ctx context.Context, ctr container.Container, app v1alpha1.Application) (string, error) {
+	return checkcache.LiveState(ctx, ctr.ArgoClient, app.Name)
+}
+
+// checkFingerprint hashes the inputs of the checks of an app. It returns "" when the results of apps are not
+// reused, or the inputs could not be identified.
+func (w *worker) checkFingerprint(ctx context.Context, app v1alpha1.Application, k8sVersion string, jsonManifests []string, logger zerolog.Logger) string {
+	if w.ctr.CheckCache == nil {
+		return ""
+	}
+
+	// apps that are not in Argo CD yet can't be read, and are checked every time
+	liveState, err := w.liveState(ctx, w.ctr, app)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to identify the live state, checking the app again")
+		return ""
+	}
+
+	policies, err := w.policyVersion()
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to identify the policies, checking the app again")
+		return ""
+	}
+
+	return checkcache.Fingerprint(checkcache.Inputs{
+		Manifests:         jsonManifests,
+		KubernetesVersion: k8sVersion,
+		LiveState:         liveState,
+		Policies:          policies,
+	})
+}
+
+// reuseResults adds the results of the last run of an app to the note if it was checked with the same fingerprint,
+// and reports whether it did.
+func (w *worker) reuseResults(ctx context.Context, appName, fingerprint string, logger zerolog.Logger) bool {
+	if fingerprint == "" {
+		return false
+	}
+
+	entry, ok := w.ctr.CheckCache.Lookup(w.pullRequest, appName, fingerprint)
+	if !ok {
+		return false
+	}
+
+	logger.Info().Str("sha", entry.SHA).Msg("inputs unchanged, reusing check results")
+
+	w.vcsNote.AddToAppMessage(ctx, appName, msg.Result{
+		State:   pkg.StateNone,
+		Check:   unchangedCheck,
+		Summary: fmt.Sprintf("Unchanged since %s", entry.SHA),
+		Details: fmt.Sprintf("The rendered manifests, live state and policies of this app are the same as when %s was checked, so its results were reused.", entry.SHA),
+	})
+	for _, result := range entry.Results {
+		w.vcsNote.AddToAppMessage(ctx, appName, result)
+	}
+
+	return true
+}
+
+// storeResults remembers the results of an app for the next run. Results that may be transient are not stored, and
+// neither are the results of an app-of-apps that changed its children, as reusing them would not check the children.
+func (w *worker) storeResults(appName, fingerprint string, changedChildren bool, logger zerolog.Logger) {
+	if fingerprint == "" {
+		return
+	}
+
+	if changedChildren {
+		logger.Debug().Caller().Msg("app changed its child apps, not storing its check results")
+		return
+	}
+
+	results, ok := w.vcsNote.Snapshot()[appName]
+	if !ok {
+		return
+	}
+	for _, result := range results {
+		if result.State >= pkg.StateError {
+			logger.Debug().Caller().Str("check", result.Check).Msg("check failed to run, not storing the check results")
+			return
+		}
+	}
+
+	entry := checkcache.Entry{
+		Fingerprint: fingerprint,
+		SHA:         w.pullRequest.SHA,
+		Created:     time.Now(),
+		Results:     results,
+	}
+	if err := w.ctr.CheckCache.Put(w.pullRequest, appName, entry); err != nil {
+		logger.Warn().Err(err).Msg("failed to store check results")
+	}
+}
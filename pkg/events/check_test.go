@@ -2,10 +2,12 @@ package events
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	gogit "github.com/go-git/go-git/v5"
 	gogitconfig "github.com/go-git/go-git/v5/config"
@@ -13,6 +15,7 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 
 	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -20,13 +23,17 @@ import (
 	affectedappsmocks "github.com/zapier/kubechecks/mocks/affected_apps/mocks"
 	generatorsmocks "github.com/zapier/kubechecks/mocks/generator/mocks"
 	vcsmocks "github.com/zapier/kubechecks/mocks/vcs/mocks"
+	"github.com/zapier/kubechecks/pkg"
 	"github.com/zapier/kubechecks/pkg/affected_apps"
+	"github.com/zapier/kubechecks/pkg/archive"
 	"github.com/zapier/kubechecks/pkg/checks"
 	"github.com/zapier/kubechecks/pkg/config"
 	"github.com/zapier/kubechecks/pkg/container"
 	"github.com/zapier/kubechecks/pkg/generator"
 	"github.com/zapier/kubechecks/pkg/git"
+	"github.com/zapier/kubechecks/pkg/history"
 	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/report"
 	"github.com/zapier/kubechecks/pkg/vcs"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -449,3 +456,161 @@ func MockInitMatcherFn() MatcherFn {
 		return nil
 	}
 }
+
+func TestIsPullRequestRepo(t *testing.T) {
+	assert.True(t, isPullRequestRepo("git@github.com:zapier/kubechecks.git", "https://github.com/zapier/kubechecks.git"))
+	assert.True(t, isPullRequestRepo("https://github.com/zapier/kubechecks", "https://github.com/zapier/kubechecks.git"))
+	assert.False(t, isPullRequestRepo("https://github.com/zapier/other.git", "https://github.com/zapier/kubechecks.git"))
+}
+
+type noEmoji struct{}
+
+func (noEmoji) ToEmoji(pkg.CommitState) string { return "" }
+
+func TestCheckEvent_QueueChildApp(t *testing.T) {
+	newApp := func(name string) v1alpha1.Application {
+		return v1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+
+	ce := &CheckEvent{
+		addedAppsSet: make(map[string]v1alpha1.Application),
+		appChannel:   make(chan *v1alpha1.Application, 10),
+		ctr:          container.Container{Config: config.ServerConfig{MaxAppOfAppsDepth: 2}},
+		vcsNote:      msg.NewMessage("message", 1, 2, noEmoji{}),
+	}
+	ce.vcsNote.AddNewApp(context.Background(), "root")
+
+	ce.queueChildApp("root", newApp("child"), checks.ChildAdded)
+	ce.queueChildApp("child", newApp("grandchild"), checks.ChildChanged)
+	ce.queueChildApp("grandchild", newApp("too-deep"), checks.ChildChanged)
+
+	// the child is being checked when it renders its parent
+	ce.vcsNote.AddNewApp(context.Background(), "child")
+	ce.queueChildApp("child", newApp("root"), checks.ChildChanged)
+	ce.queueChildApp("child", newApp("child"), checks.ChildChanged)
+
+	assert.Len(t, ce.appChannel, 2, "only the child and grandchild are checked")
+	assert.Contains(t, ce.addedAppsSet, "child")
+	assert.Contains(t, ce.addedAppsSet, "grandchild")
+
+	assert.Equal(t, map[string]string{"child": "root", "grandchild": "child", "too-deep": "grandchild"}, ce.vcsNote.Parents())
+
+	comment := ce.vcsNote.BuildComment(context.Background(), time.Now(), "sha", "", false, "", 0, 0)
+	assert.Contains(t, comment, "the app is rendered by app `root`, and was added")
+	assert.Contains(t, comment, "the app is rendered by app `grandchild`, and was changed")
+	assert.Contains(t, comment, "Not checked, the app is nested more than 2 levels deep")
+	assert.Contains(t, comment, "app `child` renders app `root`, which is one of its parents: `root` → `child` → `root`")
+}
+
+func TestCheckEvent_MarkSuperseded(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	_, ok := supersededBy(ctx)
+	assert.False(t, ok)
+
+	cancel(pkg.SupersededError{SHA: "abc123"})
+	sha, ok := supersededBy(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "abc123", sha)
+
+	note := msg.NewMessage("zapier/kubechecks", 1, 2, nil)
+	vcsClient := new(vcsmocks.MockClient)
+	vcsClient.EXPECT().
+		UpdateMessage(mock.Anything, note, "## Kubechecks test Report\n:fast_forward: superseded by abc123").
+		RunAndReturn(func(ctx context.Context, _ *msg.Message, _ string) error {
+			return ctx.Err()
+		})
+
+	ce := CheckEvent{
+		ctr:     container.Container{Config: config.ServerConfig{Identifier: "test"}, VcsClient: vcsClient},
+		vcsNote: note,
+		logger:  zerolog.Nop(),
+	}
+	require.NoError(t, ce.markSuperseded(ctx, sha), "the comment must be updated with a context that is not cancelled")
+	vcsClient.AssertExpectations(t)
+}
+
+func TestCheckEvent_RecordRun(t *testing.T) {
+	store, err := history.NewStore(history.Config{Path: filepath.Join(t.TempDir(), "history.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	note := msg.NewMessage("zapier/kubechecks", 1, 2, nil)
+	note.URL = "https://github.com/zapier/kubechecks/pull/1#issuecomment-2"
+	ce := CheckEvent{
+		ctr: container.Container{
+			Config:  config.ServerConfig{DashboardAddr: ":8081", DashboardUrlBase: "https://kubechecks.internal"},
+			History: store,
+		},
+		pullRequest: vcs.PullRequest{FullName: "zapier/kubechecks", CheckID: 1, SHA: "abc123"},
+		vcsNote:     note,
+		logger:      zerolog.Nop(),
+	}
+
+	start := time.Now()
+	ce.startRun(start)
+	require.NotZero(t, ce.runID)
+	assert.Equal(t, fmt.Sprintf("https://kubechecks.internal/dashboard/runs/%d", ce.runID), note.RunURL)
+
+	run, ok, err := store.Get(ce.runID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, history.StatusRunning, run.Status)
+
+	note.AddNewApp(context.Background(), "app")
+	note.AddToAppMessage(context.Background(), "app", msg.Result{State: pkg.StateFailure, Check: "rego", Summary: "Rego"})
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(pkg.SupersededError{SHA: "def456"})
+	ce.finishRun(ctx, start, nil)
+
+	run, _, err = store.Get(ce.runID)
+	require.NoError(t, err)
+	assert.Equal(t, history.StatusSuperseded, run.Status)
+	assert.Equal(t, "abc123", run.SHA)
+	assert.Equal(t, note.URL, run.CommentURL)
+	assert.Equal(t, []history.App{{
+		Name:   "app",
+		State:  "failure",
+		Checks: []history.Check{{Name: "rego", State: "failure", Summary: "Rego"}},
+	}}, run.Apps)
+
+	ce.finishRun(context.Background(), start, errors.New("failed to push comment"))
+	run, _, err = store.Get(ce.runID)
+	require.NoError(t, err)
+	assert.Equal(t, history.StatusFailed, run.Status)
+}
+
+func TestCheckEvent_ReportsFailedRun(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.ServerConfig{
+		ArchiveCacheDir: t.TempDir(),
+		ReportJSONFile:  filepath.Join(dir, "report.json"),
+		ReportJUnitFile: filepath.Join(dir, "report.xml"),
+	}
+
+	vcsClient := new(vcsmocks.MockClient)
+	vcsClient.EXPECT().GetName().Return("github")
+	vcsClient.EXPECT().DownloadArchive(mock.Anything, mock.Anything).Return("", errors.New("merge conflict"))
+	vcsClient.EXPECT().PostMessage(mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	pr := vcs.PullRequest{FullName: "zapier/kubechecks", CheckID: 1, SHA: "abc123"}
+	ce := NewCheckEvent(pr, container.Container{
+		Config:         cfg,
+		VcsClient:      vcsClient,
+		ArchiveManager: archive.NewManager(cfg, vcsClient),
+	}, nil, nil, nil)
+	require.Error(t, ce.Process(context.Background()))
+
+	data, err := os.ReadFile(cfg.ReportJSONFile)
+	require.NoError(t, err, "a run that failed still writes its reports")
+	var decoded report.Report
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, report.StatusFailed, decoded.Status)
+	assert.Equal(t, "error", decoded.State)
+	assert.Contains(t, decoded.Error, "merge conflict")
+
+	data, err = os.ReadFile(cfg.ReportJUnitFile)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `<failure message="Failed to validate pull request for archive processing`)
+}
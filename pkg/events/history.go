@@ -0,0 +1,96 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/zapier/kubechecks/pkg/history"
+	"github.com/zapier/kubechecks/pkg/report"
+)
+
+// startRun records the run in the history, so that the dashboard shows it while it runs and its comment links to it.
+func (ce *CheckEvent) startRun(start time.Time) {
+	if ce.ctr.History == nil {
+		return
+	}
+
+	r := report.New(ce.pullRequest, ce.ctr.Config.Identifier, ce.affectedItems, nil, start, start)
+	id, err := ce.ctr.History.Start(ce.historyRun(r))
+	if err != nil {
+		ce.logger.Warn().Caller().Err(err).Msg("failed to record run in the history")
+		return
+	}
+
+	ce.runID = id
+	if ce.ctr.Config.DashboardAddr != "" {
+		ce.vcsNote.RunURL = history.RunURL(ce.ctr.Config.DashboardUrlBase, id)
+	}
+}
+
+// finishRun records the outcome of the run in the history.
+func (ce *CheckEvent) finishRun(ctx context.Context, start time.Time, err error) {
+	if ce.ctr.History == nil || ce.runID == 0 {
+		return
+	}
+
+	r := report.New(ce.pullRequest, ce.ctr.Config.Identifier, ce.affectedItems, ce.vcsNote.Snapshot(), start, time.Now())
+	r.SetParents(ce.vcsNote.Parents())
+
+	run := ce.historyRun(r)
+	run.ID = ce.runID
+	run.FinishedAt = r.FinishedAt
+	run.Status = runStatus(ctx, err)
+
+	if err := ce.ctr.History.Finish(run); err != nil {
+		ce.logger.Warn().Caller().Err(err).Msg("failed to record the outcome of the run in the history")
+	}
+}
+
+// runStatus tells how a run ended, from the error it returned and the cause of the cancellation of its context.
+func runStatus(ctx context.Context, err error) history.Status {
+	switch _, superseded := supersededBy(ctx); {
+	case superseded:
+		return history.StatusSuperseded
+	case cancelled(ctx), lockLost(ctx):
+		return history.StatusCancelled
+	case err != nil:
+		return history.StatusFailed
+	default:
+		return history.StatusCompleted
+	}
+}
+
+// historyRun keeps the parts of a report that are shown on the dashboard.
+func (ce *CheckEvent) historyRun(r *report.Report) history.Run {
+	run := history.Run{
+		CommentURL: ce.vcsNote.URL,
+		Repository: r.PullRequest.Repository,
+		Number:     r.PullRequest.Number,
+		Title:      r.PullRequest.Title,
+		SHA:        r.PullRequest.SHA,
+		HeadRef:    r.PullRequest.HeadRef,
+		StartedAt:  r.StartedAt,
+		State:      r.State,
+	}
+
+	for _, app := range r.Applications {
+		a := history.App{
+			Name:           app.Name,
+			ArgoCDInstance: app.ArgoCDInstance,
+			Parent:         app.Parent,
+			State:          app.State,
+		}
+		for _, check := range app.Checks {
+			a.Checks = append(a.Checks, history.Check{
+				Name:              check.Name,
+				State:             check.State,
+				Summary:           check.Summary,
+				NoChangesDetected: check.NoChangesDetected,
+				DurationSeconds:   check.DurationSeconds,
+			})
+		}
+		run.Apps = append(run.Apps, a)
+	}
+
+	return run
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/revalidate"
+)
+
+// startRevalidation stops re-validating the pull request while it is checked again, and records the head of its base
+// branch, so that pushes to it while it is checked are noticed.
+func (ce *CheckEvent) startRevalidation(ctx context.Context) {
+	if ce.ctr.Revalidation == nil {
+		return
+	}
+
+	ce.ctr.Revalidation.Forget(ce.pullRequest)
+	ce.baseline = revalidate.Baseline{
+		BaseHead:  ce.ctr.Revalidation.BaseHead(ctx, ce.pullRequest),
+		LiveState: make(map[string]string),
+	}
+}
+
+// recordLiveState records the live state of an app as it is checked, so that drift while and after it is checked is
+// noticed.
+func (ce *CheckEvent) recordLiveState(ctx context.Context, app v1alpha1.Application) {
+	if ce.ctr.Revalidation == nil {
+		return
+	}
+
+	version := ce.ctr.Revalidation.LiveState(ctx, app)
+
+	ce.baselineLock.Lock()
+	defer ce.baselineLock.Unlock()
+	ce.baseline.LiveState[pkg.QualifiedName(app.ObjectMeta)] = version
+}
+
+// trackRevalidation re-validates the pull request once its report is posted, until its base branch or the live state
+// of the apps it checked changes.
+func (ce *CheckEvent) trackRevalidation(comment string) {
+	if ce.ctr.Revalidation == nil {
+		return
+	}
+
+	ce.addedAppsSetLock.Lock()
+	apps := make([]v1alpha1.Application, 0, len(ce.addedAppsSet))
+	for _, app := range ce.addedAppsSet {
+		apps = append(apps, app)
+	}
+	ce.addedAppsSetLock.Unlock()
+
+	ce.baselineLock.Lock()
+	defer ce.baselineLock.Unlock()
+	ce.ctr.Revalidation.Track(ce.pullRequest, ce.vcsNote, comment, apps, ce.baseline)
+}
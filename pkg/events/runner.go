@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/rs/zerolog"
@@ -28,8 +29,20 @@ func newRunner(
 	jsonManifests, yamlManifests []string,
 	logger zerolog.Logger,
 	note *msg.Message,
-	queueApp, removeApp func(application v1alpha1.Application),
+	queueChildApp func(parent string, child v1alpha1.Application, change checks.ChildChange),
+	removeApp func(application v1alpha1.Application),
 ) *Runner {
+	// apps found in the rendered manifests are checked as well, as children of this app
+	queueApp := func(child v1alpha1.Application, change checks.ChildChange) {
+		// child apps are managed by the same Argo CD instance as their parent
+		child.ObjectMeta = pkg.WithArgoCDInstance(child.ObjectMeta, pkg.ArgoCDInstanceOf(app.ObjectMeta))
+		queueChildApp(appName, child, change)
+	}
+	removeChildApp := func(child v1alpha1.Application) {
+		child.ObjectMeta = pkg.WithArgoCDInstance(child.ObjectMeta, pkg.ArgoCDInstanceOf(app.ObjectMeta))
+		removeApp(child)
+	}
+
 	return &Runner{
 		Request: checks.Request{
 			App:               app,
@@ -40,7 +53,7 @@ func newRunner(
 			Log:               logger,
 			Note:              note,
 			QueueApp:          queueApp,
-			RemoveApp:         removeApp,
+			RemoveApp:         removeChildApp,
 			YamlManifests:     yamlManifests,
 		},
 	}
@@ -55,9 +68,12 @@ func (r *Runner) Run(ctx context.Context, desc string, fn checkFunction, worstSt
 		logger := r.Log.With().Str("check", desc).Logger()
 
 		ctx, span := tracer.Start(ctx, desc)
+		start := time.Now()
 
 		addToAppMessage := func(result msg.Result) {
 			result.State = pkg.BestState(result.State, worstState)
+			result.Check = desc
+			result.Duration = time.Since(start)
 			r.Note.AddToAppMessage(ctx, r.AppName, result)
 		}
 
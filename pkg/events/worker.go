@@ -39,19 +39,23 @@ type worker struct {
 	pullRequest     vcs.PullRequest
 	vcsNote         *msg.Message
 
-	done                func()
-	getRepo             func(ctx context.Context, cloneURL, branchName string) (*git.Repo, error)
-	queueApp, removeApp func(application v1alpha1.Application)
-	addAIReviewResult   func(appName string, result msg.Result, suggestions []vcs.ReviewSuggestion)
-	claimAIReviewSlot   func() bool
-	changedFiles        []string
+	done              func()
+	getRepo           func(ctx context.Context, cloneURL, branchName string) (*git.Repo, error)
+	queueChildApp     func(parent string, child v1alpha1.Application, change checks.ChildChange)
+	removeApp         func(application v1alpha1.Application)
+	addAIReviewResult func(appName string, result msg.Result, suggestions []vcs.ReviewSuggestion)
+	claimAIReviewSlot func() bool
+	policyVersion     func() (string, error)
+	liveState         func(ctx context.Context, ctr container.Container, app v1alpha1.Application) (string, error)
+	recordLiveState   func(ctx context.Context, app v1alpha1.Application)
+	changedFiles      []string
 }
 
 // process apps
 func (w *worker) run(ctx context.Context) {
 	for app := range w.appChannel {
 		if app != nil {
-			w.logger.Info().Str("app", app.Name).Msg("Processing App")
+			w.logger.Info().Str("app", pkg.QualifiedName(app.ObjectMeta)).Msg("Processing App")
 			w.processApp(ctx, *app)
 		} else {
 			w.logger.Warn().Msg("appWorkers received a nil app")
@@ -67,10 +71,17 @@ func (w *worker) run(ctx context.Context) {
 // The processing is performed concurrently using Go routines and error groups. Any check results are sent through
 // the returnChan. The function also manages the inFlight atomic counter to track active processing routines.
 func (w *worker) processApp(ctx context.Context, app v1alpha1.Application) {
+	// manifests and diffs come from the Argo CD instance that manages the app
+	if instance := pkg.ArgoCDInstanceOf(app.ObjectMeta); instance != "" {
+		instanceWorker := *w
+		instanceWorker.ctr = w.ctr.ForInstance(instance)
+		w = &instanceWorker
+	}
+
 	var (
 		err error
 
-		appName = app.Name
+		appName = pkg.QualifiedName(app.ObjectMeta)
 
 		rootLogger = w.logger.With().
 				Str("app_name", appName).
@@ -90,6 +101,10 @@ func (w *worker) processApp(ctx context.Context, app v1alpha1.Application) {
 	// Build a new section for this app in the parent comment
 	w.vcsNote.AddNewApp(ctx, appName)
 
+	if w.recordLiveState != nil {
+		w.recordLiveState(ctx, app)
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			desc := fmt.Sprintf("panic while checking %s", appName)
@@ -100,6 +115,7 @@ func (w *worker) processApp(ctx context.Context, app v1alpha1.Application) {
 			telemetry.SetError(span, fmt.Errorf("%v", r), "panic while running check")
 			result := msg.Result{
 				State:   pkg.StatePanic,
+				Check:   "processing app",
 				Summary: desc,
 				Details: fmt.Sprintf(errorCommentFormat, desc, r),
 			}
@@ -113,6 +129,7 @@ func (w *worker) processApp(ctx context.Context, app v1alpha1.Application) {
 		rootLogger.Error().Caller().Err(err).Str("app", appName).Str("repo", w.pullRequest.Name).Msg("Unable to get manifests")
 		w.vcsNote.AddToAppMessage(ctx, appName, msg.Result{
 			State:   pkg.StateError,
+			Check:   "getting manifests",
 			Summary: "Unable to get manifests",
 			Details: fmt.Sprintf("```\n%s\n```", err),
 		})
@@ -132,7 +149,23 @@ func (w *worker) processApp(ctx context.Context, app v1alpha1.Application) {
 	k8sVersion = normalizeK8sVersion(k8sVersion, w.ctr.Config.FallbackK8sVersion)
 	rootLogger.Info().Msgf("Kubernetes version (normalized): %s", k8sVersion)
 
-	runner := newRunner(w.ctr, app, appName, k8sVersion, jsonManifests, yamlManifests, rootLogger, w.vcsNote, w.queueApp, w.removeApp)
+	fingerprint := w.checkFingerprint(ctx, app, k8sVersion, jsonManifests, rootLogger)
+	if w.reuseResults(ctx, appName, fingerprint, rootLogger) {
+		return
+	}
+
+	// results of an app-of-apps are only reused if it did not change its children, which are checked on their own
+	var changedChildren atomic.Bool
+	queueChildApp := func(parent string, child v1alpha1.Application, change checks.ChildChange) {
+		changedChildren.Store(true)
+		w.queueChildApp(parent, child, change)
+	}
+	removeApp := func(application v1alpha1.Application) {
+		changedChildren.Store(true)
+		w.removeApp(application)
+	}
+
+	runner := newRunner(w.ctr, app, appName, k8sVersion, jsonManifests, yamlManifests, rootLogger, w.vcsNote, queueChildApp, removeApp)
 
 	// Launch AI review in parallel — but only if there are actual changes
 	var aiReviewWg sync.WaitGroup
@@ -168,6 +201,8 @@ func (w *worker) processApp(ctx context.Context, app v1alpha1.Application) {
 
 	runner.Wait()
 	aiReviewWg.Wait()
+
+	w.storeResults(appName, fingerprint, changedChildren.Load(), rootLogger)
 }
 
 // runAIReview runs the AI review for a single app and collects the result for aggregation.
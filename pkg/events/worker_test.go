@@ -1,9 +1,19 @@
 package events
 
 import (
+	"context"
 	"testing"
 
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/checkcache"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/vcs"
 )
 
 func TestNormalizeK8sVersion(t *testing.T) {
@@ -84,3 +94,58 @@ hello: world
 		})
 	}
 }
+
+func TestWorker_ReuseResults(t *testing.T) {
+	store, err := checkcache.NewStore(checkcache.Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+
+	liveState := "synced"
+	newWorker := func(sha string) *worker {
+		return &worker{
+			ctr:           container.Container{CheckCache: store},
+			pullRequest:   vcs.PullRequest{FullName: "zapier/kubechecks", CheckID: 1, SHA: sha},
+			vcsNote:       msg.NewMessage("zapier/kubechecks", 1, 1, nil),
+			policyVersion: func() (string, error) { return "policies", nil },
+			liveState: func(context.Context, container.Container, v1alpha1.Application) (string, error) {
+				return liveState, nil
+			},
+		}
+	}
+	ctx := context.Background()
+	app := v1alpha1.Application{}
+	manifests := []string{`{"kind":"ConfigMap"}`}
+	result := msg.Result{State: pkg.StateSuccess, Check: "diff", Summary: "Diff"}
+
+	w := newWorker("first")
+	fingerprint := w.checkFingerprint(ctx, app, "1.30.0", manifests, zerolog.Nop())
+	require.NotEmpty(t, fingerprint)
+	assert.False(t, w.reuseResults(ctx, "app", fingerprint, zerolog.Nop()))
+
+	w.vcsNote.AddNewApp(ctx, "app")
+	w.vcsNote.AddToAppMessage(ctx, "app", result)
+	w.storeResults("app", fingerprint, false, zerolog.Nop())
+
+	// the next push reuses the results, and marks them with the commit they were checked at
+	w = newWorker("second")
+	w.vcsNote.AddNewApp(ctx, "app")
+	require.True(t, w.reuseResults(ctx, "app", w.checkFingerprint(ctx, app, "1.30.0", manifests, zerolog.Nop()), zerolog.Nop()))
+	results := w.vcsNote.Snapshot()["app"]
+	require.Len(t, results, 2)
+	assert.Equal(t, "Unchanged since first", results[0].Summary)
+	assert.Equal(t, result, results[1])
+
+	// changed manifests are checked again, and so are apps that drifted in the cluster since
+	assert.False(t, w.reuseResults(ctx, "app", w.checkFingerprint(ctx, app, "1.30.0", []string{`{"kind":"Secret"}`}, zerolog.Nop()), zerolog.Nop()))
+	liveState = "drifted"
+	assert.False(t, w.reuseResults(ctx, "app", w.checkFingerprint(ctx, app, "1.30.0", manifests, zerolog.Nop()), zerolog.Nop()))
+
+	// an app-of-apps that changed its children is checked again, so that its children are checked as well
+	w.storeResults("other", fingerprint, true, zerolog.Nop())
+	assert.False(t, w.reuseResults(ctx, "other", fingerprint, zerolog.Nop()))
+
+	// failed checks are run again
+	w.vcsNote.AddNewApp(ctx, "failed")
+	w.vcsNote.AddToAppMessage(ctx, "failed", msg.Result{State: pkg.StateError, Check: "diff"})
+	w.storeResults("failed", fingerprint, false, zerolog.Nop())
+	assert.False(t, w.reuseResults(ctx, "failed", fingerprint, zerolog.Nop()))
+}
@@ -3,22 +3,31 @@ package generator
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	argogenerator "github.com/argoproj/argo-cd/v3/applicationset/generators"
+	"github.com/argoproj/argo-cd/v3/applicationset/services"
 	"github.com/argoproj/argo-cd/v3/applicationset/utils"
 	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/zapier/kubechecks/pkg/config"
 	"github.com/zapier/kubechecks/pkg/container"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func New() AppsGenerator {
-	return &gen{}
+// ErrNoKubeClient is returned when there is no cluster to run the appset generators against, e.g. in offline mode.
+var ErrNoKubeClient = errors.New("generating applications requires a kubernetes client")
+
+// New returns a generator that reads the files of git generators from repos.
+func New(repos services.Repos) AppsGenerator {
+	return &gen{repos: repos}
 }
 
 type gen struct {
+	repos services.Repos
 }
 
 type AppsGenerator interface {
@@ -26,8 +35,16 @@ type AppsGenerator interface {
 }
 
 func (c *gen) GenerateApplicationSetApps(ctx context.Context, appset argov1alpha1.ApplicationSet, ctr *container.Container) ([]argov1alpha1.Application, error) {
+	if ctr.KubeClientSet == nil {
+		return nil, ErrNoKubeClient
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(ctr.KubeClientSet.Config())
+	if err != nil {
+		return nil, fmt.Errorf("error creating dynamic client: %w", err)
+	}
 
-	appSetGenerators := getGenerators(ctx, *ctr.KubeClientSet.ControllerClient(), ctr.KubeClientSet.ClientSet(), ctr.Config.ArgoCDNamespace)
+	appSetGenerators := getGenerators(ctx, *ctr.KubeClientSet.ControllerClient(), ctr.KubeClientSet.ClientSet(), dynamicClient, c.repos, ctr.Config)
 
 	apps, appsetReason, err := generateApplications(appset, appSetGenerators, *ctr.KubeClientSet.ControllerClient())
 	if err != nil {
@@ -37,29 +54,43 @@ func (c *gen) GenerateApplicationSetApps(ctx context.Context, appset argov1alpha
 	return apps, nil
 }
 
-// GetGenerators returns the generators that will be used to generate applications for the ApplicationSet
-//
-// only support List and Clusters generators
-func getGenerators(ctx context.Context, c client.Client, k8sClient kubernetes.Interface, namespace string) map[string]argogenerator.Generator {
+// getGenerators returns the generators that will be used to generate applications for the ApplicationSet
+func getGenerators(
+	ctx context.Context, c client.Client, k8sClient kubernetes.Interface, dynamicClient dynamic.Interface,
+	repos services.Repos, cfg config.ServerConfig,
+) map[string]argogenerator.Generator {
+	namespace := cfg.ArgoCDNamespace
+	scmConfig := argogenerator.NewSCMConfig(
+		cfg.AppSetSCMRootCAPath, cfg.AppSetAllowedSCMProviders, cfg.EnableAppSetSCMProviders,
+		false, githubAppCredentials(ctx, k8sClient, namespace), true,
+	)
 
 	terminalGenerators := map[string]argogenerator.Generator{
-		"List":     argogenerator.NewListGenerator(),
-		"Clusters": argogenerator.NewClusterGenerator(ctx, c, k8sClient, namespace),
+		"List":                    argogenerator.NewListGenerator(),
+		"Clusters":                argogenerator.NewClusterGenerator(ctx, c, k8sClient, namespace),
+		"Git":                     argogenerator.NewGitGenerator(repos, namespace),
+		"SCMProvider":             argogenerator.NewSCMProviderGenerator(c, scmConfig),
+		"ClusterDecisionResource": argogenerator.NewDuckTypeGenerator(ctx, dynamicClient, k8sClient, namespace),
+		"PullRequest":             argogenerator.NewPullRequestGenerator(c, scmConfig),
+		"Plugin":                  argogenerator.NewPluginGenerator(c, namespace),
 	}
 
 	nestedGenerators := map[string]argogenerator.Generator{
-		"List":     terminalGenerators["List"],
-		"Clusters": terminalGenerators["Clusters"],
-		"Matrix":   argogenerator.NewMatrixGenerator(terminalGenerators),
-		"Merge":    argogenerator.NewMergeGenerator(terminalGenerators),
+		"Matrix": argogenerator.NewMatrixGenerator(terminalGenerators),
+		"Merge":  argogenerator.NewMergeGenerator(terminalGenerators),
+	}
+	for name, generator := range terminalGenerators {
+		nestedGenerators[name] = generator
 	}
 
 	topLevelGenerators := map[string]argogenerator.Generator{
-		"List":     terminalGenerators["List"],
-		"Clusters": terminalGenerators["Clusters"],
-		"Matrix":   argogenerator.NewMatrixGenerator(nestedGenerators),
-		"Merge":    argogenerator.NewMergeGenerator(nestedGenerators),
+		"Matrix": argogenerator.NewMatrixGenerator(nestedGenerators),
+		"Merge":  argogenerator.NewMergeGenerator(nestedGenerators),
 	}
+	for name, generator := range terminalGenerators {
+		topLevelGenerators[name] = generator
+	}
+
 	return topLevelGenerators
 }
 
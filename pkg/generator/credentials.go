@@ -0,0 +1,19 @@
+package generator
+
+import (
+	"context"
+
+	"github.com/argoproj/argo-cd/v3/applicationset/services/github_app_auth"
+	"github.com/argoproj/argo-cd/v3/util/db"
+	"github.com/argoproj/argo-cd/v3/util/github_app"
+	argosettings "github.com/argoproj/argo-cd/v3/util/settings"
+	"k8s.io/client-go/kubernetes"
+)
+
+// githubAppCredentials looks up the GitHub App secrets that the SCM provider and pull request generators refer to
+// among the repository credentials of Argo CD, like the ApplicationSet controller does. Only secrets that Argo CD would
+// use are used, never the GitHub App that kubechecks runs as.
+func githubAppCredentials(ctx context.Context, k8sClient kubernetes.Interface, namespace string) github_app_auth.Credentials {
+	settingsMgr := argosettings.NewSettingsManager(ctx, k8sClient, namespace)
+	return github_app.NewAuthCredentials(db.NewDB(namespace, settingsMgr, k8sClient).(db.RepoCredsDB))
+}
@@ -0,0 +1,200 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/ghodss/yaml"
+	"github.com/pmezard/go-difflib/difflib"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/msg"
+)
+
+// AppSetPreview compares the apps an appset generates from the pull request with the apps it owns right now.
+type AppSetPreview struct {
+	AppSet  argov1alpha1.ApplicationSet
+	Created []argov1alpha1.Application
+	Deleted []argov1alpha1.Application
+	Changed []AppSpecChange
+}
+
+// AppSpecChange is an app that exists before and after the change, with a different spec.
+type AppSpecChange struct {
+	Name string
+	Diff string
+}
+
+// PreviewAppSet works out which apps the appset will create, delete and update.
+func PreviewAppSet(appSet argov1alpha1.ApplicationSet, generated, live []argov1alpha1.Application) (AppSetPreview, error) {
+	preview := AppSetPreview{AppSet: appSet}
+
+	liveByName := make(map[string]argov1alpha1.Application, len(live))
+	for _, app := range live {
+		liveByName[app.Name] = app
+	}
+
+	generatedNames := make(map[string]struct{}, len(generated))
+	for _, app := range generated {
+		generatedNames[app.Name] = struct{}{}
+
+		old, ok := liveByName[app.Name]
+		if !ok {
+			preview.Created = append(preview.Created, app)
+			continue
+		}
+
+		diff, err := specDiff(old.Spec, app.Spec)
+		if err != nil {
+			return preview, fmt.Errorf("failed to diff the spec of %s: %w", app.Name, err)
+		}
+		if diff == "" {
+			continue
+		}
+		preview.Changed = append(preview.Changed, AppSpecChange{Name: app.Name, Diff: diff})
+	}
+
+	for _, app := range live {
+		if _, ok := generatedNames[app.Name]; !ok {
+			preview.Deleted = append(preview.Deleted, app)
+		}
+	}
+
+	sortApps(preview.Created)
+	sortApps(preview.Deleted)
+	sort.Slice(preview.Changed, func(i, j int) bool {
+		return preview.Changed[i].Name < preview.Changed[j].Name
+	})
+
+	return preview, nil
+}
+
+func sortApps(apps []argov1alpha1.Application) {
+	sort.Slice(apps, func(i, j int) bool {
+		return apps[i].Name < apps[j].Name
+	})
+}
+
+// specDiff diffs the specs as they are serialized, so that differences that don't survive serialization, like a nil
+// and an empty list, don't count as changes. It returns "" when the specs are the same.
+func specDiff(old, new argov1alpha1.ApplicationSpec) (string, error) {
+	oldData, err := yaml.Marshal(old)
+	if err != nil {
+		return "", err
+	}
+	newData, err := yaml.Marshal(new)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(oldData, newData) {
+		return "", nil
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:       difflib.SplitLines(string(oldData)),
+		B:       difflib.SplitLines(string(newData)),
+		Context: 2,
+	})
+}
+
+// HasChanges is true when any app is created, deleted or updated.
+func (p AppSetPreview) HasChanges() bool {
+	return len(p.Created) > 0 || len(p.Deleted) > 0 || len(p.Changed) > 0
+}
+
+func (p AppSetPreview) syncPolicy() (preserveResources bool, applicationsSync *argov1alpha1.ApplicationsSyncPolicy) {
+	if p.AppSet.Spec.SyncPolicy == nil {
+		return false, nil
+	}
+	return p.AppSet.Spec.SyncPolicy.PreserveResourcesOnDeletion, p.AppSet.Spec.SyncPolicy.ApplicationsSync
+}
+
+// AllowsDelete is false when the appset's applicationsSync policy keeps apps that are no longer generated.
+func (p AppSetPreview) AllowsDelete() bool {
+	_, applicationsSync := p.syncPolicy()
+	return applicationsSync == nil || applicationsSync.AllowDelete()
+}
+
+// AllowsUpdate is false when the appset's applicationsSync policy leaves existing apps alone.
+func (p AppSetPreview) AllowsUpdate() bool {
+	_, applicationsSync := p.syncPolicy()
+	return applicationsSync == nil || applicationsSync.AllowUpdate()
+}
+
+// DeletesResources is true when apps are deleted together with everything they deployed.
+func (p AppSetPreview) DeletesResources() bool {
+	preserveResources, _ := p.syncPolicy()
+	return len(p.Deleted) > 0 && p.AllowsDelete() && !preserveResources
+}
+
+// Result renders the preview as a section of the pull request comment.
+func (p AppSetPreview) Result() msg.Result {
+	state := pkg.StateSuccess
+	var details strings.Builder
+
+	if len(p.Created) > 0 {
+		details.WriteString("**Applications to be created:**\n")
+		for _, app := range p.Created {
+			fmt.Fprintf(&details, "- `%s`%s\n", app.Name, describeDestination(app))
+		}
+		details.WriteString("\n")
+	}
+
+	if len(p.Deleted) > 0 {
+		details.WriteString("**Applications to be deleted:**\n")
+		for _, app := range p.Deleted {
+			fmt.Fprintf(&details, "- `%s`%s\n", app.Name, describeDestination(app))
+		}
+		details.WriteString("\n")
+
+		switch {
+		case !p.AllowsDelete():
+			details.WriteString("> The `applicationsSync` policy of the appset does not allow deletes, these applications will be left in place.\n\n")
+		case p.DeletesResources():
+			state = pkg.StateWarning
+			details.WriteString("> :warning: `preserveResourcesOnDeletion` is not enabled on the appset: " +
+				"deleting these applications **also deletes every resource they deployed** from the cluster.\n\n")
+		default:
+			details.WriteString("> `preserveResourcesOnDeletion` is enabled on the appset, the resources of these applications will be left in the cluster.\n\n")
+		}
+	}
+
+	if len(p.Changed) > 0 {
+		details.WriteString("**Applications with a changed spec:**\n")
+		if !p.AllowsUpdate() {
+			details.WriteString("> The `applicationsSync` policy of the appset does not allow updates, these changes will not be applied.\n\n")
+		}
+		for _, change := range p.Changed {
+			fmt.Fprintf(&details, "<details>\n<summary><code>%s</code></summary>\n\n```diff\n%s```\n</details>\n\n", change.Name, change.Diff)
+		}
+	}
+
+	return msg.Result{
+		State:   state,
+		Check:   "appset preview",
+		Summary: fmt.Sprintf("%d to create, %d to delete, %d to update", len(p.Created), len(p.Deleted), len(p.Changed)),
+		Details: details.String(),
+	}
+}
+
+func describeDestination(app argov1alpha1.Application) string {
+	dest := app.Spec.Destination
+	cluster := dest.Name
+	if cluster == "" {
+		cluster = dest.Server
+	}
+
+	switch {
+	case cluster != "" && dest.Namespace != "":
+		return fmt.Sprintf(" (%s, namespace `%s`)", cluster, dest.Namespace)
+	case cluster != "":
+		return fmt.Sprintf(" (%s)", cluster)
+	case dest.Namespace != "":
+		return fmt.Sprintf(" (namespace `%s`)", dest.Namespace)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"testing"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zapier/kubechecks/pkg"
+)
+
+func previewApp(name, path string) argov1alpha1.Application {
+	return argov1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: argov1alpha1.ApplicationSpec{
+			Source:      &argov1alpha1.ApplicationSource{RepoURL: "https://github.com/zapier/kubechecks.git", Path: path},
+			Destination: argov1alpha1.ApplicationDestination{Name: "in-cluster", Namespace: name},
+		},
+	}
+}
+
+func TestPreviewAppSet(t *testing.T) {
+	live := []argov1alpha1.Application{previewApp("a", "apps/a"), previewApp("b", "apps/b"), previewApp("c", "apps/c")}
+	generated := []argov1alpha1.Application{previewApp("a", "apps/a"), previewApp("c", "apps/c-v2"), previewApp("d", "apps/d")}
+
+	preview, err := PreviewAppSet(argov1alpha1.ApplicationSet{}, generated, live)
+	require.NoError(t, err)
+	assert.True(t, preview.HasChanges())
+
+	require.Len(t, preview.Created, 1)
+	assert.Equal(t, "d", preview.Created[0].Name)
+	require.Len(t, preview.Deleted, 1)
+	assert.Equal(t, "b", preview.Deleted[0].Name)
+	require.Len(t, preview.Changed, 1)
+	assert.Equal(t, "c", preview.Changed[0].Name)
+	assert.Contains(t, preview.Changed[0].Diff, "-  path: apps/c\n+  path: apps/c-v2\n")
+
+	result := preview.Result()
+	assert.Equal(t, pkg.StateWarning, result.State, "deleting apps without preserving resources is a warning")
+	assert.Equal(t, "1 to create, 1 to delete, 1 to update", result.Summary)
+	assert.Contains(t, result.Details, "- `d` (in-cluster, namespace `d`)")
+	assert.Contains(t, result.Details, "also deletes every resource")
+
+	unchanged, err := PreviewAppSet(argov1alpha1.ApplicationSet{}, live, live)
+	require.NoError(t, err)
+	assert.False(t, unchanged.HasChanges())
+
+	// the generator leaves empty lists where the live app has none
+	normalized := previewApp("a", "apps/a")
+	normalized.Spec.Info = []argov1alpha1.Info{}
+	normalized.Spec.IgnoreDifferences = argov1alpha1.IgnoreDifferences{}
+	unchanged, err = PreviewAppSet(argov1alpha1.ApplicationSet{}, []argov1alpha1.Application{normalized}, live[:1])
+	require.NoError(t, err)
+	assert.False(t, unchanged.HasChanges(), "specs that serialize the same are unchanged")
+}
+
+func TestAppSetPreview_SyncPolicy(t *testing.T) {
+	deleted := []argov1alpha1.Application{previewApp("a", "apps/a")}
+
+	testcases := map[string]struct {
+		policy          *argov1alpha1.ApplicationSetSyncPolicy
+		expectedState   pkg.CommitState
+		expectedDetails string
+	}{
+		"no policy": {
+			expectedState:   pkg.StateWarning,
+			expectedDetails: "also deletes every resource",
+		},
+		"preserve resources": {
+			policy:          &argov1alpha1.ApplicationSetSyncPolicy{PreserveResourcesOnDeletion: true},
+			expectedState:   pkg.StateSuccess,
+			expectedDetails: "will be left in the cluster",
+		},
+		"create-only": {
+			policy:          &argov1alpha1.ApplicationSetSyncPolicy{ApplicationsSync: pkg.Pointer(argov1alpha1.ApplicationsSyncPolicyCreateOnly)},
+			expectedState:   pkg.StateSuccess,
+			expectedDetails: "does not allow deletes",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			appSet := argov1alpha1.ApplicationSet{Spec: argov1alpha1.ApplicationSetSpec{SyncPolicy: tc.policy}}
+			preview, err := PreviewAppSet(appSet, nil, deleted)
+			require.NoError(t, err)
+
+			result := preview.Result()
+			assert.Equal(t, tc.expectedState, result.State)
+			assert.Contains(t, result.Details, tc.expectedDetails)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/argoproj/argo-cd/v3/applicationset/services"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/pkg/errors"
+
+	"github.com/zapier/kubechecks/pkg/git"
+)
+
+// GetRepoFn returns a checkout of a repository at a revision.
+type GetRepoFn func(ctx context.Context, repoURL, revision string) (*git.Repo, error)
+
+// localRepos serves the files and directories of the git generators from local checkouts, instead of asking the
+// Argo CD repo server, which only knows about branches that have been pushed.
+type localRepos struct {
+	getRepo GetRepoFn
+}
+
+var _ services.Repos = (*localRepos)(nil)
+
+// NewLocalRepos returns the repositories used by the git generators.
+func NewLocalRepos(getRepo GetRepoFn) services.Repos {
+	return &localRepos{getRepo: getRepo}
+}
+
+func (r *localRepos) GetFiles(ctx context.Context, repoURL, revision, _, pattern string, _, _ bool) (map[string][]byte, error) {
+	repo, err := r.getRepo(ctx, repoURL, revision)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get repo %q", repoURL)
+	}
+
+	repoFS := os.DirFS(repo.Directory)
+	matches, err := doublestar.Glob(repoFS, strings.TrimPrefix(pattern, "/"), doublestar.WithFilesOnly())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to expand %q", pattern)
+	}
+
+	files := make(map[string][]byte, len(matches))
+	for _, match := range matches {
+		content, err := fs.ReadFile(repoFS, match)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %q", match)
+		}
+		files[match] = content
+	}
+
+	return files, nil
+}
+
+func (r *localRepos) GetDirectories(ctx context.Context, repoURL, revision, _ string, _, _ bool) ([]string, error) {
+	repo, err := r.getRepo(ctx, repoURL, revision)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get repo %q", repoURL)
+	}
+
+	var dirs []string
+	err = fs.WalkDir(os.DirFS(repo.Directory), ".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() || path == "." {
+			return nil
+		}
+
+		// hidden directories are skipped, like the repo server does by default
+		if strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		dirs = append(dirs, path)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list directories of %q", repoURL)
+	}
+
+	return dirs, nil
+}
@@ -0,0 +1,95 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/zapier/kubechecks/pkg/git"
+)
+
+func TestLocalRepos(t *testing.T) {
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"apps/a/config.json":  `{"name": "a"}`,
+		"apps/b/config.json":  `{"name": "b"}`,
+		"apps/b/values.yaml":  "replicas: 1\n",
+		".github/config.json": `{}`,
+	} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	var requested []string
+	repos := NewLocalRepos(func(ctx context.Context, repoURL, revision string) (*git.Repo, error) {
+		requested = append(requested, repoURL+"@"+revision)
+		return &git.Repo{Directory: dir}, nil
+	})
+
+	files, err := repos.GetFiles(ctx, "https://github.com/zapier/kubechecks.git", "main", "default", "apps/**/config.json", false, false)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{
+		"apps/a/config.json": []byte(`{"name": "a"}`),
+		"apps/b/config.json": []byte(`{"name": "b"}`),
+	}, files)
+
+	dirs, err := repos.GetDirectories(ctx, "https://github.com/zapier/kubechecks.git", "main", "default", false, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apps", "apps/a", "apps/b"}, dirs, "hidden directories are skipped")
+
+	assert.Equal(t, []string{"https://github.com/zapier/kubechecks.git@main", "https://github.com/zapier/kubechecks.git@main"}, requested)
+}
+
+func TestGithubAppCredentials(t *testing.T) {
+	ctx := context.Background()
+
+	k8sClient := fake.NewClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "github-app",
+				Namespace: "argocd",
+				Labels:    map[string]string{"argocd.argoproj.io/secret-type": "repo-creds"},
+			},
+			Data: map[string][]byte{
+				"url":                     []byte("https://github.com/zapier"),
+				"githubAppID":             []byte("1"),
+				"githubAppInstallationID": []byte("2"),
+				"githubAppPrivateKey":     []byte("key"),
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "token",
+				Namespace: "argocd",
+				Labels:    map[string]string{"argocd.argoproj.io/secret-type": "repo-creds"},
+			},
+			Data: map[string][]byte{
+				"url":      []byte("https://github.com/zapier"),
+				"password": []byte("token"),
+			},
+		},
+	)
+	creds := githubAppCredentials(ctx, k8sClient, "argocd")
+
+	auth, err := creds.GetAuthSecret(ctx, "github-app")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), auth.Id)
+	assert.Equal(t, int64(2), auth.InstallationId)
+	assert.Equal(t, "key", auth.PrivateKey)
+
+	_, err = creds.GetAuthSecret(ctx, "token")
+	assert.Error(t, err, "not a github app")
+
+	_, err = creds.GetAuthSecret(ctx, "missing")
+	assert.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -423,12 +424,22 @@ func (rm *PersistentRepoManager) startCleanupRoutine() {
 func (rm *PersistentRepoManager) cleanupStaleRepos() {
 	log.Debug().Caller().Msg("starting cleanup of stale repositories")
 
+	removed, remaining := rm.removeUnused(rm.cfg.RepoCacheTTL)
+	if removed > 0 {
+		log.Info().
+			Int("removed", removed).
+			Int("remaining", remaining).
+			Msg("cleanup completed")
+	}
+}
+
+// removeUnused removes the repos that are not in use and were last used longer than unusedFor ago. It returns how
+// many repos it removed, and how many are left.
+func (rm *PersistentRepoManager) removeUnused(unusedFor time.Duration) (removed, remaining int) {
 	rm.lock.Lock()
 	defer rm.lock.Unlock()
 
 	now := time.Now()
-	ttl := rm.cfg.RepoCacheTTL
-	var removed int
 
 	for key, pr := range rm.repos {
 		// Skip if repo is currently in use
@@ -437,7 +448,7 @@ func (rm *PersistentRepoManager) cleanupStaleRepos() {
 		}
 
 		// Check if repo is stale
-		if now.Sub(pr.lastUsed) > ttl {
+		if now.Sub(pr.lastUsed) > unusedFor {
 			log.Info().
 				Str("url", pr.CloneURL).
 				Dur("unused_for", now.Sub(pr.lastUsed)).
@@ -449,12 +460,45 @@ func (rm *PersistentRepoManager) cleanupStaleRepos() {
 		}
 	}
 
-	if removed > 0 {
-		log.Info().
-			Int("removed", removed).
-			Int("remaining", len(rm.repos)).
-			Msg("cleanup completed")
+	return removed, len(rm.repos)
+}
+
+// CachedRepo describes a repository in the cache.
+type CachedRepo struct {
+	URL        string    `json:"url"`
+	Path       string    `json:"path"`
+	BaseBranch string    `json:"base_branch"`
+	LastUsed   time.Time `json:"last_used"`
+	// InUse is the number of checks using the repository.
+	InUse int32 `json:"in_use"`
+}
+
+// Entries lists the repositories in the cache, the most recently used first.
+func (rm *PersistentRepoManager) Entries() []CachedRepo {
+	rm.lock.RLock()
+	defer rm.lock.RUnlock()
+
+	entries := make([]CachedRepo, 0, len(rm.repos))
+	for _, pr := range rm.repos {
+		entries = append(entries, CachedRepo{
+			URL:        pr.CloneURL,
+			Path:       pr.Directory,
+			BaseBranch: pr.baseBranch,
+			LastUsed:   pr.lastUsed,
+			InUse:      atomic.LoadInt32(&pr.refCount),
+		})
 	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries
+}
+
+// Purge removes every repository that is not in use, and returns how many it removed.
+func (rm *PersistentRepoManager) Purge() int {
+	removed, _ := rm.removeUnused(-1)
+	return removed
 }
 
 // Helper functions
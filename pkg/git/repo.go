@@ -397,13 +397,42 @@ func (r *Repo) GetListOfChangedFiles(ctx context.Context) ([]string, error) {
 		return nil, errors.Wrapf(err, "failed to get reference %s", remoteBranchRef)
 	}
 
+	return diffCommits(repo, baseRef.Hash(), headRef.Hash())
+}
+
+// GetListOfChangedFilesBetween returns a list of files that have changed between two revisions, which can be
+// anything git rev-parse understands, e.g. "origin/main", "HEAD~2" or a commit SHA.
+func (r *Repo) GetListOfChangedFilesBetween(ctx context.Context, baseRevision, headRevision string) ([]string, error) {
+	_, span := tracer.Start(ctx, "RepoGetListOfChangedFilesBetween")
+	defer span.End()
+
+	repo, err := gogit.PlainOpen(r.Directory)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open repository")
+	}
+
+	baseHash, err := repo.ResolveRevision(plumbing.Revision(baseRevision))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve revision %s", baseRevision)
+	}
+
+	headHash, err := repo.ResolveRevision(plumbing.Revision(headRevision))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve revision %s", headRevision)
+	}
+
+	return diffCommits(repo, *baseHash, *headHash)
+}
+
+// diffCommits returns the files that differ between the trees of two commits, including both sides of renames.
+func diffCommits(repo *gogit.Repository, baseHash, headHash plumbing.Hash) ([]string, error) {
 	// Get commit objects
-	headCommit, err := repo.CommitObject(headRef.Hash())
+	headCommit, err := repo.CommitObject(headHash)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get HEAD commit")
 	}
 
-	baseCommit, err := repo.CommitObject(baseRef.Hash())
+	baseCommit, err := repo.CommitObject(baseHash)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get base commit")
 	}
@@ -441,6 +470,26 @@ func (r *Repo) GetListOfChangedFiles(ctx context.Context) ([]string, error) {
 	return fileList, nil
 }
 
+// GetRemoteURL returns the first url configured for the origin remote
+func (r *Repo) GetRemoteURL() (string, error) {
+	repo, err := gogit.PlainOpen(r.Directory)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open repository")
+	}
+
+	remote, err := repo.Remote(gogit.DefaultRemoteName)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get origin remote")
+	}
+
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", errors.New("origin remote has no urls")
+	}
+
+	return urls[0], nil
+}
+
 // GetCurrentCommitSHA returns the current commit SHA
 func (r *Repo) GetCurrentCommitSHA() (string, error) {
 	repo, err := gogit.PlainOpen(r.Directory)
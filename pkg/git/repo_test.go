@@ -2,8 +2,13 @@ package git
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -70,3 +75,44 @@ func TestBuildCloneURL(t *testing.T) {
 		})
 	}
 }
+
+func TestRepoGetListOfChangedFilesBetween(t *testing.T) {
+	ctx := context.TODO()
+	dir := t.TempDir()
+
+	r, err := gogit.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := r.Worktree()
+	require.NoError(t, err)
+
+	commit := func(files map[string]string) {
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			_, err := wt.Add(name)
+			require.NoError(t, err)
+		}
+		_, err := wt.Commit("commit", &gogit.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+	}
+
+	commit(map[string]string{"apps/a/app.yaml": "a", "apps/b/app.yaml": "b"})
+	commit(map[string]string{"apps/a/app.yaml": "a2"})
+	commit(map[string]string{"apps/c/app.yaml": "c"})
+
+	repo := &Repo{Directory: dir}
+
+	files, err := repo.GetListOfChangedFilesBetween(ctx, "HEAD~2", "HEAD")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"apps/a/app.yaml", "apps/c/app.yaml"}, files)
+
+	files, err = repo.GetListOfChangedFilesBetween(ctx, "HEAD~2", "HEAD~1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apps/a/app.yaml"}, files)
+
+	_, err = repo.GetListOfChangedFilesBetween(ctx, "missing", "HEAD")
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package helm
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"sigs.k8s.io/yaml"
+)
+
+var ErrInvalidValueRef = errors.New("value file reference must look like $ref/path")
+
+// ProcessChart returns the chart directory, and the directories of all charts it depends on through local
+// file:// dependencies in its Chart.yaml, recursively.
+func ProcessChart(sourceFS fs.FS, relChartPath string) (dirs []string, err error) {
+	proc := processor{
+		visitedDirs: make(map[string]struct{}),
+	}
+
+	dirs, err = proc.processChart(sourceFS, filepath.Clean(relChartPath))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to process chart %q", relChartPath)
+	}
+
+	return dirs, nil
+}
+
+type processor struct {
+	visitedDirs map[string]struct{}
+}
+
+type chartFile struct {
+	Dependencies []struct {
+		Name       string `json:"name"`
+		Repository string `json:"repository"`
+	} `json:"dependencies"`
+}
+
+func (p processor) processChart(sourceFS fs.FS, relBase string) ([]string, error) {
+	if _, ok := p.visitedDirs[relBase]; ok {
+		log.Trace().Caller().Msgf("chart %q already processed", relBase)
+		return nil, nil
+	}
+	p.visitedDirs[relBase] = struct{}{}
+
+	dirs := []string{relBase}
+
+	chartPath := filepath.Join(relBase, "Chart.yaml")
+	file, err := sourceFS.Open(chartPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return dirs, nil // not a chart, the dir is the important thing
+		}
+
+		return nil, errors.Wrapf(err, "failed to open file %q", chartPath)
+	}
+	defer file.Close() // nolint:errcheck // read only
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read file %q", chartPath)
+	}
+
+	var chart chartFile
+	if err = yaml.Unmarshal(content, &chart); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse %q", chartPath)
+	}
+
+	for _, dep := range chart.Dependencies {
+		if !strings.HasPrefix(dep.Repository, "file://") {
+			continue
+		}
+
+		depDir := filepath.Join(relBase, strings.TrimPrefix(dep.Repository, "file://"))
+		if !fs.ValidPath(depDir) {
+			log.Warn().Str("chart", relBase).Str("dependency", dep.Name).Msg("local dependency is outside of the repository")
+			continue
+		}
+
+		depDirs, err := p.processChart(sourceFS, depDir)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to process dependency %q", dep.Name)
+		}
+		dirs = append(dirs, depDirs...)
+	}
+
+	return dirs, nil
+}
+
+// ProcessValueFile returns the files a value file path refers to, and the directory that new matches would
+// be created in when the path is a glob. The path is relative to the root of sourceFS.
+func ProcessValueFile(sourceFS fs.FS, relValuePath string) (files, dirs []string, err error) {
+	relValuePath = filepath.Clean(relValuePath)
+	if !fs.ValidPath(relValuePath) {
+		return nil, nil, nil
+	}
+
+	if !containsGlob(relValuePath) {
+		return []string{relValuePath}, nil, nil
+	}
+
+	files, err = fs.Glob(sourceFS, relValuePath)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to expand %q", relValuePath)
+	}
+
+	return files, []string{staticPrefix(relValuePath)}, nil
+}
+
+// SplitValueRef splits a multi-source value file path like "$values/envs/prod.yaml" into the ref name
+// and the path inside the referenced source.
+func SplitValueRef(valueFile string) (ref, path string, err error) {
+	if !strings.HasPrefix(valueFile, "$") {
+		return "", "", fmt.Errorf("%q: %w", valueFile, ErrInvalidValueRef)
+	}
+
+	ref, path, ok := strings.Cut(strings.TrimPrefix(valueFile, "$"), "/")
+	if !ok || ref == "" || path == "" {
+		return "", "", fmt.Errorf("%q: %w", valueFile, ErrInvalidValueRef)
+	}
+
+	return ref, path, nil
+}
+
+func containsGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// staticPrefix returns the deepest directory of a glob pattern that does not contain any glob characters.
+func staticPrefix(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for containsGlob(dir) {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
@@ -0,0 +1,89 @@
+package helm
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessChart(t *testing.T) {
+	t.Run("NotAChart", func(t *testing.T) {
+		dirs, err := ProcessChart(fstest.MapFS{}, "apps/app")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"apps/app"}, dirs)
+	})
+
+	t.Run("LocalDependencies", func(t *testing.T) {
+		sourceFS := fstest.MapFS{
+			"charts/app/Chart.yaml":    {Data: []byte("name: app\ndependencies:\n- name: lib\n  repository: file://../lib\n- name: common\n  repository: file://../common\n- name: redis\n  repository: oci://registry.example.com/charts\n")},
+			"charts/lib/Chart.yaml":    {Data: []byte("name: lib\ndependencies:\n- name: common\n  repository: file://../common\n")},
+			"charts/common/Chart.yaml": {Data: []byte("name: common\n")},
+		}
+
+		dirs, err := ProcessChart(sourceFS, "charts/app")
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"charts/app", "charts/lib", "charts/common"}, dirs)
+	})
+
+	t.Run("DependencyOutsideRepository", func(t *testing.T) {
+		sourceFS := fstest.MapFS{
+			"app/Chart.yaml": {Data: []byte("name: app\ndependencies:\n- name: lib\n  repository: file://../../lib\n")},
+		}
+
+		dirs, err := ProcessChart(sourceFS, "app")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app"}, dirs)
+	})
+
+	t.Run("InvalidChart", func(t *testing.T) {
+		sourceFS := fstest.MapFS{
+			"app/Chart.yaml": {Data: []byte("dependencies: {")},
+		}
+
+		_, err := ProcessChart(sourceFS, "app")
+		assert.Error(t, err)
+	})
+}
+
+func TestProcessValueFile(t *testing.T) {
+	sourceFS := fstest.MapFS{
+		"values/envs/prod.yaml":    {},
+		"values/envs/staging.yaml": {},
+		"values/envs/README.md":    {},
+	}
+
+	testcases := map[string]struct {
+		path          string
+		expectedFiles []string
+		expectedDirs  []string
+	}{
+		"plain file":    {path: "apps/app/../../values/common.yaml", expectedFiles: []string{"values/common.yaml"}},
+		"glob":          {path: "values/envs/*.yaml", expectedFiles: []string{"values/envs/prod.yaml", "values/envs/staging.yaml"}, expectedDirs: []string{"values/envs"}},
+		"glob dir":      {path: "values/*/prod.yaml", expectedFiles: []string{"values/envs/prod.yaml"}, expectedDirs: []string{"values"}},
+		"outside repo":  {path: "../values.yaml"},
+		"glob no match": {path: "other/*.yaml", expectedDirs: []string{"other"}},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			files, dirs, err := ProcessValueFile(sourceFS, tc.path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedFiles, files)
+			assert.Equal(t, tc.expectedDirs, dirs)
+		})
+	}
+}
+
+func TestSplitValueRef(t *testing.T) {
+	ref, path, err := SplitValueRef("$values/envs/prod.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "values", ref)
+	assert.Equal(t, "envs/prod.yaml", path)
+
+	for _, invalid := range []string{"values/prod.yaml", "$values", "$/prod.yaml", "$values/"} {
+		_, _, err = SplitValueRef(invalid)
+		assert.ErrorIs(t, err, ErrInvalidValueRef, invalid)
+	}
+}
@@ -0,0 +1,345 @@
+// Package history keeps the runs of kubechecks, so that they can be browsed and compared on the dashboard long after
+// their comments scrolled out of sight.
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/zapier/kubechecks/pkg"
+)
+
+// DashboardPathPrefix is where the dashboard listener serves the dashboard.
+const DashboardPathPrefix = "/dashboard"
+
+// Status tells how far a run got.
+type Status string
+
+const (
+	StatusRunning    Status = "running"
+	StatusCompleted  Status = "completed"
+	StatusSuperseded Status = "superseded"
+	StatusCancelled  Status = "cancelled"
+	StatusFailed     Status = "failed"
+)
+
+// Run is a single run of kubechecks against a pull request. States use the vocabulary of the machine-readable reports,
+// e.g. "success" or "failure". The details of each check are not kept, they only belong in the comment.
+type Run struct {
+	ID         uint64 `json:"id"`
+	Status     Status `json:"status"`
+	CommentURL string `json:"commentUrl,omitempty"`
+
+	Repository string `json:"repository"`
+	Number     int    `json:"number"`
+	Title      string `json:"title,omitempty"`
+	SHA        string `json:"sha"`
+	HeadRef    string `json:"headRef,omitempty"`
+
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt,omitempty"`
+	// State is the worst state of all checks that detected changes.
+	State string `json:"state,omitempty"`
+	Apps  []App  `json:"apps,omitempty"`
+}
+
+// Duration is how long a run took, or has been running.
+func (r Run) Duration() time.Duration {
+	if r.FinishedAt.IsZero() {
+		return time.Since(r.StartedAt)
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+type App struct {
+	Name           string  `json:"name"`
+	ArgoCDInstance string  `json:"argocdInstance,omitempty"`
+	Parent         string  `json:"parent,omitempty"`
+	State          string  `json:"state"`
+	Checks         []Check `json:"checks,omitempty"`
+}
+
+type Check struct {
+	Name              string  `json:"name"`
+	State             string  `json:"state"`
+	Summary           string  `json:"summary"`
+	NoChangesDetected bool    `json:"noChangesDetected,omitempty"`
+	DurationSeconds   float64 `json:"durationSeconds"`
+}
+
+// Config configures the store.
+type Config struct {
+	// Path is the file that runs are stored in.
+	Path string
+	// Retention is how long runs are kept. Runs are kept forever when it is 0.
+	Retention time.Duration
+}
+
+var runsBucket = []byte("runs")
+
+// Store keeps runs in a single file, e.g. on a persistent volume.
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration
+}
+
+// NewStore opens, or creates, the file that runs are stored in, and removes the runs that expired.
+func NewStore(cfg Config) (*Store, error) {
+	db, err := bolt.Open(cfg.Path, 0o600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open history store %q", cfg.Path)
+	}
+
+	if err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(runsBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "failed to create runs bucket")
+	}
+
+	s := &Store{db: db, retention: cfg.Retention}
+	if err = s.prune(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Start records a run that just started, and returns the ID it is stored under.
+func (s *Store) Start(run Run) (uint64, error) {
+	run.Status = StatusRunning
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(runsBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		run.ID = id
+
+		return putRun(bucket, run)
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to store run")
+	}
+
+	return run.ID, nil
+}
+
+// Finish replaces a run that was started with its outcome, and removes the runs that expired.
+func (s *Store) Finish(run Run) error {
+	if err := s.db.Update(func(tx *bolt.Tx) error {
+		return putRun(tx.Bucket(runsBucket), run)
+	}); err != nil {
+		return errors.Wrapf(err, "failed to store run %d", run.ID)
+	}
+
+	return s.prune()
+}
+
+func putRun(bucket *bolt.Bucket, run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put(idToKey(run.ID), data)
+}
+
+// Get returns the run stored under id.
+func (s *Store) Get(id uint64) (Run, bool, error) {
+	var run Run
+	var found bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(runsBucket).Get(idToKey(id))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &run)
+	})
+	if err != nil {
+		return Run{}, false, errors.Wrapf(err, "failed to get run %d", id)
+	}
+
+	return run, found, nil
+}
+
+// Filter selects runs. Empty fields match every run.
+type Filter struct {
+	Repository string
+	CheckID    int
+	// Since only matches runs that started after it.
+	Since time.Time
+	// Limit is the most runs that are returned.
+	Limit int
+}
+
+func (f Filter) matches(run Run) bool {
+	if f.Repository != "" && run.Repository != f.Repository {
+		return false
+	}
+	if f.CheckID != 0 && run.Number != f.CheckID {
+		return false
+	}
+	return true
+}
+
+// List returns the runs that match the filter, the most recent first.
+func (s *Store) List(filter Filter) ([]Run, error) {
+	var runs []Run
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// keys are big endian, so walking them backwards starts with the most recent run
+		c := tx.Bucket(runsBucket).Cursor()
+		for _, data := c.Last(); data != nil; _, data = c.Prev() {
+			var run Run
+			if err := json.Unmarshal(data, &run); err != nil {
+				return err
+			}
+
+			if !filter.Since.IsZero() && run.StartedAt.Before(filter.Since) {
+				break
+			}
+			if !filter.matches(run) {
+				continue
+			}
+
+			runs = append(runs, run)
+			if filter.Limit > 0 && len(runs) >= filter.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list runs")
+	}
+
+	return runs, nil
+}
+
+// CheckTrend counts how often a check of an app failed.
+type CheckTrend struct {
+	App      string `json:"app"`
+	Check    string `json:"check"`
+	Runs     int    `json:"runs"`
+	Failures int    `json:"failures"`
+}
+
+// Trends counts the failures of each check of each app in the completed runs that match the filter, the checks that
+// failed most often first. A failure is a check that ended in the failure, error or panic state.
+func (s *Store) Trends(filter Filter) ([]CheckTrend, error) {
+	filter.Limit = 0
+	runs, err := s.List(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct{ app, check string }
+	counts := make(map[key]*CheckTrend)
+	for _, run := range runs {
+		if run.Status != StatusCompleted {
+			continue
+		}
+
+		for _, app := range run.Apps {
+			for _, check := range app.Checks {
+				if check.Name == "" || check.NoChangesDetected {
+					continue
+				}
+
+				k := key{app.Name, check.Name}
+				trend, ok := counts[k]
+				if !ok {
+					trend = &CheckTrend{App: app.Name, Check: check.Name}
+					counts[k] = trend
+				}
+				trend.Runs++
+				if state, err := pkg.ParseCommitState(check.State); err == nil && state >= pkg.StateFailure {
+					trend.Failures++
+				}
+			}
+		}
+	}
+
+	trends := make([]CheckTrend, 0, len(counts))
+	for _, trend := range counts {
+		if trend.Failures > 0 {
+			trends = append(trends, *trend)
+		}
+	}
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Failures != trends[j].Failures {
+			return trends[i].Failures > trends[j].Failures
+		}
+		if trends[i].App != trends[j].App {
+			return trends[i].App < trends[j].App
+		}
+		return trends[i].Check < trends[j].Check
+	})
+
+	return trends, nil
+}
+
+// prune removes the runs that started longer than the retention ago.
+func (s *Store) prune() error {
+	if s.retention <= 0 {
+		return nil
+	}
+	cutoff := time.Now().Add(-s.retention)
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		// the oldest runs come first, so the walk stops at the first run that is kept
+		c := tx.Bucket(runsBucket).Cursor()
+		for k, data := c.First(); k != nil; k, data = c.First() {
+			var run Run
+			if err := json.Unmarshal(data, &run); err != nil {
+				return err
+			}
+			if !run.StartedAt.Before(cutoff) {
+				return nil
+			}
+			if err := c.Delete(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return errors.Wrap(err, "failed to remove expired runs")
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func idToKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// RunURL returns the link to the page of a run on the dashboard, or "" when the address the dashboard listener is
+// reachable at is not known.
+func RunURL(base string, id uint64) string {
+	if base == "" {
+		return ""
+	}
+
+	u, err := url.JoinPath(base, DashboardPathPrefix, "runs", strconv.FormatUint(id, 10))
+	if err != nil {
+		return ""
+	}
+	return u
+}
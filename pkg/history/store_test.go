@@ -0,0 +1,134 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRun(repo string, number int, startedAt time.Time, apps ...App) Run {
+	return Run{Repository: repo, Number: number, StartedAt: startedAt, Apps: apps}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := NewStore(Config{Path: path})
+	require.NoError(t, err)
+
+	now := time.Now()
+	app := App{Name: "app", State: "failure", Checks: []Check{
+		{Name: "rego", State: "failure", Summary: "Rego"},
+	}}
+
+	id, err := store.Start(newRun("zapier/kubechecks", 1, now, app))
+	require.NoError(t, err)
+
+	run, ok, err := store.Get(id)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, StatusRunning, run.Status)
+	assert.Equal(t, []App{app}, run.Apps)
+
+	run.Status = StatusCompleted
+	run.CommentURL = "https://github.com/zapier/kubechecks/pull/1#issuecomment-1"
+	require.NoError(t, store.Finish(run))
+
+	otherID, err := store.Start(newRun("zapier/other", 2, now.Add(time.Second)))
+	require.NoError(t, err)
+
+	// the store survives a restart
+	require.NoError(t, store.Close())
+	store, err = NewStore(Config{Path: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	runs, err := store.List(Filter{})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, otherID, runs[0].ID, "the most recent run comes first")
+	assert.Equal(t, id, runs[1].ID)
+	assert.Equal(t, StatusCompleted, runs[1].Status)
+	assert.Equal(t, "https://github.com/zapier/kubechecks/pull/1#issuecomment-1", runs[1].CommentURL)
+
+	runs, err = store.List(Filter{Repository: "zapier/kubechecks", CheckID: 1})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, id, runs[0].ID)
+
+	runs, err = store.List(Filter{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, runs, 1)
+
+	_, ok, err = store.Get(42)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStore_Retention(t *testing.T) {
+	store, err := NewStore(Config{Path: filepath.Join(t.TempDir(), "history.db"), Retention: time.Hour})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	oldID, err := store.Start(newRun("zapier/kubechecks", 1, time.Now().Add(-2*time.Hour)))
+	require.NoError(t, err)
+	id, err := store.Start(newRun("zapier/kubechecks", 2, time.Now()))
+	require.NoError(t, err)
+
+	run, _, err := store.Get(id)
+	require.NoError(t, err)
+	run.Status = StatusCompleted
+	require.NoError(t, store.Finish(run))
+
+	_, ok, err := store.Get(oldID)
+	require.NoError(t, err)
+	assert.False(t, ok, "expired runs are removed")
+	_, ok, err = store.Get(id)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestStore_Trends(t *testing.T) {
+	store, err := NewStore(Config{Path: filepath.Join(t.TempDir(), "history.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	record := func(status Status, apps ...App) {
+		id, err := store.Start(newRun("zapier/kubechecks", 1, time.Now(), apps...))
+		require.NoError(t, err)
+		run, _, err := store.Get(id)
+		require.NoError(t, err)
+		run.Status = status
+		require.NoError(t, store.Finish(run))
+	}
+	app := func(name string, checks ...Check) App {
+		return App{Name: name, Checks: checks}
+	}
+
+	record(StatusCompleted,
+		app("api", Check{Name: "rego", State: "failure"}, Check{Name: "diff", State: "success"}),
+		app("web", Check{Name: "rego", State: "error"}),
+	)
+	record(StatusCompleted,
+		app("api", Check{Name: "rego", State: "failure"}, Check{Name: "diff", State: "success"}),
+		app("web", Check{Name: "rego", State: "success"}),
+	)
+	// unchanged apps and interrupted runs are not counted
+	record(StatusCompleted, app("web", Check{Name: "rego", State: "failure", NoChangesDetected: true}))
+	record(StatusSuperseded, app("api", Check{Name: "rego", State: "failure"}))
+
+	trends, err := store.Trends(Filter{})
+	require.NoError(t, err)
+	assert.Equal(t, []CheckTrend{
+		{App: "api", Check: "rego", Runs: 2, Failures: 2},
+		{App: "web", Check: "rego", Runs: 2, Failures: 1},
+	}, trends)
+}
+
+func TestRunURL(t *testing.T) {
+	assert.Equal(t, "https://kubechecks.internal/dashboard/runs/7", RunURL("https://kubechecks.internal", 7))
+	assert.Equal(t, "https://kubechecks.internal/dashboard/runs/7", RunURL("https://kubechecks.internal/", 7))
+	assert.Empty(t, RunURL("", 7))
+}
@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -37,11 +38,34 @@ func ProcessKustomizationFile(sourceFS fs.FS, relKustomizationPath string) (file
 	return files, dirs, nil
 }
 
+// RemoteResources returns what a kustomization file and the local bases it references pull in when they are built:
+// the remote bases, resources and components, and the helm charts that are inflated from a chart repository.
+func RemoteResources(sourceFS fs.FS, relKustomizationPath string) (resources []string, charts []types.HelmChart, err error) {
+	filename := filepath.Base(relKustomizationPath)
+	if filename != "kustomization.yaml" {
+		return nil, nil, fmt.Errorf("%q was unexpected: %w", relKustomizationPath, ErrUnexpectedFilename)
+	}
+
+	proc := processor{
+		visitedDirs: make(map[string]struct{}),
+	}
+
+	if _, _, err = proc.processDir(sourceFS, filepath.Dir(relKustomizationPath)); err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to process kustomize file %q", relKustomizationPath)
+	}
+
+	return proc.remoteResources, proc.remoteCharts, nil
+}
+
 type processor struct {
 	visitedDirs map[string]struct{}
+
+	// remoteResources and remoteCharts collect what the processed kustomizations pull in from elsewhere
+	remoteResources []string
+	remoteCharts    []types.HelmChart
 }
 
-func (p processor) processDir(sourceFS fs.FS, relBase string) (files, dirs []string, err error) {
+func (p *processor) processDir(sourceFS fs.FS, relBase string) (files, dirs []string, err error) {
 	if _, ok := p.visitedDirs[relBase]; ok {
 		log.Warn().Msgf("directory %q already processed", relBase)
 		return nil, nil, nil
@@ -80,6 +104,17 @@ func (p processor) processDir(sourceFS fs.FS, relBase string) (files, dirs []str
 	var directories []string
 	directories = append(directories, kust.Components...)
 
+	for _, resource := range append(slices.Clone(filesOrDirectories), directories...) {
+		if isRemoteResource(resource) {
+			p.remoteResources = append(p.remoteResources, resource)
+		}
+	}
+	for _, chart := range kust.HelmCharts {
+		if chart.Repo != "" {
+			p.remoteCharts = append(p.remoteCharts, chart)
+		}
+	}
+
 	files = []string{"kustomization.yaml"}
 	files = append(files, kust.Configurations...)
 	files = append(files, kust.Crds...)
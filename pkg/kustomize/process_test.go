@@ -263,6 +263,37 @@ dummy:
 	})
 }
 
+func TestRemoteResources(t *testing.T) {
+	sourceFS := fstest.MapFS{
+		"testdir/kustomization.yaml": &fstest.MapFile{
+			Data: []byte(`
+resources:
+- resource.yaml
+- base
+- https://github.com/user/repo//deploy?ref=main
+helmCharts:
+- name: local
+- name: redis
+  repo: https://charts.example.com
+  version: 1.2.3
+`),
+		},
+		"testdir/resource.yaml": &fstest.MapFile{},
+		"testdir/base/kustomization.yaml": &fstest.MapFile{
+			Data: []byte(`components: ["github.com/user/repo/components?ref=v1"]`),
+		},
+	}
+
+	resources, charts, err := RemoteResources(sourceFS, filepath.Join("testdir", "kustomization.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://github.com/user/repo//deploy?ref=main", "github.com/user/repo/components?ref=v1"}, resources)
+	require.Len(t, charts, 1)
+	assert.Equal(t, "redis", charts[0].Name)
+
+	_, _, err = RemoteResources(sourceFS, filepath.Join("testdir", "kustomization.yml"))
+	assert.ErrorIs(t, err, ErrUnexpectedFilename)
+}
+
 func TestIsRemoteResource(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,89 @@
+package leader
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Config describes the lease that the replicas of kubechecks compete for.
+type Config struct {
+	Namespace string
+	Name      string
+	// Identity is unique to each replica, usually the name of its pod.
+	Identity string
+
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	if c.LeaseDuration == 0 {
+		c.LeaseDuration = 15 * time.Second
+	}
+	if c.RenewDeadline == 0 {
+		c.RenewDeadline = 10 * time.Second
+	}
+	if c.RetryPeriod == 0 {
+		c.RetryPeriod = 2 * time.Second
+	}
+	return c
+}
+
+// Duty is work that only one replica should do at a time. Its context is cancelled when the replica stops leading.
+type Duty func(ctx context.Context)
+
+// Run takes part in the election until ctx is done. Whenever this replica becomes the leader, every duty is
+// started; when the lease is lost, their context is cancelled, and the replica campaigns again.
+func Run(ctx context.Context, client kubernetes.Interface, cfg Config, duties ...Duty) error {
+	cfg = cfg.withDefaults()
+
+	lock, err := resourcelock.New(
+		resourcelock.LeasesResourceLock,
+		cfg.Namespace, cfg.Name,
+		client.CoreV1(), client.CoordinationV1(),
+		resourcelock.ResourceLockConfig{Identity: cfg.Identity},
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create lease lock")
+	}
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		Name:            cfg.Name,
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.RenewDeadline,
+		RetryPeriod:     cfg.RetryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Info().Str("identity", cfg.Identity).Msg("started leading, running singleton duties")
+				for _, duty := range duties {
+					go duty(ctx)
+				}
+			},
+			OnStoppedLeading: func() {
+				log.Info().Str("identity", cfg.Identity).Msg("stopped leading")
+			},
+			OnNewLeader: func(identity string) {
+				log.Debug().Caller().Str("leader", identity).Msg("observed a new leader")
+			},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to create leader elector")
+	}
+
+	// Run returns once the lease is lost, campaign again until we are told to stop
+	for ctx.Err() == nil {
+		elector.Run(ctx)
+	}
+
+	return nil
+}
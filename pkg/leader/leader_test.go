@@ -0,0 +1,55 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRun(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	cfg := Config{
+		Namespace:     "kubechecks",
+		Name:          "kubechecks",
+		LeaseDuration: time.Second,
+		RenewDeadline: 500 * time.Millisecond,
+		RetryPeriod:   100 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan string, 2)
+	for _, identity := range []string{"replica-1", "replica-2"} {
+		cfg := cfg
+		cfg.Identity = identity
+		go func() {
+			assert.NoError(t, Run(ctx, client, cfg, func(ctx context.Context) {
+				started <- identity
+			}))
+		}()
+	}
+
+	var leader string
+	select {
+	case leader = <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no replica became the leader")
+	}
+
+	lease, err := client.CoordinationV1().Leases("kubechecks").Get(context.Background(), "kubechecks", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, leader, *lease.Spec.HolderIdentity)
+
+	// the other replica never runs the duties while the leader holds the lease
+	select {
+	case identity := <-started:
+		t.Fatalf("%s also became the leader", identity)
+	case <-time.After(2 * time.Second):
+	}
+}
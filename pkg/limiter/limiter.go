@@ -0,0 +1,117 @@
+// Package limiter bounds how many calls kubechecks makes at once to a shared backend, like the Argo CD repo server,
+// a destination cluster or an AI provider. The checks of every PR share the same limits.
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("pkg/limiter")
+
+// Semaphore allows up to a fixed number of calls at once to a backend. A nil Semaphore doesn't limit anything.
+type Semaphore struct {
+	name   string
+	key    string
+	tokens chan struct{}
+}
+
+// New returns a semaphore that allows limit calls at once to the backend identified by name and key, or nil when
+// limit is 0 or less.
+func New(name, key string, limit int) *Semaphore {
+	if limit <= 0 {
+		return nil
+	}
+
+	limitGauge.WithLabelValues(name, key).Set(float64(limit))
+	return &Semaphore{
+		name:   name,
+		key:    key,
+		tokens: make(chan struct{}, limit),
+	}
+}
+
+// Acquire waits until a call may be made, and returns the function that must be called once it is done. The wait is
+// recorded as a span. An error is returned if ctx is done first.
+func (s *Semaphore) Acquire(ctx context.Context) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	_, span := tracer.Start(ctx, "limiter.Acquire", trace.WithAttributes(
+		attribute.String("limiter", s.name),
+		attribute.String("key", s.key),
+	))
+	defer span.End()
+
+	start := time.Now()
+	waiting := waitingGauge.WithLabelValues(s.name, s.key)
+	waiting.Inc()
+	defer waiting.Dec()
+
+	select {
+	case s.tokens <- struct{}{}:
+	case <-ctx.Done():
+		err := errors.Wrapf(context.Cause(ctx), "waiting for %s %s", s.name, s.key)
+		span.RecordError(err)
+		return nil, err
+	}
+
+	wait := time.Since(start)
+	span.SetAttributes(attribute.Float64("wait_seconds", wait.Seconds()))
+	waitDuration.WithLabelValues(s.name).Observe(wait.Seconds())
+	inUseGauge.WithLabelValues(s.name, s.key).Inc()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			inUseGauge.WithLabelValues(s.name, s.key).Dec()
+			<-s.tokens
+		})
+	}, nil
+}
+
+// Keyed holds a semaphore for each key, e.g. for each destination cluster. A nil Keyed doesn't limit anything.
+type Keyed struct {
+	name  string
+	limit int
+
+	mu         sync.Mutex
+	semaphores map[string]*Semaphore
+}
+
+// NewKeyed returns semaphores that allow limit calls at once for each key, or nil when limit is 0 or less.
+func NewKeyed(name string, limit int) *Keyed {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &Keyed{
+		name:       name,
+		limit:      limit,
+		semaphores: make(map[string]*Semaphore),
+	}
+}
+
+// Acquire waits until a call for key may be made, see Semaphore.Acquire.
+func (k *Keyed) Acquire(ctx context.Context, key string) (func(), error) {
+	if k == nil {
+		return func() {}, nil
+	}
+
+	k.mu.Lock()
+	semaphore, ok := k.semaphores[key]
+	if !ok {
+		semaphore = New(k.name, key, k.limit)
+		k.semaphores[key] = semaphore
+	}
+	k.mu.Unlock()
+
+	return semaphore.Acquire(ctx)
+}
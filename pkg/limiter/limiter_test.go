@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore(t *testing.T) {
+	s := New("repo-server", "test-semaphore", 2)
+
+	first, err := s.Acquire(context.Background())
+	require.NoError(t, err)
+	second, err := s.Acquire(context.Background())
+	require.NoError(t, err)
+
+	// the third call waits until one of the others is done
+	acquired := make(chan func())
+	go func() {
+		release, err := s.Acquire(context.Background())
+		assert.NoError(t, err)
+		acquired <- release
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired more than the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first()
+	first() // releasing twice frees a single slot
+	third := <-acquired
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = s.Acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	second()
+	third()
+}
+
+func TestSemaphore_NoLimit(t *testing.T) {
+	s := New("ai-provider", "test-no-limit", 0)
+	assert.Nil(t, s)
+
+	for i := 0; i < 10; i++ {
+		_, err := s.Acquire(context.Background())
+		require.NoError(t, err)
+	}
+}
+
+func TestKeyed(t *testing.T) {
+	k := NewKeyed("cluster", 1)
+
+	release, err := k.Acquire(context.Background(), "https://a.example.com")
+	require.NoError(t, err)
+
+	// another cluster has a slot of its own
+	other, err := k.Acquire(context.Background(), "https://b.example.com")
+	require.NoError(t, err)
+	other()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = k.Acquire(ctx, "https://a.example.com")
+	assert.Error(t, err)
+
+	release()
+	release, err = k.Acquire(context.Background(), "https://a.example.com")
+	require.NoError(t, err)
+	release()
+
+	assert.Nil(t, NewKeyed("cluster", 0))
+}
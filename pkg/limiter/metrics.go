@@ -0,0 +1,57 @@
+package limiter
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	labels = []string{"limiter", "key"}
+
+	limitGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kubechecks",
+			Subsystem: "limiter",
+			Name:      "limit",
+			Help:      "Number of calls allowed at once",
+		},
+		labels,
+	)
+
+	inUseGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kubechecks",
+			Subsystem: "limiter",
+			Name:      "in_use",
+			Help:      "Number of calls in progress",
+		},
+		labels,
+	)
+
+	waitingGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "kubechecks",
+			Subsystem: "limiter",
+			Name:      "waiting",
+			Help:      "Number of calls waiting for their turn",
+		},
+		labels,
+	)
+
+	waitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "kubechecks",
+			Subsystem: "limiter",
+			Name:      "wait_duration_seconds",
+			Help:      "Time a call waited for its turn (seconds)",
+			Buckets:   []float64{0.01, 0.1, 1, 5, 10, 30, 60, 300},
+		},
+		[]string{"limiter"},
+	)
+)
+
+func init() {
+	r := prometheus.DefaultRegisterer
+
+	r.MustRegister(limitGauge)
+	r.MustRegister(inUseGauge)
+	r.MustRegister(waitingGauge)
+	r.MustRegister(waitDuration)
+}
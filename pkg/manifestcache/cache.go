@@ -0,0 +1,336 @@
+// Package manifestcache keeps rendered manifests on disk, keyed by a hash of everything rendering depends on, so that
+// applications whose inputs did not change are not rendered again on a new push or a re-run.
+package manifestcache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zapier/kubechecks/pkg"
+)
+
+// Config holds cache configuration
+type Config struct {
+	Dir string // Directory the manifests are stored in
+	// TTL is how long rendered manifests are used, there is no limit when 0.
+	TTL time.Duration
+	// MaxSize is the number of bytes the cache may use. The least recently used entries are removed beyond it, and
+	// there is no limit when 0.
+	MaxSize int64
+}
+
+// Cache stores the rendered manifests of each key in a file.
+type Cache struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+
+	// mu guards size, and makes sure only one goroutine evicts entries at a time
+	mu   sync.Mutex
+	size int64
+}
+
+type entry struct {
+	Created   time.Time `json:"created"`
+	Manifests []string  `json:"manifests"`
+}
+
+// NewCache creates the cache directory if needed, and returns a cache that uses it.
+func NewCache(cfg Config) (*Cache, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, errors.Wrap(err, "failed to create manifest cache directory")
+	}
+
+	c := &Cache{dir: cfg.Dir, ttl: cfg.TTL, maxSize: cfg.MaxSize}
+
+	files, err := c.files()
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		c.size += file.size
+	}
+	manifestCacheSize.Set(float64(c.size))
+
+	log.Info().
+		Str("dir", cfg.Dir).
+		Str("ttl", cfg.TTL.String()).
+		Int64("max_size", cfg.MaxSize).
+		Int64("size", c.size).
+		Msg("manifest cache enabled")
+
+	return c, nil
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+// Get returns the manifests stored for key, and false if there are none or they expired.
+func (c *Cache) Get(key string) ([]string, bool) {
+	path := c.path(key)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("path", path).Msg("failed to read cached manifests")
+		}
+		manifestCacheMisses.Inc()
+		return nil, false
+	}
+
+	var e entry
+	if err = json.Unmarshal(data, &e); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("failed to parse cached manifests, removing them")
+		c.remove(path, int64(len(data)))
+		manifestCacheMisses.Inc()
+		return nil, false
+	}
+
+	if c.ttl > 0 && time.Since(e.Created) > c.ttl {
+		c.remove(path, int64(len(data)))
+		manifestCacheMisses.Inc()
+		return nil, false
+	}
+
+	// the modification time tracks when an entry was last used, for evictions
+	now := time.Now()
+	if err = os.Chtimes(path, now, now); err != nil {
+		log.Debug().Caller().Err(err).Str("path", path).Msg("failed to touch cached manifests")
+	}
+
+	manifestCacheHits.Inc()
+	return e.Manifests, true
+}
+
+// Put stores the manifests of key, and removes the least recently used entries if the cache is too big.
+func (c *Cache) Put(key string, manifests []string) error {
+	data, err := json.Marshal(entry{Created: time.Now(), Manifests: manifests})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode manifests")
+	}
+
+	path := c.path(key)
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create manifest cache directory")
+	}
+
+	// write to a temporary file first, so that readers never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create manifest cache file")
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return errors.Wrap(err, "failed to write manifest cache file")
+	}
+	if err = tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to write manifest cache file")
+	}
+
+	var previous int64
+	if info, err := os.Stat(path); err == nil {
+		previous = info.Size()
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "failed to store manifest cache file")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size += int64(len(data)) - previous
+	if c.maxSize > 0 && c.size > c.maxSize {
+		c.evict()
+	}
+	manifestCacheSize.Set(float64(c.size))
+
+	return nil
+}
+
+func (c *Cache) remove(path string, size int64) {
+	if err := os.Remove(path); err != nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size -= size
+	manifestCacheSize.Set(float64(c.size))
+}
+
+// Stats returns the number of cached entries and their size in bytes.
+func (c *Cache) Stats() (entries int, size int64, err error) {
+	files, err := c.files()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, file := range files {
+		size += file.size
+	}
+	return len(files), size, nil
+}
+
+// Purge removes every entry, and returns how many it removed.
+func (c *Cache) Purge() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	files, err := c.files()
+	if err != nil {
+		return 0, err
+	}
+
+	c.size = 0
+	removed := 0
+	for _, file := range files {
+		if err = os.Remove(file.path); err != nil {
+			c.size += file.size
+			continue
+		}
+		removed++
+	}
+	manifestCacheSize.Set(float64(c.size))
+
+	log.Info().Int("removed", removed).Msg("purged manifest cache")
+	return removed, nil
+}
+
+type cacheFile struct {
+	path     string
+	size     int64
+	lastUsed time.Time
+}
+
+func (c *Cache) files() ([]cacheFile, error) {
+	var files []cacheFile
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, cacheFile{path: path, size: info.Size(), lastUsed: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list cached manifests")
+	}
+
+	return files, nil
+}
+
+// evict removes the least recently used entries until the cache fits in its maximum size, starting with the ones
+// that were not used within the TTL. The caller must hold c.mu.
+func (c *Cache) evict() {
+	files, err := c.files()
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to evict cached manifests")
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].lastUsed.Before(files[j].lastUsed)
+	})
+
+	c.size = 0
+	for _, file := range files {
+		c.size += file.size
+	}
+
+	target := c.maxSize * 9 / 10 // leave some room, so that the next few entries don't evict again
+	for _, file := range files {
+		expired := c.ttl > 0 && time.Since(file.lastUsed) > c.ttl
+		if c.size <= target && !expired {
+			break
+		}
+		if err = os.Remove(file.path); err != nil {
+			continue
+		}
+		c.size -= file.size
+		manifestCacheEvictions.Inc()
+	}
+}
+
+// Key hashes everything rendering depends on into a cache key.
+func Key(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		// the length keeps ("ab", "c") and ("a", "bc") apart
+		_ = binary.Write(h, binary.BigEndian, uint64(len(part)))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashDir hashes the paths and contents of the files in dir, skipping the directories named in exclude. It is the
+// same for two directories with the same files, whenever and wherever they were created.
+func HashDir(dir string, exclude []string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && path != dir && slices.Contains(exclude, d.Name()) {
+			return filepath.SkipDir
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			_, _ = io.WriteString(h, "dir\x00"+rel+"\x00")
+		case d.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			_, _ = io.WriteString(h, "link\x00"+rel+"\x00"+target+"\x00")
+		default:
+			_, _ = io.WriteString(h, "file\x00"+rel+"\x00")
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer pkg.WithErrorLogging(f.Close, "failed to close file")
+
+			size, err := io.Copy(h, f)
+			if err != nil {
+				return err
+			}
+			_ = binary.Write(h, binary.BigEndian, uint64(size))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to hash directory")
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
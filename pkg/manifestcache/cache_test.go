@@ -0,0 +1,101 @@
+package manifestcache
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	cache, err := NewCache(Config{Dir: t.TempDir(), TTL: time.Hour})
+	require.NoError(t, err)
+
+	key := Key([]byte("repo-server/v3.0.0"), []byte("files"))
+	_, ok := cache.Get(key)
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Put(key, []string{"kind: ConfigMap"}))
+	manifests, ok := cache.Get(key)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"kind: ConfigMap"}, manifests)
+
+	// a new cache finds the manifests of the previous one
+	cache, err = NewCache(Config{Dir: cache.dir, TTL: time.Hour})
+	require.NoError(t, err)
+	_, ok = cache.Get(key)
+	assert.True(t, ok)
+}
+
+func TestCache_TTL(t *testing.T) {
+	cache, err := NewCache(Config{Dir: t.TempDir(), TTL: time.Millisecond})
+	require.NoError(t, err)
+
+	key := Key([]byte("a"))
+	require.NoError(t, cache.Put(key, []string{"kind: ConfigMap"}))
+	time.Sleep(10 * time.Millisecond)
+
+	_, ok := cache.Get(key)
+	assert.False(t, ok)
+	assert.NoFileExists(t, cache.path(key))
+}
+
+func TestCache_Evict(t *testing.T) {
+	cache, err := NewCache(Config{Dir: t.TempDir(), MaxSize: 250})
+	require.NoError(t, err)
+
+	manifest := []string{strings.Repeat("x", 50)}
+	first, second, third := Key([]byte("1")), Key([]byte("2")), Key([]byte("3"))
+	require.NoError(t, cache.Put(first, manifest))
+	require.NoError(t, cache.Put(second, manifest))
+
+	// the first entry is used after the second one, so the second one is evicted first
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(cache.path(second), past, past))
+	_, ok := cache.Get(first)
+	require.True(t, ok)
+
+	require.NoError(t, cache.Put(third, manifest))
+	assert.FileExists(t, cache.path(first))
+	assert.NoFileExists(t, cache.path(second))
+	assert.FileExists(t, cache.path(third))
+	assert.LessOrEqual(t, cache.size, int64(250))
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, Key([]byte("a"), []byte("b")), Key([]byte("a"), []byte("b")))
+	assert.NotEqual(t, Key([]byte("ab"), []byte("c")), Key([]byte("a"), []byte("bc")))
+}
+
+func TestHashDir(t *testing.T) {
+	write := func(t *testing.T, files map[string]string) string {
+		dir := t.TempDir()
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		}
+		return dir
+	}
+	hash := func(t *testing.T, files map[string]string) string {
+		h, err := HashDir(write(t, files), []string{".git"})
+		require.NoError(t, err)
+		return h
+	}
+
+	files := map[string]string{"app/values.yaml": "replicas: 1", "app/Chart.yaml": "name: app"}
+	assert.Equal(t, hash(t, files), hash(t, files), "the same files hash the same in another directory")
+
+	withGit := map[string]string{"app/values.yaml": "replicas: 1", "app/Chart.yaml": "name: app", ".git/HEAD": "ref"}
+	assert.Equal(t, hash(t, files), hash(t, withGit), "excluded directories are ignored")
+
+	changed := map[string]string{"app/values.yaml": "replicas: 2", "app/Chart.yaml": "name: app"}
+	assert.NotEqual(t, hash(t, files), hash(t, changed))
+
+	moved := map[string]string{"other/values.yaml": "replicas: 1", "app/Chart.yaml": "name: app"}
+	assert.NotEqual(t, hash(t, files), hash(t, moved))
+}
@@ -0,0 +1,47 @@
+package manifestcache
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	manifestCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "kubechecks",
+			Subsystem: "manifest_cache",
+			Name:      "hits_total",
+			Help:      "Number of renders skipped because the manifests were cached",
+		},
+	)
+	manifestCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "kubechecks",
+			Subsystem: "manifest_cache",
+			Name:      "misses_total",
+			Help:      "Number of renders whose manifests were not cached",
+		},
+	)
+	manifestCacheEvictions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "kubechecks",
+			Subsystem: "manifest_cache",
+			Name:      "evictions_total",
+			Help:      "Number of cached manifests removed to stay within the maximum size",
+		},
+	)
+	manifestCacheSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "kubechecks",
+			Subsystem: "manifest_cache",
+			Name:      "size_bytes",
+			Help:      "Size of the cached manifests (bytes)",
+		},
+	)
+)
+
+func init() {
+	r := prometheus.DefaultRegisterer
+
+	r.MustRegister(manifestCacheHits)
+	r.MustRegister(manifestCacheMisses)
+	r.MustRegister(manifestCacheEvictions)
+	r.MustRegister(manifestCacheSize)
+}
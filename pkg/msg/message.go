@@ -21,6 +21,24 @@ type Result struct {
 	State             pkg.CommitState
 	Summary, Details  string
 	NoChangesDetected bool
+
+	// Check is the name of the processor that produced this result, and Duration how long it took.
+	// Both are filled in by the check runner and are only used for machine-readable reports.
+	Check    string
+	Duration time.Duration
+
+	// Findings holds the individual issues behind this result, for checks that can report them.
+	Findings []Finding
+}
+
+// Finding is a single issue reported by a check, such as a schema violation, a failed policy
+// or a deprecated API. Findings are not rendered in comments, they are exported in reports.
+type Finding struct {
+	Tool     string          // the tool that reported the finding, e.g. "kubeconform"
+	RuleID   string          // a stable identifier for the kind of finding
+	Level    pkg.CommitState // how severe the finding is
+	Message  string
+	Resource string // the rendered resource the finding is about, if any
 }
 
 type AppResults struct {
@@ -39,6 +57,9 @@ func NewMessage(name string, prId, commentId int, vcs toEmoji) *Message {
 		vcs:     vcs,
 
 		apps:           make(map[string]*AppResults),
+		appSets:        make(map[string]Result),
+		reasons:        make(map[string][]string),
+		parents:        make(map[string]string),
 		deletedAppsSet: make(map[string]struct{}),
 	}
 }
@@ -56,10 +77,21 @@ type Message struct {
 	CheckID int
 	NoteID  int
 
+	// URL links to the comment on the VCS, when the client knows it.
+	URL string
+	// RunURL links to the page of the run on the dashboard, and is shown in the footer when set.
+	RunURL string
+
 	// Key = Appname, value = Results
 	apps map[string]*AppResults
-	lock sync.Mutex
-	vcs  toEmoji
+	// Key = Appname, value = why the app was checked
+	reasons map[string][]string
+	// Key = Appname, value = the app-of-apps that rendered it
+	parents map[string]string
+	// Key = Appsetname, value = the apps the appset will create, delete and update
+	appSets map[string]Result
+	lock    sync.Mutex
+	vcs     toEmoji
 
 	deletedAppsSet map[string]struct{}
 }
@@ -67,6 +99,10 @@ type Message struct {
 func (m *Message) WorstState() pkg.CommitState {
 	state := pkg.StateNone
 
+	for _, result := range m.appSets {
+		state = pkg.WorstState(state, result.State)
+	}
+
 	for app, r := range m.apps {
 		if m.isDeleted(app) {
 			continue
@@ -84,6 +120,75 @@ func (m *Message) WorstState() pkg.CommitState {
 	return state
 }
 
+// Snapshot returns a copy of the results recorded so far for every app that has not been removed, keyed by app name.
+func (m *Message) Snapshot() map[string][]Result {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	snapshot := make(map[string][]Result, len(m.apps))
+	for app, r := range m.apps {
+		if m.isDeleted(app) {
+			continue
+		}
+
+		snapshot[app] = append([]Result(nil), r.results...)
+	}
+
+	return snapshot
+}
+
+// AddReasons records why an app was checked. Reasons are shown in a collapsed block above the check results.
+func (m *Message) AddReasons(app string, reasons ...string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for _, reason := range reasons {
+		if slices.Contains(m.reasons[app], reason) {
+			continue
+		}
+		m.reasons[app] = append(m.reasons[app], reason)
+	}
+}
+
+// AddAppSetPreview records the apps an appset will create, delete and update. Previews are shown above the apps.
+func (m *Message) AddAppSetPreview(appSet string, result Result) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.appSets[appSet] = result
+}
+
+// SetParent records that an app was rendered by an app-of-apps. The first parent of an app is kept.
+func (m *Message) SetParent(app, parent string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.parents[app]; !ok {
+		m.parents[app] = parent
+	}
+}
+
+// Parent returns the app-of-apps that rendered an app, if any.
+func (m *Message) Parent(app string) (string, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	parent, ok := m.parents[app]
+	return parent, ok
+}
+
+// Parents returns a copy of the app-of-apps of every child app, keyed by app name.
+func (m *Message) Parents() map[string]string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	parents := make(map[string]string, len(m.parents))
+	for app, parent := range m.parents {
+		parents[app] = parent
+	}
+	return parents
+}
+
 func (m *Message) RemoveApp(app string) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -135,8 +240,13 @@ func (m *Message) buildFooter(
 	start time.Time, commitSHA, labelFilter string, showDebugInfo bool,
 	appsChecked, totalChecked int,
 ) string {
+	runLink := ""
+	if m.RunURL != "" {
+		runLink = fmt.Sprintf(" [Run details](%s)", m.RunURL)
+	}
+
 	if !showDebugInfo {
-		return fmt.Sprintf("<small> _Done. CommitSHA: %s_ <small>\n", commitSHA)
+		return fmt.Sprintf("<small> _Done. CommitSHA: %s_%s <small>\n", commitSHA, runLink)
 	}
 
 	envStr := ""
@@ -145,8 +255,8 @@ func (m *Message) buildFooter(
 	}
 	duration := time.Since(start)
 
-	return fmt.Sprintf("<small> _Done: Pod: %s, Dur: %v, SHA: %s%s_ <small>, Apps Checked: %d, Total Checks: %d\n",
-		hostname, duration.Round(time.Second), pkg.GitCommit, envStr, appsChecked, totalChecked)
+	return fmt.Sprintf("<small> _Done: Pod: %s, Dur: %v, SHA: %s%s_ <small>, Apps Checked: %d, Total Checks: %d%s\n",
+		hostname, duration.Round(time.Second), pkg.GitCommit, envStr, appsChecked, totalChecked, runLink)
 }
 
 // BuildComment iterates the map of all apps in this message, building a final comment from their current state
@@ -163,60 +273,127 @@ func (m *Message) BuildComment(
 	sb.WriteString(fmt.Sprintf("# Kubechecks %s Report\n", identifier))
 
 	updateWritten := false
+	for _, appSetName := range getSortedKeys(m.appSets) {
+		result := m.appSets[appSetName]
+
+		sb.WriteString("<details>\n")
+		sb.WriteString("<summary>\n\n")
+		sb.WriteString(fmt.Sprintf("## ArgoCD ApplicationSet Changes: `%s` %s\n", appSetName, m.vcs.ToEmoji(result.State)))
+		sb.WriteString("</summary>\n\n")
+		sb.WriteString(fmt.Sprintf("%s\n\n%s", result.Summary, result.Details))
+		sb.WriteString("</details>")
+
+		updateWritten = true
+	}
+
+	// child apps of an app-of-apps are shown inside their parent, unless it is not shown itself
+	children := make(map[string][]string)
+	var roots []string
 	for _, appName := range names {
-		if m.isDeleted(appName) {
+		parent, ok := m.parents[appName]
+		if _, exists := m.apps[parent]; ok && exists && !m.isDeleted(parent) {
+			children[parent] = append(children[parent], appName)
 			continue
 		}
+		roots = append(roots, appName)
+	}
+
+	visited := make(map[string]struct{})
+	for _, appName := range roots {
+		if section := m.buildAppSection(appName, 0, children, visited); section != "" {
+			sb.WriteString(section)
+			updateWritten = true
+		}
+	}
 
-		var checkStrings []string
-		results := m.apps[appName]
+	if !updateWritten {
+		sb.WriteString("No changes")
+	}
 
-		appState := pkg.StateSuccess
-		noChangesDetected := false
+	footer := m.buildFooter(start, commitSHA, labelFilter, showDebugInfo, appsChecked, totalChecked)
+	sb.WriteString(fmt.Sprintf("\n\n%s", footer))
 
-		for _, check := range results.results {
-			if check.NoChangesDetected {
-				noChangesDetected = true
-				continue
-			}
+	return sb.String()
+}
 
-			if check.State == pkg.StateSkip {
-				continue
-			}
+// buildAppSection renders the results of an app, with the sections of its child apps nested inside.
+func (m *Message) buildAppSection(appName string, depth int, children map[string][]string, visited map[string]struct{}) string {
+	if _, ok := visited[appName]; ok {
+		return ""
+	}
+	visited[appName] = struct{}{}
 
-			var summary string
-			if check.State == pkg.StateNone {
-				summary = check.Summary
-			} else {
-				summary = fmt.Sprintf("%s %s %s", check.Summary, check.State.BareString(), m.vcs.ToEmoji(check.State))
-			}
+	if m.isDeleted(appName) {
+		return ""
+	}
 
-			msg := fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n</details>", summary, check.Details)
-			checkStrings = append(checkStrings, msg)
-			appState = pkg.WorstState(appState, check.State)
+	var childSections strings.Builder
+	for _, child := range children[appName] {
+		childSections.WriteString(m.buildAppSection(child, depth+1, children, visited))
+	}
+
+	var checkStrings []string
+	results := m.apps[appName]
+
+	appState := pkg.StateSuccess
+	noChangesDetected := false
+
+	for _, check := range results.results {
+		if check.NoChangesDetected {
+			noChangesDetected = true
+			continue
 		}
 
-		if noChangesDetected {
+		if check.State == pkg.StateSkip {
 			continue
 		}
 
-		sb.WriteString("<details>\n")
-		sb.WriteString("<summary>\n\n")
-		sb.WriteString(fmt.Sprintf("## ArgoCD Application Checks: `%s` %s\n", appName, m.vcs.ToEmoji(appState)))
-		sb.WriteString("</summary>\n\n")
-		sb.WriteString(strings.Join(checkStrings, "\n\n---\n\n"))
-		sb.WriteString("</details>")
+		var summary string
+		if check.State == pkg.StateNone {
+			summary = check.Summary
+		} else {
+			summary = fmt.Sprintf("%s %s %s", check.Summary, check.State.BareString(), m.vcs.ToEmoji(check.State))
+		}
 
-		updateWritten = true
+		msg := fmt.Sprintf("<details>\n<summary>%s</summary>\n\n%s\n</details>", summary, check.Details)
+		checkStrings = append(checkStrings, msg)
+		appState = pkg.WorstState(appState, check.State)
 	}
 
-	if !updateWritten {
-		sb.WriteString("No changes")
+	if noChangesDetected {
+		// the app itself is not shown, but the changes to its children still are
+		return childSections.String()
 	}
 
-	footer := m.buildFooter(start, commitSHA, labelFilter, showDebugInfo, appsChecked, totalChecked)
-	sb.WriteString(fmt.Sprintf("\n\n%s", footer))
+	var sb strings.Builder
+	sb.WriteString("<details>\n")
+	sb.WriteString("<summary>\n\n")
+	if depth == 0 {
+		sb.WriteString(fmt.Sprintf("## ArgoCD Application Checks: `%s` %s\n", appName, m.vcs.ToEmoji(appState)))
+	} else {
+		sb.WriteString(fmt.Sprintf("### Child Application Checks: `%s` %s\n", appName, m.vcs.ToEmoji(appState)))
+	}
+	sb.WriteString("</summary>\n\n")
+	if reasons := m.reasons[appName]; len(reasons) > 0 {
+		sb.WriteString(buildReasons(reasons))
+	}
+	sb.WriteString(strings.Join(checkStrings, "\n\n---\n\n"))
+	if childSections.Len() > 0 {
+		sb.WriteString("\n\n")
+		sb.WriteString(childSections.String())
+	}
+	sb.WriteString("</details>")
+
+	return sb.String()
+}
 
+func buildReasons(reasons []string) string {
+	var sb strings.Builder
+	sb.WriteString("<details>\n<summary>Why was this checked?</summary>\n\n")
+	for _, reason := range reasons {
+		sb.WriteString(fmt.Sprintf("- %s\n", reason))
+	}
+	sb.WriteString("</details>\n\n")
 	return sb.String()
 }
 
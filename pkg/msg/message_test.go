@@ -49,6 +49,91 @@ should add some important details here
 `, comment)
 }
 
+func TestBuildComment_RunURL(t *testing.T) {
+	m := NewMessage("message", 1, 2, fakeEmojiable{":test:"})
+	m.RunURL = "https://checker.example.com/dashboard/runs/7"
+	comment := m.BuildComment(context.TODO(), time.Now(), "commit-sha", "", false, "test-identifier", 0, 0)
+	assert.True(t, strings.HasSuffix(comment, "<small> _Done. CommitSHA: commit-sha_ [Run details](https://checker.example.com/dashboard/runs/7) <small>\n"), comment)
+}
+
+func TestBuildComment_Reasons(t *testing.T) {
+	m := NewMessage("message", 1, 2, fakeEmojiable{":test:"})
+	m.apps = map[string]*AppResults{
+		"myapp": {
+			results: []Result{{State: pkg.StateSuccess, Summary: "all good", Details: "details"}},
+		},
+	}
+	m.AddReasons("myapp", "file `a.yaml` is a helm value file of the app")
+	m.AddReasons("myapp", "file `a.yaml` is a helm value file of the app", "the app is generated by appset `set`")
+
+	comment := m.BuildComment(context.TODO(), time.Now(), "commit-sha", "label-filter", false, "test-identifier", 1, 1)
+	assert.Contains(t, comment, `</summary>
+
+<details>
+<summary>Why was this checked?</summary>
+
+- file `+"`a.yaml`"+` is a helm value file of the app
+- the app is generated by appset `+"`set`"+`
+</details>
+
+<details>
+<summary>all good Passed :test:</summary>`)
+}
+
+func TestBuildComment_AppSetPreview(t *testing.T) {
+	m := NewMessage("message", 1, 2, fakeEmojiable{":test:"})
+	m.AddAppSetPreview("my-appset", Result{State: pkg.StateWarning, Summary: "1 to create, 1 to delete, 0 to update", Details: "details"})
+
+	comment := m.BuildComment(context.TODO(), time.Now(), "commit-sha", "label-filter", false, "test-identifier", 0, 0)
+	assert.Equal(t, `# Kubechecks test-identifier Report
+<details>
+<summary>
+
+## ArgoCD ApplicationSet Changes: `+"`my-appset`"+` :test:
+</summary>
+
+1 to create, 1 to delete, 0 to update
+
+details</details>
+
+<small> _Done. CommitSHA: commit-sha_ <small>
+`, comment)
+	assert.Equal(t, pkg.StateWarning, m.WorstState())
+}
+
+func TestBuildComment_ChildApps(t *testing.T) {
+	m := NewMessage("message", 1, 2, fakeEmojiable{":test:"})
+	m.apps = map[string]*AppResults{
+		"root":    {results: []Result{{State: pkg.StateSuccess, Summary: "root diff", Details: "root details"}}},
+		"child":   {results: []Result{{State: pkg.StateSuccess, Summary: "child diff", Details: "child details"}}},
+		"other":   {results: []Result{{State: pkg.StateSuccess, NoChangesDetected: true}}},
+		"orphan":  {results: []Result{{State: pkg.StateSuccess, Summary: "orphan diff", Details: "orphan details"}}},
+		"promote": {results: []Result{{State: pkg.StateSuccess, Summary: "promoted diff", Details: "promoted details"}}},
+	}
+	m.SetParent("child", "root")
+	m.SetParent("child", "other")
+	m.SetParent("orphan", "missing")
+	m.SetParent("promote", "other")
+
+	parent, ok := m.Parent("child")
+	assert.True(t, ok)
+	assert.Equal(t, "root", parent, "the first parent is kept")
+
+	comment := m.BuildComment(context.TODO(), time.Now(), "commit-sha", "label-filter", false, "test-identifier", 1, 1)
+	assert.Contains(t, comment, `root details
+</details>
+
+<details>
+<summary>
+
+### Child Application Checks: `+"`child`"+` :test:
+</summary>`, "children are nested in their parent")
+	assert.Contains(t, comment, "## ArgoCD Application Checks: `orphan`", "apps whose parent is not shown are top level")
+	assert.Contains(t, comment, "### Child Application Checks: `promote`", "children of unchanged apps are still shown")
+	assert.NotContains(t, comment, "`other`")
+	assert.Equal(t, map[string]string{"child": "root", "orphan": "missing", "promote": "other"}, m.Parents())
+}
+
 func TestBuildComment_SkipUnchanged(t *testing.T) {
 	appResults := map[string]*AppResults{
 		"myapp": {
@@ -2,7 +2,10 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,6 +34,17 @@ type CheckRequest struct {
 	Processors      []checks.ProcessorEntry
 	AIReviewChecker AIReviewChecker
 	Timestamp       time.Time
+
+	seq      uint64 // orders the requests of a repo, so that only the newest one of each PR is processed
+	storeID  uint64 // ID of the persisted request, 0 when it isn't persisted
+	priority Priority
+}
+
+// inFlightRequest is the request a worker is processing, and the way to cancel it.
+type inFlightRequest struct {
+	checkID int
+	sha     string
+	cancel  context.CancelCauseFunc
 }
 
 // RepoQueue manages a queue of check requests for a single repository
@@ -43,6 +57,15 @@ type RepoQueue struct {
 	queuedAt    time.Time
 	processed   int
 	processFunc ProcessFunc
+	store       Store
+	scheduler   *Scheduler
+
+	pending  []*CheckRequest // requests in the channel, oldest first
+	current  *CheckRequest   // request the worker took from the channel
+	waiting  bool            // the current request waits for a scheduler slot
+	seq      uint64
+	newest   map[int]uint64 // key: CheckID, value: seq of the newest request of the PR
+	inFlight *inFlightRequest
 }
 
 // QueueManager manages all repository queues
@@ -51,11 +74,24 @@ type QueueManager struct {
 	mu          sync.RWMutex
 	queueSize   int
 	processFunc ProcessFunc
+	store       Store
+	scheduler   *Scheduler
+
+	repoPriorities  map[string]string
+	labelPriorities []string
 }
 
 // Config holds queue manager configuration
 type Config struct {
 	QueueSize int
+	// Store persists requests until they are processed. Requests are only kept in memory when it is nil.
+	Store Store
+	// MaxConcurrent is the number of requests processed at once across every repository, there is no limit when 0.
+	MaxConcurrent int
+	// RepoPriorities holds the priority of the checks of each repository, keyed by its full name.
+	RepoPriorities map[string]string
+	// LabelPriorities are the priorities that PR labels may set, see PriorityOf.
+	LabelPriorities []string
 }
 
 // NewQueueManager creates a new queue manager
@@ -64,10 +100,19 @@ func NewQueueManager(cfg Config, processFunc ProcessFunc) *QueueManager {
 		cfg.QueueSize = 100 // Default buffer size
 	}
 
+	var scheduler *Scheduler
+	if cfg.MaxConcurrent > 0 {
+		scheduler = NewScheduler(cfg.MaxConcurrent)
+	}
+
 	return &QueueManager{
-		queues:      make(map[string]*RepoQueue),
-		queueSize:   cfg.QueueSize,
-		processFunc: processFunc,
+		queues:          make(map[string]*RepoQueue),
+		queueSize:       cfg.QueueSize,
+		processFunc:     processFunc,
+		store:           cfg.Store,
+		scheduler:       scheduler,
+		repoPriorities:  cfg.RepoPriorities,
+		labelPriorities: cfg.LabelPriorities,
 	}
 }
 
@@ -82,12 +127,91 @@ type EnqueueParams struct {
 // Enqueue adds a check request to the appropriate repository queue
 // Returns error if queue is full (non-blocking)
 func (qm *QueueManager) Enqueue(ctx context.Context, params EnqueueParams) error {
+	request := &CheckRequest{
+		PullRequest:     params.PullRequest,
+		Container:       params.Container,
+		Processors:      params.Processors,
+		AIReviewChecker: params.AIReviewChecker,
+		Timestamp:       time.Now(),
+	}
+
+	if qm.store != nil {
+		id, err := qm.store.Add(StoredRequest{PullRequest: request.PullRequest, Timestamp: request.Timestamp})
+		if err != nil {
+			// the request is still processed, it just won't survive a restart
+			log.Error().
+				Err(err).
+				Str("repo", params.PullRequest.CloneURL).
+				Int("check_id", params.PullRequest.CheckID).
+				Msg("failed to persist check request")
+		}
+		request.storeID = id
+	}
+
+	if err := qm.enqueue(request); err != nil {
+		forget(qm.store, request)
+		return err
+	}
+
+	return nil
+}
+
+// ResumeParams contains what is needed to process the requests that were persisted before a restart
+type ResumeParams struct {
+	Container       container.Container
+	Processors      []checks.ProcessorEntry
+	AIReviewChecker AIReviewChecker
+}
+
+// Resume enqueues the requests that were pending or in-flight when kubechecks last stopped, oldest first.
+func (qm *QueueManager) Resume(ctx context.Context, params ResumeParams) error {
+	if qm.store == nil {
+		return nil
+	}
+
+	stored, err := qm.store.List()
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("count", len(stored)).Msg("resuming persisted check requests")
+
+	for _, item := range stored {
+		pr := item.PullRequest
+		pr.Config = params.Container.Config
+
+		request := &CheckRequest{
+			PullRequest:     pr,
+			Container:       params.Container,
+			Processors:      params.Processors,
+			AIReviewChecker: params.AIReviewChecker,
+			Timestamp:       item.Timestamp,
+			storeID:         item.ID,
+		}
+
+		if err = qm.enqueue(request); err != nil {
+			log.Warn().
+				Err(err).
+				Str("repo", pr.CloneURL).
+				Int("check_id", pr.CheckID).
+				Msg("failed to resume check request, dropping it")
+			forget(qm.store, request)
+			continue
+		}
+		repoWorkerRequestsResumed.Inc()
+	}
+
+	return nil
+}
+
+// enqueue adds a request to the queue of its repository, creating the queue if needed.
+func (qm *QueueManager) enqueue(request *CheckRequest) error {
 	// extract the url from pullrequest, use it as the key
-	repoURL, err := pkg.Canonicalize(params.PullRequest.CloneURL)
+	repoKey, err := repoKeyOf(request.PullRequest.CloneURL)
 	if err != nil {
-		return fmt.Errorf("failed to canonicalize repo URL: %w", err)
+		return err
 	}
-	repoKey := fmt.Sprintf("%s/%s", repoURL.Host, repoURL.Path)
+	request.priority = PriorityOf(request.PullRequest, qm.repoPriorities, qm.labelPriorities)
 
 	// Get or create queue for this repo using double-checked locking for better concurrency
 	// Fast path: read lock to check if queue exists
@@ -102,11 +226,14 @@ func (qm *QueueManager) Enqueue(ctx context.Context, params EnqueueParams) error
 		queue, exists = qm.queues[repoKey]
 		if !exists {
 			queue = &RepoQueue{
-				repoURL:     params.PullRequest.CloneURL,
+				repoURL:     request.PullRequest.CloneURL,
 				queue:       make(chan *CheckRequest, qm.queueSize),
 				done:        make(chan struct{}),
 				queuedAt:    time.Now(),
 				processFunc: qm.processFunc,
+				store:       qm.store,
+				scheduler:   qm.scheduler,
+				newest:      make(map[int]uint64),
 			}
 			qm.queues[repoKey] = queue
 
@@ -115,45 +242,145 @@ func (qm *QueueManager) Enqueue(ctx context.Context, params EnqueueParams) error
 			go queue.startWorker()
 
 			log.Info().
-				Str("repo", params.PullRequest.CloneURL).
+				Str("repo", request.PullRequest.CloneURL).
 				Int("queue_size", qm.queueSize).
 				Msg("created new queue and started worker")
 		}
 		qm.mu.Unlock()
 	}
 
-	// Create check request
-	request := &CheckRequest{
-		PullRequest:     params.PullRequest,
-		Container:       params.Container,
-		Processors:      params.Processors,
-		AIReviewChecker: params.AIReviewChecker,
-		Timestamp:       time.Now(),
-	}
-
-	// Try to enqueue (non-blocking)
+	// Try to enqueue (non-blocking). The lock makes sure the worker only looks at the request once it is recorded
+	// as the newest of its PR.
+	queue.mu.Lock()
 	select {
 	case queue.queue <- request:
+		queue.pending = append(queue.pending, request)
+		queue.seq++
+		request.seq = queue.seq
+		queue.newest[request.PullRequest.CheckID] = request.seq
+		queue.supersedeInFlight(request.PullRequest)
+		queue.mu.Unlock()
+
 		repoWorkerRequestsTotal.Inc()
 		repoWorkerQueueSize.WithLabelValues(repoKey).Set(float64(len(queue.queue)))
 		qm.updateTotalQueueMetrics()
 		log.Info().
-			Str("repo", params.PullRequest.CloneURL).
-			Int("check_id", params.PullRequest.CheckID).
+			Str("repo", request.PullRequest.CloneURL).
+			Int("check_id", request.PullRequest.CheckID).
 			Int("queue_length", len(queue.queue)).
 			Msg("enqueued PR check request")
 		return nil
 	default:
+		queue.mu.Unlock()
+
 		// Queue is full, return error immediately without blocking
 		log.Warn().
-			Str("repo", params.PullRequest.CloneURL).
-			Int("check_id", params.PullRequest.CheckID).
+			Str("repo", request.PullRequest.CloneURL).
+			Int("check_id", request.PullRequest.CheckID).
 			Int("queue_size", qm.queueSize).
 			Msg("queue full, rejecting request")
-		return fmt.Errorf("queue full for repo %s (queue size: %d)", params.PullRequest.CloneURL, qm.queueSize)
+		return fmt.Errorf("queue full for repo %s (queue size: %d)", request.PullRequest.CloneURL, qm.queueSize)
+	}
+}
+
+// forget removes a request from the store, once it no longer needs to survive a restart.
+func forget(store Store, request *CheckRequest) {
+	if store == nil || request.storeID == 0 {
+		return
+	}
+
+	if err := store.Remove(request.storeID); err != nil {
+		log.Error().
+			Err(err).
+			Str("repo", request.PullRequest.CloneURL).
+			Int("check_id", request.PullRequest.CheckID).
+			Msg("failed to remove check request from the store")
+	}
+}
+
+// supersedeInFlight cancels the request being processed when it checks an older commit of the same PR.
+// The caller must hold rq.mu.
+func (rq *RepoQueue) supersedeInFlight(pr vcs.PullRequest) {
+	if rq.inFlight == nil || rq.inFlight.checkID != pr.CheckID || rq.inFlight.sha == pr.SHA {
+		return
+	}
+
+	log.Info().
+		Str("repo", pr.CloneURL).
+		Int("check_id", pr.CheckID).
+		Str("sha", rq.inFlight.sha).
+		Str("superseded_by", pr.SHA).
+		Msg("cancelling check of an outdated commit")
+
+	rq.inFlight.cancel(pkg.SupersededError{SHA: pr.SHA})
+	rq.inFlight = nil
+	repoWorkerRequestsCancelled.Inc()
+}
+
+// isSuperseded is true when a newer request for the same PR has been enqueued since this one.
+func (rq *RepoQueue) isSuperseded(request *CheckRequest) bool {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	return rq.newest[request.PullRequest.CheckID] != request.seq
+}
+
+// take records that the worker took a request from the channel.
+func (rq *RepoQueue) take(request *CheckRequest) {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	if i := slices.Index(rq.pending, request); i >= 0 {
+		rq.pending = slices.Delete(rq.pending, i, i+1)
+	}
+	rq.current = request
+}
+
+// acquire waits until the scheduler lets the request be processed. It returns false if the queue shuts down first.
+func (rq *RepoQueue) acquire(request *CheckRequest) bool {
+	if rq.scheduler == nil {
+		return true
+	}
+
+	rq.mu.Lock()
+	rq.waiting = true
+	rq.mu.Unlock()
+
+	acquired := rq.scheduler.Acquire(rq.repoURL, request, request.priority, rq.done)
+
+	rq.mu.Lock()
+	rq.waiting = false
+	rq.mu.Unlock()
+
+	return acquired
+}
+
+// release frees the scheduler slot of a request once the worker is done with it.
+func (rq *RepoQueue) release(request *CheckRequest) {
+	if rq.scheduler != nil {
+		rq.scheduler.Release(request)
+	}
+
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+
+	rq.current = nil
+	if rq.newest[request.PullRequest.CheckID] == request.seq {
+		delete(rq.newest, request.PullRequest.CheckID)
 	}
 }
 
+// skip drops a request that a newer one for the same PR supersedes.
+func (rq *RepoQueue) skip(request *CheckRequest) {
+	repoWorkerRequestsSuperseded.Inc()
+	log.Info().
+		Str("repo", rq.repoURL).
+		Int("check_id", request.PullRequest.CheckID).
+		Str("sha", request.PullRequest.SHA).
+		Msg("skipping request, a newer one for the same PR is queued")
+	forget(rq.store, request)
+}
+
 // startWorker processes check requests sequentially for this repository
 func (rq *RepoQueue) startWorker() {
 	defer rq.wg.Done()
@@ -165,35 +392,73 @@ func (rq *RepoQueue) startWorker() {
 	for {
 		select {
 		case request := <-rq.queue:
-			rq.processRequest(request)
-		case <-rq.done:
-			// Drain remaining items and notify affected PRs
-			remaining := len(rq.queue)
-			if remaining > 0 {
-				log.Warn().
-					Str("repo", rq.repoURL).
-					Int("dropped_count", remaining).
-					Msg("shutdown initiated, draining queue and notifying PRs")
-
-				rq.notifyDroppedRequests()
+			rq.take(request)
+			if rq.isSuperseded(request) {
+				rq.skip(request)
+				rq.release(request)
+				continue
 			}
 
-			log.Info().
-				Str("repo", rq.repoURL).
-				Int("processed", rq.processed).
-				Msg("worker shutting down")
+			if !rq.acquire(request) {
+				rq.shutdown(request)
+				return
+			}
+
+			// a newer commit may have been pushed while the request waited for a slot
+			if rq.isSuperseded(request) {
+				rq.skip(request)
+			} else {
+				rq.processRequest(request)
+				forget(rq.store, request)
+			}
+			rq.release(request)
+		case <-rq.done:
+			rq.shutdown(nil)
 			return
 		}
 	}
 }
 
-// notifyDroppedRequests notifies PRs about dropped items during shutdown
-func (rq *RepoQueue) notifyDroppedRequests() {
+// shutdown stops the worker. waiting is the request that was waiting for a scheduler slot, if any.
+func (rq *RepoQueue) shutdown(waiting *CheckRequest) {
+	// Persisted items are resumed after the restart, the others are drained and the affected PRs notified
+	remaining := len(rq.queue)
+	if waiting != nil {
+		remaining++
+	}
+
+	if remaining > 0 && rq.store != nil {
+		log.Info().
+			Str("repo", rq.repoURL).
+			Int("pending_count", remaining).
+			Msg("shutdown initiated, pending requests will be resumed after the restart")
+	} else if remaining > 0 {
+		log.Warn().
+			Str("repo", rq.repoURL).
+			Int("dropped_count", remaining).
+			Msg("shutdown initiated, draining queue and notifying PRs")
+
+		rq.notifyDroppedRequests(waiting)
+	}
+
+	log.Info().
+		Str("repo", rq.repoURL).
+		Int("processed", rq.processed).
+		Msg("worker shutting down")
+}
+
+// notifyDroppedRequests notifies PRs about dropped items during shutdown, including the one that was waiting for a
+// scheduler slot, if any.
+func (rq *RepoQueue) notifyDroppedRequests(waiting *CheckRequest) {
 	// Deduplicate by PR to avoid rate limiting (multiple items for same PR = 1 comment)
 	prMap := make(map[int]*CheckRequest) // key: CheckID
 
 	// Drain queue and deduplicate
 	drained := 0
+	if waiting != nil {
+		prMap[waiting.PullRequest.CheckID] = waiting
+		drained++
+	}
 	for {
 		select {
 		case request := <-rq.queue:
@@ -260,13 +525,47 @@ func (rq *RepoQueue) processRequest(request *CheckRequest) {
 		Dur("queued_for", time.Since(request.Timestamp)).
 		Msg("worker processing request")
 
+	ctx, cancel := context.WithCancelCause(context.Background())
+	rq.mu.Lock()
+	rq.inFlight = &inFlightRequest{
+		checkID: request.PullRequest.CheckID,
+		sha:     request.PullRequest.SHA,
+		cancel:  cancel,
+	}
+	rq.mu.Unlock()
+
+	defer func() {
+		rq.mu.Lock()
+		rq.inFlight = nil
+		rq.mu.Unlock()
+		cancel(nil)
+	}()
+
 	err := rq.processFunc(
-		context.Background(),
+		ctx,
 		request.PullRequest,
 		request.Container,
 		request.Processors,
 		request.AIReviewChecker,
 	)
+	var superseded pkg.SupersededError
+	if errors.As(context.Cause(ctx), &superseded) {
+		log.Info().
+			Str("repo", request.PullRequest.CloneURL).
+			Int("check_id", request.PullRequest.CheckID).
+			Str("superseded_by", superseded.SHA).
+			Dur("duration", time.Since(start)).
+			Msg("worker cancelled request, a newer commit was pushed")
+		return
+	}
+	if errors.As(context.Cause(ctx), &pkg.CancelledError{}) {
+		log.Info().
+			Str("repo", request.PullRequest.CloneURL).
+			Int("check_id", request.PullRequest.CheckID).
+			Dur("duration", time.Since(start)).
+			Msg("worker cancelled request, cancellation was requested")
+		return
+	}
 	if err != nil {
 		repoWorkerRequestsFailed.Inc()
 		log.Error().
@@ -319,13 +618,59 @@ func (qm *QueueManager) Shutdown(ctx context.Context) error {
 	select {
 	case <-done:
 		log.Info().Msg("all queue workers shutdown successfully")
-		return nil
+		return qm.closeStore()
 	case <-ctx.Done():
+		// in-flight requests are left in the store, and resumed after the restart
 		log.Warn().Msg("queue shutdown timed out")
+		_ = qm.closeStore()
 		return ctx.Err()
 	}
 }
 
+func (qm *QueueManager) closeStore() error {
+	if qm.store == nil {
+		return nil
+	}
+	return qm.store.Close()
+}
+
+// Cancel drops the queued requests of a pull request, and cancels the one being processed.
+func (qm *QueueManager) Cancel(_ context.Context, pr vcs.PullRequest) (bool, error) {
+	repoKey, err := repoKeyOf(pr.CloneURL)
+	if err != nil {
+		return false, err
+	}
+
+	qm.mu.RLock()
+	queue, exists := qm.queues[repoKey]
+	qm.mu.RUnlock()
+	if !exists {
+		return false, nil
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	// without a newest request, the queued requests of the PR are all skipped as superseded
+	_, found := queue.newest[pr.CheckID]
+	delete(queue.newest, pr.CheckID)
+
+	if queue.inFlight != nil && queue.inFlight.checkID == pr.CheckID {
+		log.Info().
+			Str("repo", pr.CloneURL).
+			Int("check_id", pr.CheckID).
+			Str("sha", queue.inFlight.sha).
+			Msg("cancelling check on request")
+
+		queue.inFlight.cancel(pkg.CancelledError{})
+		queue.inFlight = nil
+		repoWorkerRequestsCancelled.Inc()
+		found = true
+	}
+
+	return found, nil
+}
+
 // GetStats returns statistics about all queues
 func (qm *QueueManager) GetStats() map[string]interface{} {
 	qm.mu.RLock()
@@ -352,6 +697,75 @@ func (qm *QueueManager) GetStats() map[string]interface{} {
 	return stats
 }
 
+// Order returns every request in the order it is processed: the running ones, then the ones waiting for a scheduler
+// slot in the order they will get one, then the ones still queued behind the worker of their repository.
+func (qm *QueueManager) Order() []QueuedRequest {
+	rank := make(map[*CheckRequest]int)
+	if qm.scheduler != nil {
+		_, waiting := qm.scheduler.snapshot()
+		for i, t := range waiting {
+			rank[t.request] = i
+		}
+	}
+
+	var running, waiting, queued []*CheckRequest
+	qm.mu.RLock()
+	for _, queue := range qm.queues {
+		queue.mu.Lock()
+		if queue.current != nil && queue.waiting {
+			waiting = append(waiting, queue.current)
+		} else if queue.current != nil {
+			running = append(running, queue.current)
+		}
+		queued = append(queued, queue.pending...)
+		queue.mu.Unlock()
+	}
+	qm.mu.RUnlock()
+
+	byTimestamp := func(requests []*CheckRequest) {
+		sort.SliceStable(requests, func(i, j int) bool {
+			return requests[i].Timestamp.Before(requests[j].Timestamp)
+		})
+	}
+	byTimestamp(running)
+	byTimestamp(queued)
+	sort.SliceStable(waiting, func(i, j int) bool {
+		// a request granted since the snapshot has no rank, it goes last
+		ri, ok := rank[waiting[i]]
+		if !ok {
+			ri = len(rank)
+		}
+		rj, ok := rank[waiting[j]]
+		if !ok {
+			rj = len(rank)
+		}
+		return ri < rj
+	})
+
+	order := make([]QueuedRequest, 0, len(running)+len(waiting)+len(queued))
+	for _, group := range []struct {
+		state    string
+		requests []*CheckRequest
+	}{
+		{RequestRunning, running},
+		{RequestWaiting, waiting},
+		{RequestQueued, queued},
+	} {
+		for _, request := range group.requests {
+			order = append(order, QueuedRequest{
+				Repo:     request.PullRequest.FullName,
+				CheckID:  request.PullRequest.CheckID,
+				SHA:      request.PullRequest.SHA,
+				Priority: request.priority.String(),
+				State:    group.state,
+				QueuedAt: request.Timestamp,
+			})
+		}
+	}
+
+	return order
+}
+
 // updateTotalQueueMetrics updates aggregate queue metrics
 func (qm *QueueManager) updateTotalQueueMetrics() {
 	qm.mu.Lock()
@@ -0,0 +1,211 @@
+package queue
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/checks"
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func TestQueueManager_Supersede(t *testing.T) {
+	started := make(chan string)
+	release := make(chan struct{})
+
+	var mu sync.Mutex
+	var processed []string
+	var causes []error
+
+	qm := NewQueueManager(Config{QueueSize: 10}, func(ctx context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ AIReviewChecker) error {
+		started <- pr.SHA
+		if pr.SHA == "sha-1" {
+			<-ctx.Done()
+		} else {
+			<-release
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		processed = append(processed, pr.SHA)
+		causes = append(causes, context.Cause(ctx))
+		return nil
+	})
+
+	enqueue := func(checkID int, sha string) {
+		require.NoError(t, qm.Enqueue(context.Background(), EnqueueParams{
+			PullRequest: vcs.PullRequest{CloneURL: "https://github.com/zapier/kubechecks.git", CheckID: checkID, SHA: sha},
+		}))
+	}
+
+	enqueue(1, "sha-1")
+	assert.Equal(t, "sha-1", <-started)
+
+	// sha-2 and sha-3 are queued while sha-1 is running, only sha-3 is checked
+	enqueue(1, "sha-2")
+	enqueue(2, "other-pr")
+	enqueue(1, "sha-3")
+
+	assert.Equal(t, "other-pr", <-started)
+	release <- struct{}{}
+	assert.Equal(t, "sha-3", <-started)
+	release <- struct{}{}
+
+	require.NoError(t, qm.Shutdown(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"sha-1", "other-pr", "sha-3"}, processed)
+	assert.Equal(t, pkg.SupersededError{SHA: "sha-2"}, causes[0], "the running check is cancelled by the first newer push")
+	assert.Nil(t, causes[1])
+	assert.Nil(t, causes[2])
+}
+
+func TestQueueManager_Cancel(t *testing.T) {
+	started := make(chan string)
+	causes := make(chan error, 1)
+
+	qm := NewQueueManager(Config{QueueSize: 10}, func(ctx context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ AIReviewChecker) error {
+		started <- pr.SHA
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil
+	})
+
+	ctx := context.Background()
+	pr := vcs.PullRequest{CloneURL: "https://github.com/zapier/kubechecks.git", CheckID: 1, SHA: "sha-1"}
+	cancelled, err := qm.Cancel(ctx, pr)
+	require.NoError(t, err)
+	assert.False(t, cancelled, "nothing was queued")
+
+	require.NoError(t, qm.Enqueue(ctx, EnqueueParams{PullRequest: pr}))
+	assert.Equal(t, "sha-1", <-started)
+
+	// a re-run of the same commit is queued behind the running one, both are cancelled
+	require.NoError(t, qm.Enqueue(ctx, EnqueueParams{PullRequest: pr}))
+
+	cancelled, err = qm.Cancel(ctx, pr)
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.Equal(t, pkg.CancelledError{}, <-causes)
+
+	assert.Eventually(t, func() bool {
+		return len(qm.Order()) == 0
+	}, time.Second, 10*time.Millisecond, "the queued request is skipped")
+
+	require.NoError(t, qm.Shutdown(ctx))
+}
+
+func TestRepoQueue_SameSHADoesNotCancel(t *testing.T) {
+	cancelled := false
+	rq := &RepoQueue{newest: make(map[int]uint64)}
+	rq.inFlight = &inFlightRequest{checkID: 1, sha: "sha-1", cancel: func(error) { cancelled = true }}
+
+	rq.supersedeInFlight(vcs.PullRequest{CheckID: 1, SHA: "sha-1"})
+	assert.False(t, cancelled)
+	rq.supersedeInFlight(vcs.PullRequest{CheckID: 2, SHA: "sha-2"})
+	assert.False(t, cancelled)
+
+	rq.supersedeInFlight(vcs.PullRequest{CheckID: 1, SHA: "sha-2"})
+	assert.True(t, cancelled)
+	assert.Nil(t, rq.inFlight)
+}
+
+func TestQueueManager_Resume(t *testing.T) {
+	store, err := NewBoltStore(filepath.Join(t.TempDir(), "queue.db"))
+	require.NoError(t, err)
+
+	pr := vcs.PullRequest{CloneURL: "https://github.com/zapier/kubechecks.git", CheckID: 1, SHA: "sha-1"}
+	_, err = store.Add(StoredRequest{PullRequest: pr, Timestamp: time.Now()})
+	require.NoError(t, err)
+
+	processed := make(chan vcs.PullRequest, 1)
+	qm := NewQueueManager(Config{QueueSize: 10, Store: store}, func(_ context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ AIReviewChecker) error {
+		processed <- pr
+		return nil
+	})
+
+	cfg := config.ServerConfig{Identifier: "resumed"}
+	require.NoError(t, qm.Resume(context.Background(), ResumeParams{Container: container.Container{Config: cfg}}))
+
+	resumed := <-processed
+	assert.Equal(t, "sha-1", resumed.SHA)
+	assert.Equal(t, cfg, resumed.Config, "the config of the running instance is used")
+
+	// the request is removed once it has been processed
+	assert.Eventually(t, func() bool {
+		requests, err := store.List()
+		return err == nil && len(requests) == 0
+	}, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, qm.Shutdown(context.Background()))
+}
+
+func TestQueueManager_Order(t *testing.T) {
+	started := make(chan string)
+	release := make(chan struct{})
+
+	qm := NewQueueManager(Config{
+		QueueSize:       10,
+		MaxConcurrent:   1,
+		RepoPriorities:  map[string]string{"zapier/docs": "low"},
+		LabelPriorities: []string{"low", "normal", "high"},
+	}, func(ctx context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ AIReviewChecker) error {
+		started <- pr.SHA
+		<-release
+		return nil
+	})
+
+	enqueue := func(repo string, checkID int, sha string, labels ...string) {
+		require.NoError(t, qm.Enqueue(context.Background(), EnqueueParams{
+			PullRequest: vcs.PullRequest{
+				CloneURL: "https://github.com/" + repo + ".git",
+				FullName: repo,
+				CheckID:  checkID,
+				SHA:      sha,
+				Labels:   labels,
+			},
+		}))
+	}
+	states := func() []string {
+		var states []string
+		for _, request := range qm.Order() {
+			states = append(states, request.State+" "+request.SHA+" "+request.Priority)
+		}
+		return states
+	}
+
+	enqueue("zapier/kubechecks", 1, "sha-1")
+	assert.Equal(t, "sha-1", <-started)
+
+	enqueue("zapier/docs", 2, "sha-2")
+	enqueue("zapier/website", 3, "sha-3", "kubechecks:priority-high")
+	enqueue("zapier/kubechecks", 4, "sha-4")
+	require.Eventually(t, func() bool { return waitingCount(qm.scheduler) == 2 }, time.Second, time.Millisecond)
+
+	assert.Equal(t, []string{
+		"running sha-1 normal",
+		"waiting sha-3 high",
+		"waiting sha-2 low",
+		"queued sha-4 normal",
+	}, states())
+
+	release <- struct{}{}
+	assert.Equal(t, "sha-3", <-started, "the high priority request goes first")
+	release <- struct{}{}
+	assert.Equal(t, "sha-2", <-started)
+	release <- struct{}{}
+	assert.Equal(t, "sha-4", <-started)
+	release <- struct{}{}
+
+	require.NoError(t, qm.Shutdown(context.Background()))
+	assert.Empty(t, qm.Order())
+}
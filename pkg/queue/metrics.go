@@ -60,6 +60,63 @@ var (
 		},
 	)
 
+	repoWorkerRequestsSuperseded = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "kubechecks",
+			Subsystem: "queue",
+			Name:      "repo_worker_requests_superseded_total",
+			Help:      "Total number of queued requests skipped because a newer commit of the same PR was queued",
+		},
+	)
+
+	repoWorkerRequestsCancelled = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "kubechecks",
+			Subsystem: "queue",
+			Name:      "repo_worker_requests_cancelled_total",
+			Help:      "Total number of in-flight requests cancelled because a newer commit of the same PR was queued",
+		},
+	)
+
+	repoWorkerRequestsResumed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "kubechecks",
+			Subsystem: "queue",
+			Name:      "repo_worker_requests_resumed_total",
+			Help:      "Total number of persisted requests resumed after a restart",
+		},
+	)
+
+	// Scheduler metrics
+	schedulerRunning = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "kubechecks",
+			Subsystem: "queue",
+			Name:      "scheduler_running",
+			Help:      "Number of requests holding a scheduler slot",
+		},
+	)
+
+	schedulerWaiting = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "kubechecks",
+			Subsystem: "queue",
+			Name:      "scheduler_waiting",
+			Help:      "Number of requests waiting for a scheduler slot",
+		},
+	)
+
+	schedulerWaitDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "kubechecks",
+			Subsystem: "queue",
+			Name:      "scheduler_wait_duration_seconds",
+			Help:      "Time a request waited for a scheduler slot (seconds), by priority",
+			Buckets:   []float64{1, 5, 10, 30, 60, 120, 300, 600},
+		},
+		[]string{"priority"},
+	)
+
 	repoWorkerProcessingDuration = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: "kubechecks",
@@ -83,5 +140,13 @@ func init() {
 	r.MustRegister(repoWorkerRequestsTotal)
 	r.MustRegister(repoWorkerRequestsProcessed)
 	r.MustRegister(repoWorkerRequestsFailed)
+	r.MustRegister(repoWorkerRequestsSuperseded)
+	r.MustRegister(repoWorkerRequestsCancelled)
+	r.MustRegister(repoWorkerRequestsResumed)
 	r.MustRegister(repoWorkerProcessingDuration)
+
+	// Scheduler metrics
+	r.MustRegister(schedulerRunning)
+	r.MustRegister(schedulerWaiting)
+	r.MustRegister(schedulerWaitDuration)
 }
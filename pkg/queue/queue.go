@@ -0,0 +1,67 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// Queue holds check requests until they are processed in the background.
+type Queue interface {
+	// Enqueue adds a check request, and returns an error if the queue is full.
+	Enqueue(ctx context.Context, params EnqueueParams) error
+	// Resume starts processing, including the requests that were left behind by a previous run.
+	Resume(ctx context.Context, params ResumeParams) error
+	// Shutdown stops taking new requests and waits for the ones being processed.
+	Shutdown(ctx context.Context) error
+}
+
+// OrderedQueue is a queue that can list its requests in the order they are processed.
+type OrderedQueue interface {
+	Order() []QueuedRequest
+}
+
+// CancellableQueue is a queue whose requests can be cancelled.
+type CancellableQueue interface {
+	// Cancel drops the queued requests of a pull request, and cancels the one being processed with a
+	// pkg.CancelledError cause. It returns false if there were none.
+	Cancel(ctx context.Context, pr vcs.PullRequest) (bool, error)
+}
+
+// States of a request in the queue order.
+const (
+	RequestRunning = "running"
+	RequestWaiting = "waiting"
+	RequestQueued  = "queued"
+)
+
+// QueuedRequest is a request in the queue order.
+type QueuedRequest struct {
+	Repo     string    `json:"repo"`
+	CheckID  int       `json:"check_id"`
+	SHA      string    `json:"sha"`
+	Priority string    `json:"priority"`
+	State    string    `json:"state"`
+	QueuedAt time.Time `json:"queued_at"`
+}
+
+var (
+	_ Queue        = (*QueueManager)(nil)
+	_ Queue        = (*RedisQueue)(nil)
+	_ OrderedQueue = (*QueueManager)(nil)
+
+	_ CancellableQueue = (*QueueManager)(nil)
+	_ CancellableQueue = (*RedisQueue)(nil)
+)
+
+// repoKeyOf identifies a repository, whichever URL it is cloned from.
+func repoKeyOf(cloneURL string) (string, error) {
+	repoURL, err := pkg.Canonicalize(cloneURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize repo URL: %w", err)
+	}
+	return fmt.Sprintf("%s/%s", repoURL.Host, repoURL.Path), nil
+}
@@ -0,0 +1,585 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+const (
+	redisGroup = "workers"
+
+	// redisLockRetry is how often a worker tries to lock a PR that another replica is processing.
+	redisLockRetry = time.Second
+	// redisNewestTTL is how long the newest request of a PR is remembered.
+	redisNewestTTL = 24 * time.Hour
+	// redisCancelledID replaces the newest request of a PR when its requests are cancelled, so that none of them is
+	// the newest one anymore.
+	redisCancelledID = "cancelled"
+)
+
+// redisBlock is how long a worker waits for a new request before checking for abandoned ones again.
+var redisBlock = 5 * time.Second
+
+var (
+	// enqueueScript adds a request to the stream, and records it as the newest request of its PR, in one step.
+	enqueueScript = redis.NewScript(`
+local id = redis.call('XADD', KEYS[1], '*', 'request', ARGV[1])
+redis.call('HSET', KEYS[2], 'id', id, 'sha', ARGV[2])
+redis.call('EXPIRE', KEYS[2], ARGV[3])
+return id
+`)
+
+	// renewScript extends a lock, if it is still held by the same worker.
+	renewScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+	// releaseScript deletes a lock, if it is still held by the same worker.
+	releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+)
+
+// RedisConfig holds the configuration of a queue that is shared by every replica.
+type RedisConfig struct {
+	Client redis.UniversalClient
+	// Prefix of every key, so that several kubechecks installations can share a server.
+	Prefix string
+	// Consumer identifies this replica, usually the name of its pod.
+	Consumer string
+	// Workers is the number of requests this replica processes at once.
+	Workers int
+	// QueueSize is the maximum number of requests waiting in the shared queue.
+	QueueSize int
+	// LockTTL is how long a replica that stopped keeps its PRs locked. Its requests are picked up by another
+	// replica after this long.
+	LockTTL time.Duration
+}
+
+// RedisQueue keeps check requests in a Redis stream, so that any replica can process a request that was received by
+// another one. A PR is locked while it is checked, so that two replicas never process the same PR at once.
+type RedisQueue struct {
+	client      redis.UniversalClient
+	cfg         RedisConfig
+	processFunc ProcessFunc
+
+	repoLocks sync.Map // key: repo key, value: *sync.Mutex, one request per repository at a time in each replica
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+// NewRedisQueue creates a queue backed by Redis. Nothing is processed until Resume is called.
+func NewRedisQueue(cfg RedisConfig, processFunc ProcessFunc) *RedisQueue {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "kubechecks"
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = 30 * time.Second
+	}
+
+	return &RedisQueue{
+		client:      cfg.Client,
+		cfg:         cfg,
+		processFunc: processFunc,
+	}
+}
+
+// lockRetry is short enough for a waiting request to be touched before other replicas consider it abandoned.
+func (q *RedisQueue) lockRetry() time.Duration {
+	return min(redisLockRetry, q.cfg.LockTTL/3)
+}
+
+func (q *RedisQueue) streamKey() string {
+	return q.cfg.Prefix + ":requests"
+}
+
+func (q *RedisQueue) newestKey(prKey string) string {
+	return q.cfg.Prefix + ":newest:" + prKey
+}
+
+func (q *RedisQueue) lockKey(prKey string) string {
+	return q.cfg.Prefix + ":lock:" + prKey
+}
+
+func pullRequestKey(pr vcs.PullRequest) (repoKey, prKey string, err error) {
+	repoKey, err = repoKeyOf(pr.CloneURL)
+	if err != nil {
+		return "", "", err
+	}
+	return repoKey, fmt.Sprintf("%s#%d", repoKey, pr.CheckID), nil
+}
+
+// Enqueue adds a check request to the shared queue. Only the pull request is shared, the replica that processes
+// it uses its own container, processors and AI review checker.
+func (q *RedisQueue) Enqueue(ctx context.Context, params EnqueueParams) error {
+	_, prKey, err := pullRequestKey(params.PullRequest)
+	if err != nil {
+		return err
+	}
+
+	length, err := q.client.XLen(ctx, q.streamKey()).Result()
+	if err != nil {
+		return errors.Wrap(err, "failed to get queue length")
+	}
+	if length >= int64(q.cfg.QueueSize) {
+		log.Warn().
+			Str("repo", params.PullRequest.CloneURL).
+			Int("check_id", params.PullRequest.CheckID).
+			Int("queue_size", q.cfg.QueueSize).
+			Msg("queue full, rejecting request")
+		return fmt.Errorf("shared queue full (queue size: %d)", q.cfg.QueueSize)
+	}
+
+	// the config is rebuilt by the replica that processes the request, and holds secrets
+	pr := params.PullRequest
+	pr.Config = config.ServerConfig{}
+	data, err := json.Marshal(StoredRequest{PullRequest: pr, Timestamp: time.Now()})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode request")
+	}
+
+	if err = enqueueScript.Run(ctx, q.client,
+		[]string{q.streamKey(), q.newestKey(prKey)},
+		string(data), pr.SHA, int(redisNewestTTL.Seconds()),
+	).Err(); err != nil {
+		return errors.Wrap(err, "failed to enqueue request")
+	}
+
+	repoWorkerRequestsTotal.Inc()
+	repoWorkerQueueTotal.Set(float64(length + 1))
+	log.Info().
+		Str("repo", params.PullRequest.CloneURL).
+		Int("check_id", params.PullRequest.CheckID).
+		Int64("queue_length", length+1).
+		Msg("enqueued PR check request in shared queue")
+
+	return nil
+}
+
+// Resume starts the workers of this replica. Requests that a replica stopped processing without finishing are
+// picked up once their lock has expired.
+func (q *RedisQueue) Resume(ctx context.Context, params ResumeParams) error {
+	err := q.client.XGroupCreateMkStream(ctx, q.streamKey(), redisGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return errors.Wrap(err, "failed to create consumer group")
+	}
+
+	// the workers outlive the request that started them
+	loopCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	q.cancel = cancel
+
+	for num := 0; num < q.cfg.Workers; num++ {
+		q.wg.Add(1)
+		go q.runWorker(loopCtx, fmt.Sprintf("%s-%d", q.cfg.Consumer, num), params)
+	}
+
+	log.Info().
+		Str("consumer", q.cfg.Consumer).
+		Int("workers", q.cfg.Workers).
+		Msg("started shared queue workers")
+
+	return nil
+}
+
+func (q *RedisQueue) runWorker(ctx context.Context, consumer string, params ResumeParams) {
+	defer q.wg.Done()
+
+	for ctx.Err() == nil {
+		message, ok, err := q.next(ctx, consumer)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Error().Err(err).Str("consumer", consumer).Msg("failed to read from shared queue")
+			select {
+			case <-ctx.Done():
+			case <-time.After(redisLockRetry):
+			}
+			continue
+		}
+		if ok {
+			q.handle(ctx, consumer, message, params)
+		}
+	}
+}
+
+// next returns a request that was abandoned by a replica, or else waits for a new one.
+func (q *RedisQueue) next(ctx context.Context, consumer string) (redis.XMessage, bool, error) {
+	claimed, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.streamKey(),
+		Group:    redisGroup,
+		Consumer: consumer,
+		MinIdle:  q.cfg.LockTTL,
+		Start:    "0",
+		Count:    1,
+	}).Result()
+	if err != nil {
+		return redis.XMessage{}, false, errors.Wrap(err, "failed to claim abandoned requests")
+	}
+	if len(claimed) > 0 {
+		repoWorkerRequestsResumed.Inc()
+		return claimed[0], true, nil
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    redisGroup,
+		Consumer: consumer,
+		Streams:  []string{q.streamKey(), ">"},
+		Count:    1,
+		Block:    redisBlock,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return redis.XMessage{}, false, nil
+	}
+	if err != nil {
+		return redis.XMessage{}, false, errors.Wrap(err, "failed to read new requests")
+	}
+	for _, stream := range streams {
+		for _, message := range stream.Messages {
+			return message, true, nil
+		}
+	}
+
+	return redis.XMessage{}, false, nil
+}
+
+// handle processes a single request, once it holds the lock of its PR.
+func (q *RedisQueue) handle(ctx context.Context, consumer string, message redis.XMessage, params ResumeParams) {
+	var stored StoredRequest
+	data, _ := message.Values["request"].(string)
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		log.Error().Err(err).Str("id", message.ID).Msg("dropping request that cannot be decoded")
+		q.ack(message.ID)
+		return
+	}
+
+	pr := stored.PullRequest
+	pr.Config = params.Container.Config
+
+	repoKey, prKey, err := pullRequestKey(pr)
+	if err != nil {
+		log.Error().Err(err).Str("repo", pr.CloneURL).Msg("dropping request for an invalid repo")
+		q.ack(message.ID)
+		return
+	}
+
+	repoLock, _ := q.repoLocks.LoadOrStore(repoKey, &sync.Mutex{})
+	token := consumer + "/" + message.ID
+
+	for {
+		if newest, _ := q.newest(ctx, prKey); newest.id != "" && newest.id != message.ID {
+			repoWorkerRequestsSuperseded.Inc()
+			log.Info().
+				Str("repo", pr.CloneURL).
+				Int("check_id", pr.CheckID).
+				Str("sha", pr.SHA).
+				Msg("skipping request, a newer one for the same PR is queued")
+			q.ack(message.ID)
+			return
+		}
+
+		if repoLock.(*sync.Mutex).TryLock() {
+			locked, err := q.client.SetNX(ctx, q.lockKey(prKey), token, q.cfg.LockTTL).Result()
+			if err == nil && locked {
+				break
+			}
+			repoLock.(*sync.Mutex).Unlock()
+		}
+
+		// another worker is checking the repo or the PR, keep the request claimed while waiting for it
+		q.touch(ctx, consumer, message.ID)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(q.lockRetry()):
+		}
+	}
+	defer repoLock.(*sync.Mutex).Unlock()
+
+	cleanupCtx := context.WithoutCancel(ctx)
+	release := func() {
+		if err := releaseScript.Run(cleanupCtx, q.client, []string{q.lockKey(prKey)}, token).Err(); err != nil {
+			log.Error().Err(err).Str("repo", pr.CloneURL).Int("check_id", pr.CheckID).Msg("failed to release PR lock")
+		}
+	}
+
+	// another replica may have claimed the request while this one was waiting, only its owner processes it
+	if !q.owns(ctx, consumer, message.ID) {
+		log.Info().Str("id", message.ID).Str("consumer", consumer).Msg("request was claimed by another worker, skipping")
+		release()
+		return
+	}
+
+	start := time.Now()
+	timer := prometheus.NewTimer(repoWorkerProcessingDuration)
+	defer timer.ObserveDuration()
+
+	log.Info().
+		Str("repo", pr.CloneURL).
+		Int("check_id", pr.CheckID).
+		Str("consumer", consumer).
+		Dur("queued_for", time.Since(stored.Timestamp)).
+		Msg("worker processing request")
+
+	// like the in-memory queue, a shutdown lets the request finish
+	processCtx, cancel := context.WithCancelCause(context.Background())
+	stopHeartbeat := make(chan struct{})
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		q.heartbeat(processCtx, cancel, consumer, token, prKey, message.ID, pr, stopHeartbeat)
+	}()
+
+	err = q.processFunc(processCtx, pr, params.Container, params.Processors, params.AIReviewChecker)
+
+	close(stopHeartbeat)
+	<-heartbeatDone
+
+	var superseded pkg.SupersededError
+	switch {
+	case errors.As(context.Cause(processCtx), &superseded):
+		log.Info().
+			Str("repo", pr.CloneURL).
+			Int("check_id", pr.CheckID).
+			Str("superseded_by", superseded.SHA).
+			Dur("duration", time.Since(start)).
+			Msg("worker cancelled request, a newer commit was pushed")
+	case errors.As(context.Cause(processCtx), &pkg.LockLostError{}):
+		// the request stays pending, for whichever replica holds the lock now
+		log.Warn().
+			Str("repo", pr.CloneURL).
+			Int("check_id", pr.CheckID).
+			Dur("duration", time.Since(start)).
+			Msg("worker cancelled request, the PR lock expired")
+		cancel(nil)
+		return
+	case errors.As(context.Cause(processCtx), &pkg.CancelledError{}):
+		log.Info().
+			Str("repo", pr.CloneURL).
+			Int("check_id", pr.CheckID).
+			Dur("duration", time.Since(start)).
+			Msg("worker cancelled request, cancellation was requested")
+	case err != nil:
+		repoWorkerRequestsFailed.Inc()
+		log.Error().
+			Str("repo", pr.CloneURL).
+			Int("check_id", pr.CheckID).
+			Err(err).
+			Msg("worker failed to process request, continuing to next request")
+	default:
+		repoWorkerRequestsProcessed.Inc()
+		log.Info().
+			Str("repo", pr.CloneURL).
+			Int("check_id", pr.CheckID).
+			Dur("duration", time.Since(start)).
+			Msg("worker completed request")
+	}
+	cancel(nil)
+
+	release()
+	q.ack(message.ID)
+}
+
+// heartbeat keeps the lock and the claim of a request alive while it is processed, and cancels it when a newer
+// commit of the same PR is queued, or when the lock expired before it could be renewed.
+func (q *RedisQueue) heartbeat(
+	ctx context.Context, cancel context.CancelCauseFunc,
+	consumer, token, prKey, id string, pr vcs.PullRequest, stop <-chan struct{},
+) {
+	ticker := time.NewTicker(q.cfg.LockTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := renewScript.Run(ctx, q.client, []string{q.lockKey(prKey)}, token, q.cfg.LockTTL.Milliseconds()).Int()
+		if err != nil {
+			log.Warn().Err(err).Str("repo", pr.CloneURL).Int("check_id", pr.CheckID).Msg("failed to renew PR lock")
+		} else if renewed == 0 {
+			// another replica may be checking the PR already, two of them never process it at once
+			log.Warn().
+				Str("repo", pr.CloneURL).
+				Int("check_id", pr.CheckID).
+				Str("sha", pr.SHA).
+				Msg("cancelling check, the PR lock expired")
+			repoWorkerRequestsCancelled.Inc()
+			cancel(pkg.LockLostError{})
+			return
+		}
+		q.touch(ctx, consumer, id)
+
+		newest, err := q.newest(ctx, prKey)
+		if err == nil && newest.id == redisCancelledID {
+			log.Info().
+				Str("repo", pr.CloneURL).
+				Int("check_id", pr.CheckID).
+				Str("sha", pr.SHA).
+				Msg("cancelling check on request")
+			repoWorkerRequestsCancelled.Inc()
+			cancel(pkg.CancelledError{})
+			return
+		}
+		if err == nil && newest.id != "" && newest.id != id && newest.sha != pr.SHA {
+			log.Info().
+				Str("repo", pr.CloneURL).
+				Int("check_id", pr.CheckID).
+				Str("sha", pr.SHA).
+				Str("superseded_by", newest.sha).
+				Msg("cancelling check of an outdated commit")
+			repoWorkerRequestsCancelled.Inc()
+			cancel(pkg.SupersededError{SHA: newest.sha})
+			return
+		}
+	}
+}
+
+// Cancel drops the queued requests of a pull request, and has the replica that processes one of them cancel it the
+// next time it renews its lock.
+func (q *RedisQueue) Cancel(ctx context.Context, pr vcs.PullRequest) (bool, error) {
+	_, prKey, err := pullRequestKey(pr)
+	if err != nil {
+		return false, err
+	}
+
+	newest, err := q.newest(ctx, prKey)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get the newest request")
+	}
+	if newest.id == "" || newest.id == redisCancelledID {
+		return false, nil
+	}
+
+	// requests are deleted from the stream once they have been processed
+	pending, err := q.client.XRange(ctx, q.streamKey(), newest.id, newest.id).Result()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get the newest request")
+	}
+	if len(pending) == 0 {
+		return false, nil
+	}
+
+	if _, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, q.newestKey(prKey), "id", redisCancelledID, "sha", "")
+		pipe.Expire(ctx, q.newestKey(prKey), redisNewestTTL)
+		return nil
+	}); err != nil {
+		return false, errors.Wrap(err, "failed to cancel requests")
+	}
+
+	log.Info().
+		Str("repo", pr.CloneURL).
+		Int("check_id", pr.CheckID).
+		Msg("cancelled requests in shared queue")
+
+	return true, nil
+}
+
+type newestRequest struct {
+	id, sha string
+}
+
+func (q *RedisQueue) newest(ctx context.Context, prKey string) (newestRequest, error) {
+	values, err := q.client.HMGet(ctx, q.newestKey(prKey), "id", "sha").Result()
+	if err != nil {
+		return newestRequest{}, err
+	}
+
+	id, _ := values[0].(string)
+	sha, _ := values[1].(string)
+	return newestRequest{id: id, sha: sha}, nil
+}
+
+// owns is true when the request is still pending for this worker.
+func (q *RedisQueue) owns(ctx context.Context, consumer, id string) bool {
+	pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: q.streamKey(),
+		Group:  redisGroup,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil {
+		log.Warn().Err(err).Str("id", id).Msg("failed to check who owns the request")
+		return false
+	}
+
+	return len(pending) == 1 && pending[0].Consumer == consumer
+}
+
+// touch resets the idle time of a request, so that other replicas don't claim it.
+func (q *RedisQueue) touch(ctx context.Context, consumer, id string) {
+	if err := q.client.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream:   q.streamKey(),
+		Group:    redisGroup,
+		Consumer: consumer,
+		Messages: []string{id},
+	}).Err(); err != nil {
+		log.Warn().Err(err).Str("id", id).Msg("failed to keep request claimed")
+	}
+}
+
+// ack removes a request from the stream once it no longer needs to be processed.
+func (q *RedisQueue) ack(id string) {
+	ctx := context.Background()
+	if _, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.streamKey(), redisGroup, id)
+		pipe.XDel(ctx, q.streamKey(), id)
+		return nil
+	}); err != nil {
+		log.Error().Err(err).Str("id", id).Msg("failed to acknowledge request")
+	}
+}
+
+// Shutdown stops reading new requests and waits for the ones being processed. Requests that don't finish in time
+// are picked up by another replica.
+func (q *RedisQueue) Shutdown(ctx context.Context) error {
+	log.Info().Msg("shutting down shared queue workers")
+
+	if q.cancel != nil {
+		q.cancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info().Msg("all shared queue workers shutdown successfully")
+		return nil
+	case <-ctx.Done():
+		log.Warn().Msg("shared queue shutdown timed out")
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,232 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/checks"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func newTestRedisQueues(t *testing.T, processFunc ProcessFunc, consumers ...string) []*RedisQueue {
+	t.Helper()
+
+	redisBlock = 50 * time.Millisecond
+	server := miniredis.RunT(t)
+
+	var queues []*RedisQueue
+	for _, consumer := range consumers {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { _ = client.Close() })
+
+		q := NewRedisQueue(RedisConfig{Client: client, Consumer: consumer, Workers: 2, LockTTL: 300 * time.Millisecond}, processFunc)
+		queues = append(queues, q)
+	}
+
+	return queues
+}
+
+func testPullRequest(checkID int, sha string) vcs.PullRequest {
+	return vcs.PullRequest{CloneURL: "https://github.com/zapier/kubechecks.git", CheckID: checkID, SHA: sha}
+}
+
+func TestRedisQueue(t *testing.T) {
+	var mu sync.Mutex
+	var processed []string
+	running := map[int]bool{}
+
+	release := make(chan struct{})
+	queues := newTestRedisQueues(t, func(_ context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ AIReviewChecker) error {
+		mu.Lock()
+		assert.False(t, running[pr.CheckID], "the same PR must never be processed twice at once")
+		running[pr.CheckID] = true
+		mu.Unlock()
+
+		if pr.SHA == "sha-1" {
+			<-release
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		running[pr.CheckID] = false
+		processed = append(processed, pr.SHA)
+		return nil
+	}, "replica-a", "replica-b")
+
+	ctx := context.Background()
+	for _, q := range queues {
+		require.NoError(t, q.Resume(ctx, ResumeParams{}))
+	}
+
+	// every request is received by the first replica
+	require.NoError(t, queues[0].Enqueue(ctx, EnqueueParams{PullRequest: testPullRequest(1, "sha-1")}))
+	otherRepo := vcs.PullRequest{CloneURL: "https://github.com/zapier/other.git", CheckID: 2, SHA: "other-pr"}
+	require.NoError(t, queues[0].Enqueue(ctx, EnqueueParams{PullRequest: otherRepo}))
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return running[1] && len(processed) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the newer commit waits for the PR lock, while the older one keeps running
+	require.NoError(t, queues[0].Enqueue(ctx, EnqueueParams{PullRequest: testPullRequest(1, "sha-1")}))
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(processed) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{"other-pr", "sha-1", "sha-1"}, processed)
+
+	for _, q := range queues {
+		require.NoError(t, q.Shutdown(ctx))
+	}
+
+	length, err := queues[0].client.XLen(ctx, queues[0].streamKey()).Result()
+	require.NoError(t, err)
+	assert.Zero(t, length, "every request is acknowledged and removed")
+}
+
+func TestRedisQueue_CancelsOutdatedCommit(t *testing.T) {
+	causes := make(chan error, 1)
+	processed := make(chan string, 2)
+
+	queues := newTestRedisQueues(t, func(ctx context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ AIReviewChecker) error {
+		if pr.SHA == "sha-1" {
+			<-ctx.Done()
+			causes <- context.Cause(ctx)
+		}
+		processed <- pr.SHA
+		return nil
+	}, "replica-a", "replica-b")
+
+	ctx := context.Background()
+	require.NoError(t, queues[1].Resume(ctx, ResumeParams{}))
+
+	require.NoError(t, queues[0].Enqueue(ctx, EnqueueParams{PullRequest: testPullRequest(1, "sha-1")}))
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, queues[0].Enqueue(ctx, EnqueueParams{PullRequest: testPullRequest(1, "sha-2")}))
+
+	select {
+	case cause := <-causes:
+		assert.Equal(t, pkg.SupersededError{SHA: "sha-2"}, cause)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the outdated commit was not cancelled")
+	}
+	assert.Equal(t, "sha-1", <-processed)
+	assert.Equal(t, "sha-2", <-processed)
+
+	require.NoError(t, queues[1].Shutdown(ctx))
+}
+
+func TestRedisQueue_Cancel(t *testing.T) {
+	causes := make(chan error, 1)
+
+	queues := newTestRedisQueues(t, func(ctx context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ AIReviewChecker) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return nil
+	}, "replica-a", "replica-b")
+
+	ctx := context.Background()
+	cancelled, err := queues[0].Cancel(ctx, testPullRequest(1, "sha-1"))
+	require.NoError(t, err)
+	assert.False(t, cancelled, "nothing was queued")
+
+	// the request is processed by another replica than the one that cancels it
+	require.NoError(t, queues[1].Resume(ctx, ResumeParams{}))
+	require.NoError(t, queues[0].Enqueue(ctx, EnqueueParams{PullRequest: testPullRequest(1, "sha-1")}))
+	time.Sleep(100 * time.Millisecond)
+
+	cancelled, err = queues[0].Cancel(ctx, testPullRequest(1, "sha-1"))
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+
+	select {
+	case cause := <-causes:
+		assert.Equal(t, pkg.CancelledError{}, cause)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request was not cancelled")
+	}
+
+	require.NoError(t, queues[1].Shutdown(ctx))
+}
+
+func TestRedisQueue_LostLock(t *testing.T) {
+	causes := make(chan error, 1)
+	started := make(chan struct{})
+
+	queues := newTestRedisQueues(t, func(ctx context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ AIReviewChecker) error {
+		close(started)
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	}, "replica-a")
+
+	ctx := context.Background()
+	q := queues[0]
+	require.NoError(t, q.Resume(ctx, ResumeParams{}))
+	require.NoError(t, q.Enqueue(ctx, EnqueueParams{PullRequest: testPullRequest(1, "sha-1")}))
+	<-started
+
+	// the lock expires mid-run, and another replica locks the PR
+	_, prKey, err := pullRequestKey(testPullRequest(1, "sha-1"))
+	require.NoError(t, err)
+	require.NoError(t, q.client.Set(ctx, q.lockKey(prKey), "replica-b/other", time.Minute).Err())
+
+	select {
+	case cause := <-causes:
+		assert.Equal(t, pkg.LockLostError{}, cause)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the check kept running without the lock")
+	}
+
+	require.NoError(t, q.Shutdown(ctx))
+
+	owner, err := q.client.Get(ctx, q.lockKey(prKey)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, "replica-b/other", owner, "the lock of the other replica is kept")
+	length, err := q.client.XLen(ctx, q.streamKey()).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), length, "the request is not acknowledged, its new owner finishes it")
+}
+
+func TestRedisQueue_ClaimsAbandonedRequests(t *testing.T) {
+	processed := make(chan string, 1)
+	queues := newTestRedisQueues(t, func(_ context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ AIReviewChecker) error {
+		processed <- pr.SHA
+		return nil
+	}, "replica-a", "replica-b")
+
+	ctx := context.Background()
+	dead, alive := queues[0], queues[1]
+
+	// the first replica takes the request, and stops before processing it
+	require.NoError(t, dead.client.XGroupCreateMkStream(ctx, dead.streamKey(), redisGroup, "0").Err())
+	require.NoError(t, dead.Enqueue(ctx, EnqueueParams{PullRequest: testPullRequest(1, "sha-1")}))
+	_, ok, err := dead.next(ctx, "replica-a-0")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.NoError(t, alive.Resume(ctx, ResumeParams{}))
+	select {
+	case sha := <-processed:
+		assert.Equal(t, "sha-1", sha)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the abandoned request was not picked up")
+	}
+
+	require.NoError(t, alive.Shutdown(ctx))
+}
@@ -0,0 +1,211 @@
+package queue
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// Priority of a check request. When requests wait for a slot, a repository gets a share of the slots that is
+// proportional to the priority of its request.
+type Priority int
+
+const (
+	PriorityLow    Priority = 1
+	PriorityNormal Priority = 2
+	PriorityHigh   Priority = 4
+)
+
+// PriorityLabelPrefix starts the PR labels that set the priority of its checks, e.g. kubechecks:priority-high.
+const PriorityLabelPrefix = "kubechecks:priority-"
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// ParsePriority returns the priority called name, and false if there is no such priority.
+func ParsePriority(name string) (Priority, bool) {
+	switch name {
+	case "low":
+		return PriorityLow, true
+	case "normal":
+		return PriorityNormal, true
+	case "high":
+		return PriorityHigh, true
+	default:
+		return PriorityNormal, false
+	}
+}
+
+// PriorityOf returns the priority of the checks of a PR. A priority label on the PR takes precedence over the
+// priority of its repository, which is keyed by the full name of the repository. Anyone who can label the PR can
+// set its priority, so labels only set the priorities listed in labelPriorities.
+func PriorityOf(pr vcs.PullRequest, repoPriorities map[string]string, labelPriorities []string) Priority {
+	for _, label := range pr.Labels {
+		if name, ok := strings.CutPrefix(label, PriorityLabelPrefix); ok && slices.Contains(labelPriorities, name) {
+			if priority, ok := ParsePriority(name); ok {
+				return priority
+			}
+		}
+	}
+
+	if priority, ok := ParsePriority(repoPriorities[pr.FullName]); ok {
+		return priority
+	}
+
+	return PriorityNormal
+}
+
+// ticket is a request waiting for a slot.
+type ticket struct {
+	repo     string
+	request  *CheckRequest
+	priority Priority
+	seq      uint64
+	waiting  time.Time
+	granted  chan struct{}
+}
+
+// Scheduler limits how many check requests are processed at once across every repository. Waiting requests get
+// slots with stride scheduling: each grant moves the pass of a repository forward by the inverse of the priority of
+// its request, and the repository with the lowest pass goes next. Repositories are served fairly, and a repository
+// with a high priority request is served four times as often as one with a low priority request.
+type Scheduler struct {
+	mu      sync.Mutex
+	slots   int
+	running map[*CheckRequest]string
+	waiting []*ticket
+	pass    map[string]float64
+	vtime   float64
+	seq     uint64
+}
+
+// NewScheduler returns a scheduler that processes up to slots requests at once.
+func NewScheduler(slots int) *Scheduler {
+	return &Scheduler{
+		slots:   slots,
+		running: make(map[*CheckRequest]string),
+		pass:    make(map[string]float64),
+	}
+}
+
+// Acquire blocks until the request may be processed. It returns false, without a slot, if cancel is closed first.
+func (s *Scheduler) Acquire(repo string, request *CheckRequest, priority Priority, cancel <-chan struct{}) bool {
+	s.mu.Lock()
+	s.seq++
+	t := &ticket{
+		repo:     repo,
+		request:  request,
+		priority: priority,
+		seq:      s.seq,
+		waiting:  time.Now(),
+		granted:  make(chan struct{}),
+	}
+
+	// a repository that was idle starts at the current virtual time, so it can't catch up on the slots it didn't use
+	if s.pass[repo] < s.vtime {
+		s.pass[repo] = s.vtime
+	}
+
+	s.waiting = append(s.waiting, t)
+	s.grant()
+	s.updateMetrics()
+	s.mu.Unlock()
+
+	select {
+	case <-t.granted:
+		schedulerWaitDuration.WithLabelValues(priority.String()).Observe(time.Since(t.waiting).Seconds())
+		return true
+	case <-cancel:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		select {
+		case <-t.granted:
+			// granted at the same time, give the slot to the next request
+			delete(s.running, request)
+		default:
+			s.remove(t)
+		}
+		s.grant()
+		s.updateMetrics()
+		return false
+	}
+}
+
+// Release frees the slot of a request once it has been processed.
+func (s *Scheduler) Release(request *CheckRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.running, request)
+	s.grant()
+	s.updateMetrics()
+}
+
+// grant hands out the free slots. The caller must hold s.mu.
+func (s *Scheduler) grant() {
+	for len(s.running) < s.slots && len(s.waiting) > 0 {
+		s.sortWaiting()
+		next := s.waiting[0]
+		s.waiting = s.waiting[1:]
+
+		s.vtime = s.pass[next.repo]
+		s.pass[next.repo] += 1 / float64(next.priority)
+		s.running[next.request] = next.repo
+		close(next.granted)
+	}
+}
+
+// sortWaiting orders the waiting requests by the order they would get a slot. The caller must hold s.mu.
+func (s *Scheduler) sortWaiting() {
+	sort.SliceStable(s.waiting, func(i, j int) bool {
+		a, b := s.waiting[i], s.waiting[j]
+		if s.pass[a.repo] != s.pass[b.repo] {
+			return s.pass[a.repo] < s.pass[b.repo]
+		}
+		if a.priority != b.priority {
+			return a.priority > b.priority
+		}
+		return a.seq < b.seq
+	})
+}
+
+func (s *Scheduler) remove(t *ticket) {
+	for i, waiting := range s.waiting {
+		if waiting == t {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *Scheduler) updateMetrics() {
+	schedulerRunning.Set(float64(len(s.running)))
+	schedulerWaiting.Set(float64(len(s.waiting)))
+}
+
+// snapshot returns the running requests, and the waiting ones in the order they will get a slot.
+func (s *Scheduler) snapshot() (running map[*CheckRequest]struct{}, waiting []*ticket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running = make(map[*CheckRequest]struct{}, len(s.running))
+	for request := range s.running {
+		running[request] = struct{}{}
+	}
+
+	s.sortWaiting()
+	return running, append([]*ticket(nil), s.waiting...)
+}
@@ -0,0 +1,127 @@
+package queue
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func waitingCount(s *Scheduler) int {
+	_, waiting := s.snapshot()
+	return len(waiting)
+}
+
+func TestScheduler_WeightedPriority(t *testing.T) {
+	s := NewScheduler(1)
+	blocker := &CheckRequest{}
+	require.True(t, s.Acquire("blocker", blocker, PriorityNormal, nil))
+
+	var mu sync.Mutex
+	var granted []string
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		for _, repo := range []struct {
+			name     string
+			priority Priority
+		}{{"high", PriorityHigh}, {"low", PriorityLow}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				request := &CheckRequest{}
+				require.True(t, s.Acquire(repo.name, request, repo.priority, nil))
+
+				mu.Lock()
+				granted = append(granted, repo.name)
+				mu.Unlock()
+				s.Release(request)
+			}()
+		}
+	}
+	require.Eventually(t, func() bool { return waitingCount(s) == 16 }, time.Second, time.Millisecond)
+
+	s.Release(blocker)
+	wg.Wait()
+
+	// the high priority repository is served four times as often, without starving the low priority one
+	assert.Equal(t, "high low high high high high low high high high", strings.Join(granted[:10], " "))
+}
+
+func TestScheduler_IdleRepoDoesNotCatchUp(t *testing.T) {
+	s := NewScheduler(1)
+
+	// busy is served a few times while idle has nothing to check
+	for i := 0; i < 3; i++ {
+		request := &CheckRequest{}
+		require.True(t, s.Acquire("busy", request, PriorityNormal, nil))
+		s.Release(request)
+	}
+
+	blocker := &CheckRequest{}
+	require.True(t, s.Acquire("busy", blocker, PriorityNormal, nil))
+
+	order := make(chan string, 3)
+	for i, repo := range []string{"idle", "busy", "other"} {
+		go func() {
+			request := &CheckRequest{}
+			s.Acquire(repo, request, PriorityNormal, nil)
+			order <- repo
+			s.Release(request)
+		}()
+		require.Eventually(t, func() bool { return waitingCount(s) == i+1 }, time.Second, time.Millisecond)
+	}
+
+	s.Release(blocker)
+	assert.Equal(t, "idle", <-order, "requests of idle repositories that arrived first go first")
+	assert.Equal(t, "other", <-order)
+	assert.Equal(t, "busy", <-order)
+}
+
+func TestScheduler_Cancel(t *testing.T) {
+	s := NewScheduler(1)
+	blocker := &CheckRequest{}
+	require.True(t, s.Acquire("a", blocker, PriorityNormal, nil))
+
+	cancel := make(chan struct{})
+	acquired := make(chan bool)
+	go func() {
+		acquired <- s.Acquire("b", &CheckRequest{}, PriorityHigh, cancel)
+	}()
+	require.Eventually(t, func() bool { return waitingCount(s) == 1 }, time.Second, time.Millisecond)
+
+	close(cancel)
+	assert.False(t, <-acquired)
+	assert.Equal(t, 0, waitingCount(s))
+
+	s.Release(blocker)
+	request := &CheckRequest{}
+	assert.True(t, s.Acquire("c", request, PriorityNormal, nil), "the slot is free again")
+}
+
+func TestPriorityOf(t *testing.T) {
+	repoPriorities := map[string]string{"zapier/kubechecks": "high"}
+	labelPriorities := []string{"low", "urgent"}
+
+	assert.Equal(t, PriorityHigh, PriorityOf(vcs.PullRequest{FullName: "zapier/kubechecks"}, repoPriorities, labelPriorities))
+	assert.Equal(t, PriorityNormal, PriorityOf(vcs.PullRequest{FullName: "zapier/other"}, repoPriorities, labelPriorities))
+	assert.Equal(t, PriorityLow, PriorityOf(vcs.PullRequest{
+		FullName: "zapier/kubechecks",
+		Labels:   []string{"kubechecks:priority-low"},
+	}, repoPriorities, labelPriorities), "labels take precedence over the repository")
+	assert.Equal(t, PriorityNormal, PriorityOf(vcs.PullRequest{
+		Labels: []string{"kubechecks:priority-urgent"},
+	}, repoPriorities, labelPriorities), "unknown priorities are ignored")
+	assert.Equal(t, PriorityNormal, PriorityOf(vcs.PullRequest{
+		FullName: "zapier/other",
+		Labels:   []string{"kubechecks:priority-high"},
+	}, repoPriorities, labelPriorities), "labels only set the allowed priorities")
+	assert.Equal(t, PriorityHigh, PriorityOf(vcs.PullRequest{
+		FullName: "zapier/kubechecks",
+		Labels:   []string{"kubechecks:priority-low"},
+	}, repoPriorities, nil), "labels are ignored when no priority is allowed")
+}
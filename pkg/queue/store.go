@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// StoredRequest is the part of a check request that is persisted. The container, processors and AI review checker
+// are provided again when the request is resumed.
+type StoredRequest struct {
+	ID          uint64          `json:"id"`
+	PullRequest vcs.PullRequest `json:"pullRequest"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// Store persists check requests from the moment they are enqueued until they have been processed, so that pending
+// and in-flight requests survive a restart.
+type Store interface {
+	// Add persists a request and returns the ID it is stored under.
+	Add(request StoredRequest) (uint64, error)
+	// Remove forgets a request that has been processed, superseded or dropped.
+	Remove(id uint64) error
+	// List returns every request that has not been removed, oldest first.
+	List() ([]StoredRequest, error)
+	Close() error
+}
+
+var requestsBucket = []byte("requests")
+
+// boltStore keeps the requests in a single file, e.g. on a persistent volume.
+type boltStore struct {
+	db *bolt.DB
+}
+
+var _ Store = (*boltStore)(nil)
+
+// NewBoltStore opens, or creates, the file that requests are persisted to.
+func NewBoltStore(path string) (Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open queue store %q", path)
+	}
+
+	if err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(requestsBucket)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, "failed to create requests bucket")
+	}
+
+	return &boltStore{db: db}, nil
+}
+
+func (s *boltStore) Add(request StoredRequest) (uint64, error) {
+	// the config is rebuilt from the running instance, and holds secrets that don't belong on disk
+	request.PullRequest.Config = config.ServerConfig{}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(requestsBucket)
+
+		id, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		request.ID = id
+
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(idToKey(id), data)
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to store request")
+	}
+
+	return request.ID, nil
+}
+
+func (s *boltStore) Remove(id uint64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(requestsBucket).Delete(idToKey(id))
+	})
+	return errors.Wrapf(err, "failed to remove request %d", id)
+}
+
+func (s *boltStore) List() ([]StoredRequest, error) {
+	var requests []StoredRequest
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// keys are big endian, so the cursor walks them in the order they were added
+		return tx.Bucket(requestsBucket).ForEach(func(_, data []byte) error {
+			var request StoredRequest
+			if err := json.Unmarshal(data, &request); err != nil {
+				return err
+			}
+			requests = append(requests, request)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list requests")
+	}
+
+	return requests, nil
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+func idToKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
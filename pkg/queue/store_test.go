@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func TestBoltStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.db")
+
+	store, err := NewBoltStore(path)
+	require.NoError(t, err)
+
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	first, err := store.Add(StoredRequest{
+		PullRequest: vcs.PullRequest{CheckID: 1, SHA: "sha-1", Config: config.ServerConfig{VcsToken: "secret"}},
+		Timestamp:   timestamp,
+	})
+	require.NoError(t, err)
+	second, err := store.Add(StoredRequest{PullRequest: vcs.PullRequest{CheckID: 2, SHA: "sha-2"}, Timestamp: timestamp})
+	require.NoError(t, err)
+	third, err := store.Add(StoredRequest{PullRequest: vcs.PullRequest{CheckID: 3, SHA: "sha-3"}, Timestamp: timestamp})
+	require.NoError(t, err)
+	assert.NotZero(t, first)
+
+	require.NoError(t, store.Remove(second))
+	require.NoError(t, store.Close())
+
+	// the requests survive reopening the file
+	store, err = NewBoltStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	requests, err := store.List()
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+
+	assert.Equal(t, first, requests[0].ID)
+	assert.Equal(t, "sha-1", requests[0].PullRequest.SHA)
+	assert.Empty(t, requests[0].PullRequest.Config.VcsToken, "the config must not be persisted")
+	assert.True(t, timestamp.Equal(requests[0].Timestamp))
+	assert.Equal(t, third, requests[1].ID)
+}
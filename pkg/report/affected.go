@@ -0,0 +1,127 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/affected_apps"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// Affected lists the apps and appsets that a set of changes affects, without the results of any checks.
+type Affected struct {
+	SchemaVersion string `json:"schemaVersion"`
+	Repository    string `json:"repository"`
+	BaseRef       string `json:"baseRef"`
+	HeadRef       string `json:"headRef"`
+
+	ChangedFiles    []string                 `json:"changedFiles"`
+	ApplicationSets []AffectedApplicationSet `json:"applicationSets"`
+	Applications    []AffectedApplication    `json:"applications"`
+}
+
+type AffectedApplicationSet struct {
+	Name           string   `json:"name"`
+	ArgoCDInstance string   `json:"argocdInstance,omitempty"`
+	Reasons        []string `json:"reasons,omitempty"`
+}
+
+type AffectedApplication struct {
+	Name           string   `json:"name"`
+	ArgoCDInstance string   `json:"argocdInstance,omitempty"`
+	Namespace      string   `json:"namespace,omitempty"`
+	Project        string   `json:"project,omitempty"`
+	RepoURL        string   `json:"repoUrl,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	TargetRevision string   `json:"targetRevision,omitempty"`
+	Reasons        []string `json:"reasons,omitempty"`
+}
+
+// NewAffected builds the list of affected apps and appsets for the given changed files.
+func NewAffected(pr vcs.PullRequest, files []string, items affected_apps.AffectedItems) *Affected {
+	a := &Affected{
+		SchemaVersion: SchemaVersion,
+		Repository:    pr.FullName,
+		BaseRef:       pr.BaseRef,
+		HeadRef:       pr.HeadRef,
+
+		ChangedFiles:    append([]string{}, files...),
+		ApplicationSets: make([]AffectedApplicationSet, 0, len(items.ApplicationSets)),
+		Applications:    make([]AffectedApplication, 0, len(items.Applications)),
+	}
+	sort.Strings(a.ChangedFiles)
+
+	for _, appSet := range items.ApplicationSets {
+		name := pkg.QualifiedName(appSet.ObjectMeta)
+		a.ApplicationSets = append(a.ApplicationSets, AffectedApplicationSet{
+			Name:           name,
+			ArgoCDInstance: pkg.ArgoCDInstanceOf(appSet.ObjectMeta),
+			Reasons:        items.AppSetReasons[name],
+		})
+	}
+	sort.Slice(a.ApplicationSets, func(i, j int) bool {
+		return a.ApplicationSets[i].Name < a.ApplicationSets[j].Name
+	})
+
+	for _, app := range items.Applications {
+		src := app.Spec.GetSource()
+		name := pkg.QualifiedName(app.ObjectMeta)
+		a.Applications = append(a.Applications, AffectedApplication{
+			Name:           name,
+			ArgoCDInstance: pkg.ArgoCDInstanceOf(app.ObjectMeta),
+			Namespace:      app.Spec.Destination.Namespace,
+			Project:        app.Spec.Project,
+			RepoURL:        src.RepoURL,
+			Path:           src.Path,
+			TargetRevision: src.TargetRevision,
+			Reasons:        items.AppReasons[name],
+		})
+	}
+	sort.Slice(a.Applications, func(i, j int) bool {
+		return a.Applications[i].Name < a.Applications[j].Name
+	})
+
+	return a
+}
+
+// WriteJSON writes the list as an indented JSON document.
+func (a *Affected) WriteJSON(w io.Writer) error {
+	return errors.Wrap(writeIndentedJSON(w, a), "failed to encode affected apps")
+}
+
+// WriteTable writes one row per affected appset and app, followed by one row per additional reason.
+func (a *Affected) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "KIND\tNAME\tPATH\tREASON")
+	for _, appSet := range a.ApplicationSets {
+		writeTableRows(tw, "ApplicationSet", appSet.Name, "", appSet.Reasons)
+	}
+	for _, app := range a.Applications {
+		writeTableRows(tw, "Application", app.Name, app.Path, app.Reasons)
+	}
+
+	return errors.Wrap(tw.Flush(), "failed to write table")
+}
+
+func writeTableRows(w io.Writer, kind, name, path string, reasons []string) {
+	if len(reasons) == 0 {
+		reasons = []string{""}
+	}
+
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", kind, name, path, tableCell(reasons[0]))
+	for _, reason := range reasons[1:] {
+		fmt.Fprintf(w, "\t\t\t%s\n", tableCell(reason))
+	}
+}
+
+// tableCell strips the markdown backticks the reasons are written with, as they're only noise in a terminal.
+func tableCell(s string) string {
+	return strings.ReplaceAll(s, "`", "")
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zapier/kubechecks/pkg/affected_apps"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func newTestAffected() *Affected {
+	items := affected_apps.AffectedItems{
+		Applications: []v1alpha1.Application{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-b"},
+				Spec:       v1alpha1.ApplicationSpec{Source: &v1alpha1.ApplicationSource{Path: "apps/b"}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "app-a"},
+				Spec: v1alpha1.ApplicationSpec{
+					Project: "default",
+					Source:  &v1alpha1.ApplicationSource{RepoURL: "https://github.com/zapier/repo.git", Path: "apps/a"},
+				},
+			},
+		},
+		ApplicationSets: []v1alpha1.ApplicationSet{{ObjectMeta: metav1.ObjectMeta{Name: "set-a"}}},
+	}
+	items.AddAppReason("app-a", "file `apps/a/values.yaml` is in `apps/a`, which is the source path of the app", "rendered by app `root`")
+	items.AddAppSetReason("set-a", "file `appsets/a.yaml` defines the appset, and was changed")
+
+	pr := vcs.PullRequest{FullName: "zapier/repo", BaseRef: "main", HeadRef: "feature"}
+	return NewAffected(pr, []string{"apps/a/values.yaml", "appsets/a.yaml"}, items)
+}
+
+func TestNewAffected(t *testing.T) {
+	a := newTestAffected()
+
+	assert.Equal(t, "zapier/repo", a.Repository)
+	assert.Equal(t, []string{"apps/a/values.yaml", "appsets/a.yaml"}, a.ChangedFiles)
+
+	require.Len(t, a.Applications, 2)
+	assert.Equal(t, "app-a", a.Applications[0].Name, "applications must be sorted")
+	assert.Equal(t, "default", a.Applications[0].Project)
+	assert.Len(t, a.Applications[0].Reasons, 2)
+	assert.Empty(t, a.Applications[1].Reasons)
+
+	require.Len(t, a.ApplicationSets, 1)
+	assert.Equal(t, []string{"file `appsets/a.yaml` defines the appset, and was changed"}, a.ApplicationSets[0].Reasons)
+
+	var buf bytes.Buffer
+	require.NoError(t, a.WriteJSON(&buf))
+
+	var decoded Affected
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, *a, decoded)
+}
+
+func TestAffected_WriteTable(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newTestAffected().WriteTable(&buf))
+
+	expected := "" +
+		"KIND            NAME   PATH    REASON\n" +
+		"ApplicationSet  set-a          file appsets/a.yaml defines the appset, and was changed\n" +
+		"Application     app-a  apps/a  file apps/a/values.yaml is in apps/a, which is the source path of the app\n" +
+		"                               rendered by app root\n" +
+		"Application     app-b  apps/b  \n"
+	assert.Equal(t, expected, buf.String())
+}
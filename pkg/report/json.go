@@ -0,0 +1,36 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/zapier/kubechecks/pkg"
+)
+
+// WriteJSON writes the report as an indented JSON document.
+func (r *Report) WriteJSON(w io.Writer) error {
+	return errors.Wrap(writeIndentedJSON(w, r), "failed to encode report")
+}
+
+func writeIndentedJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+
+	return encoder.Encode(v)
+}
+
+// WriteFile creates (or truncates) the file at path and writes the report to it using the given writer,
+// e.g. (*Report).WriteJSON.
+func (r *Report) WriteFile(path string, write func(*Report, io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to create report file")
+	}
+	defer pkg.WithErrorLogging(f.Close, "failed to close report file")
+
+	return write(r, f)
+}
@@ -0,0 +1,134 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+func junitTime(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+// WriteJUnit writes the report as JUnit XML, with a test suite per app and a test case per check.
+// Failed, errored and panicked checks are reported as failures, skipped checks as skipped. A run that failed, was
+// superseded or was cancelled adds a failed or skipped test case of its own.
+func (r *Report) WriteJUnit(w io.Writer) error {
+	suites := junitTestSuites{
+		Name:   fmt.Sprintf("kubechecks %s#%d", r.PullRequest.Repository, r.PullRequest.Number),
+		Time:   junitTime(r.DurationSeconds),
+		Suites: make([]junitTestSuite, 0, len(r.Applications)),
+	}
+
+	for _, app := range r.Applications {
+		suite := junitTestSuite{
+			Name:      app.Name,
+			TestCases: make([]junitTestCase, 0, len(app.Checks)),
+		}
+
+		var duration float64
+		for _, check := range app.Checks {
+			duration += check.DurationSeconds
+
+			testCase := junitTestCase{
+				Name:      check.Name,
+				ClassName: app.Name,
+				Time:      junitTime(check.DurationSeconds),
+			}
+
+			switch check.State {
+			case "failure", "error", "panic":
+				testCase.Failure = &junitFailure{
+					Message: check.Summary,
+					Type:    check.State,
+					Body:    check.Details,
+				}
+				suite.Failures++
+			case "skip":
+				testCase.Skipped = &junitSkipped{Message: check.Summary}
+				suite.Skipped++
+			}
+
+			suite.TestCases = append(suite.TestCases, testCase)
+		}
+
+		suite.Tests = len(suite.TestCases)
+		suite.Time = junitTime(duration)
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	// a run that did not complete is a test case of its own, so that it is not mistaken for a passing one
+	if r.Status != "" && r.Status != StatusCompleted {
+		testCase := junitTestCase{Name: "run", ClassName: "kubechecks", Time: junitTime(r.DurationSeconds)}
+		suite := junitTestSuite{Name: "kubechecks", Tests: 1, Time: testCase.Time}
+		if r.Status == StatusFailed {
+			testCase.Failure = &junitFailure{Message: r.Error, Type: r.Status}
+			suite.Failures++
+		} else {
+			testCase.Skipped = &junitSkipped{Message: r.Status}
+			suite.Skipped++
+		}
+		suite.TestCases = []junitTestCase{testCase}
+
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return errors.Wrap(err, "failed to write junit report")
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return errors.Wrap(err, "failed to encode junit report")
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return errors.Wrap(err, "failed to write junit report")
+}
@@ -0,0 +1,249 @@
+// Package report turns the results of a kubechecks run into machine-readable documents
+// that can be archived by CI systems or ingested by other tooling.
+package report
+
+import (
+	"sort"
+	"time"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/affected_apps"
+	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// SchemaVersion is the version of the JSON document written by WriteJSON. It must be bumped whenever
+// a field is removed or changes meaning; adding fields does not require a new version.
+const SchemaVersion = "1"
+
+type Report struct {
+	SchemaVersion string `json:"schemaVersion"`
+	Tool          Tool   `json:"tool"`
+	Identifier    string `json:"identifier,omitempty"`
+
+	PullRequest PullRequest `json:"pullRequest"`
+
+	StartedAt       time.Time `json:"startedAt"`
+	FinishedAt      time.Time `json:"finishedAt"`
+	DurationSeconds float64   `json:"durationSeconds"`
+
+	// State is the worst state of all checks that detected changes, or at least "error" when the run failed.
+	State string `json:"state"`
+	// Status is how the run ended. Runs that did not complete only hold the results they got to.
+	Status string `json:"status"`
+	// Error is why a failed run stopped.
+	Error string `json:"error,omitempty"`
+
+	AffectedApplicationSets []string      `json:"affectedApplicationSets"`
+	Applications            []Application `json:"applications"`
+}
+
+type Tool struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+type PullRequest struct {
+	Repository string   `json:"repository"`
+	Number     int      `json:"number"`
+	Title      string   `json:"title,omitempty"`
+	SHA        string   `json:"sha"`
+	BaseRef    string   `json:"baseRef"`
+	HeadRef    string   `json:"headRef"`
+	CloneURL   string   `json:"cloneUrl"`
+	Labels     []string `json:"labels,omitempty"`
+}
+
+type Application struct {
+	Name           string  `json:"name"`
+	ArgoCDInstance string  `json:"argocdInstance,omitempty"`
+	Namespace      string  `json:"namespace,omitempty"`
+	Project        string  `json:"project,omitempty"`
+	RepoURL        string  `json:"repoUrl,omitempty"`
+	Path           string  `json:"path,omitempty"`
+	TargetRevision string  `json:"targetRevision,omitempty"`
+	State          string  `json:"state"`
+	Checks         []Check `json:"checks"`
+
+	// Reasons explain why the application was checked.
+	Reasons []string `json:"reasons,omitempty"`
+	// Parent is the app-of-apps that rendered the application, if any.
+	Parent string `json:"parent,omitempty"`
+}
+
+type Check struct {
+	Name              string    `json:"name"`
+	State             string    `json:"state"`
+	Summary           string    `json:"summary"`
+	Details           string    `json:"details"`
+	NoChangesDetected bool      `json:"noChangesDetected"`
+	DurationSeconds   float64   `json:"durationSeconds"`
+	Findings          []Finding `json:"findings,omitempty"`
+}
+
+type Finding struct {
+	Tool     string `json:"tool"`
+	RuleID   string `json:"ruleId"`
+	Level    string `json:"level"`
+	Message  string `json:"message"`
+	Resource string `json:"resource,omitempty"`
+}
+
+// Statuses of a run, the same as those of the history of runs.
+const (
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+	StatusSuperseded = "superseded"
+	StatusCancelled  = "cancelled"
+)
+
+// stateNames uses the same vocabulary as pkg.ParseCommitState, so that states can be read back.
+var stateNames = map[pkg.CommitState]string{
+	pkg.StateNone:    "none",
+	pkg.StateSkip:    "skip",
+	pkg.StateSuccess: "success",
+	pkg.StateRunning: "running",
+	pkg.StateWarning: "warning",
+	pkg.StateFailure: "failure",
+	pkg.StateError:   "error",
+	pkg.StatePanic:   "panic",
+}
+
+func stateName(state pkg.CommitState) string {
+	name, ok := stateNames[state]
+	if !ok {
+		return "unknown"
+	}
+	return name
+}
+
+// New builds a report for a single run. Results are keyed by app name, as returned by msg.Message.Snapshot.
+func New(
+	pr vcs.PullRequest, identifier string, items affected_apps.AffectedItems,
+	results map[string][]msg.Result, startedAt, finishedAt time.Time,
+) *Report {
+	r := &Report{
+		SchemaVersion: SchemaVersion,
+		Tool: Tool{
+			Name:    "kubechecks",
+			Version: pkg.GitTag,
+			Commit:  pkg.GitCommit,
+		},
+		Identifier: identifier,
+		PullRequest: PullRequest{
+			Repository: pr.FullName,
+			Number:     pr.CheckID,
+			Title:      pr.Title,
+			SHA:        pr.SHA,
+			BaseRef:    pr.BaseRef,
+			HeadRef:    pr.HeadRef,
+			CloneURL:   pr.CloneURL,
+			Labels:     pr.Labels,
+		},
+		StartedAt:       startedAt,
+		FinishedAt:      finishedAt,
+		DurationSeconds: finishedAt.Sub(startedAt).Seconds(),
+		Status:          StatusCompleted,
+
+		AffectedApplicationSets: make([]string, 0, len(items.ApplicationSets)),
+		Applications:            make([]Application, 0, len(results)),
+	}
+
+	for _, appSet := range items.ApplicationSets {
+		r.AffectedApplicationSets = append(r.AffectedApplicationSets, pkg.QualifiedName(appSet.ObjectMeta))
+	}
+	sort.Strings(r.AffectedApplicationSets)
+
+	apps := make(map[string]v1alpha1.Application, len(items.Applications))
+	for _, app := range items.Applications {
+		apps[pkg.QualifiedName(app.ObjectMeta)] = app
+	}
+
+	worst := pkg.StateNone
+	for name, appResults := range results {
+		app := newApplication(name, apps[name], appResults)
+		app.Reasons = items.AppReasons[name]
+		for _, result := range appResults {
+			if result.NoChangesDetected {
+				continue
+			}
+			worst = pkg.WorstState(worst, result.State)
+		}
+		r.Applications = append(r.Applications, app)
+	}
+	sort.Slice(r.Applications, func(i, j int) bool {
+		return r.Applications[i].Name < r.Applications[j].Name
+	})
+	r.State = stateName(worst)
+
+	return r
+}
+
+// SetStatus records how the run ended, and why when it failed.
+func (r *Report) SetStatus(status string, err error) {
+	r.Status = status
+	if err == nil {
+		return
+	}
+
+	r.Error = err.Error()
+	if r.State != stateName(pkg.StatePanic) {
+		r.State = stateName(pkg.StateError)
+	}
+}
+
+// SetParents records the app-of-apps of each child application, as returned by msg.Message.Parents.
+func (r *Report) SetParents(parents map[string]string) {
+	for i := range r.Applications {
+		r.Applications[i].Parent = parents[r.Applications[i].Name]
+	}
+}
+
+func newApplication(name string, app v1alpha1.Application, results []msg.Result) Application {
+	src := app.Spec.GetSource()
+	a := Application{
+		Name:           name,
+		ArgoCDInstance: pkg.ArgoCDInstanceOf(app.ObjectMeta),
+		Namespace:      app.Spec.Destination.Namespace,
+		Project:        app.Spec.Project,
+		RepoURL:        src.RepoURL,
+		Path:           src.Path,
+		TargetRevision: src.TargetRevision,
+		Checks:         make([]Check, 0, len(results)),
+	}
+
+	state := pkg.StateNone
+	for _, result := range results {
+		state = pkg.WorstState(state, result.State)
+		a.Checks = append(a.Checks, newCheck(result))
+	}
+	a.State = stateName(state)
+
+	return a
+}
+
+func newCheck(result msg.Result) Check {
+	c := Check{
+		Name:              result.Check,
+		State:             stateName(result.State),
+		Summary:           result.Summary,
+		Details:           result.Details,
+		NoChangesDetected: result.NoChangesDetected,
+		DurationSeconds:   result.Duration.Seconds(),
+	}
+
+	for _, f := range result.Findings {
+		c.Findings = append(c.Findings, Finding{
+			Tool:     f.Tool,
+			RuleID:   f.RuleID,
+			Level:    stateName(f.Level),
+			Message:  f.Message,
+			Resource: f.Resource,
+		})
+	}
+
+	return c
+}
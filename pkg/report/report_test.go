@@ -0,0 +1,222 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/affected_apps"
+	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func newTestReport() *Report {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	items := affected_apps.AffectedItems{
+		Applications: []v1alpha1.Application{{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-a"},
+			Spec: v1alpha1.ApplicationSpec{
+				Project: "default",
+				Source:  &v1alpha1.ApplicationSource{RepoURL: "https://github.com/zapier/repo.git", Path: "apps/a"},
+			},
+		}},
+		ApplicationSets: []v1alpha1.ApplicationSet{
+			{ObjectMeta: metav1.ObjectMeta{Name: "set-b"}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "set-a"}},
+		},
+	}
+
+	results := map[string][]msg.Result{
+		"app-b": {
+			{State: pkg.StateFailure, Check: "validating app against schema", NoChangesDetected: true},
+		},
+		"app-a": {
+			{State: pkg.StateSuccess, Check: "generating diff for app", Summary: "diff", Details: "+ added", Duration: 2 * time.Second},
+			{
+				State: pkg.StateWarning,
+				Check: "validating app against schema",
+				Findings: []msg.Finding{
+					{Tool: "kubeconform", RuleID: "kubeconform/invalid-resource", Level: pkg.StateWarning, Message: "bad field", Resource: "v1 ConfigMap test"},
+				},
+			},
+			{
+				State: pkg.StateFailure,
+				Check: "validation policy",
+				Findings: []msg.Finding{
+					{Tool: "conftest", RuleID: "conftest/main", Level: pkg.StateFailure, Message: "denied"},
+				},
+			},
+		},
+	}
+
+	pr := vcs.PullRequest{FullName: "zapier/repo", CheckID: 12, SHA: "abc123", BaseRef: "main", HeadRef: "feature"}
+
+	return New(pr, "test", items, results, start, start.Add(time.Minute))
+}
+
+func TestNew(t *testing.T) {
+	r := newTestReport()
+
+	assert.Equal(t, SchemaVersion, r.SchemaVersion)
+	assert.Equal(t, "zapier/repo", r.PullRequest.Repository)
+	assert.Equal(t, 12, r.PullRequest.Number)
+	assert.Equal(t, float64(60), r.DurationSeconds)
+	assert.Equal(t, []string{"set-a", "set-b"}, r.AffectedApplicationSets)
+
+	// app-b's failure has no changes, so it does not count towards the overall state
+	assert.Equal(t, "failure", r.State)
+
+	require.Len(t, r.Applications, 2)
+	app := r.Applications[0]
+	assert.Equal(t, "app-a", app.Name)
+	assert.Equal(t, "apps/a", app.Path)
+	assert.Equal(t, "default", app.Project)
+	assert.Equal(t, "failure", app.State)
+	require.Len(t, app.Checks, 3)
+	assert.Equal(t, "generating diff for app", app.Checks[0].Name)
+	assert.Equal(t, "+ added", app.Checks[0].Details)
+	assert.Equal(t, float64(2), app.Checks[0].DurationSeconds)
+	assert.Equal(t, "warning", app.Checks[1].Findings[0].Level)
+
+	assert.Equal(t, "app-b", r.Applications[1].Name)
+	assert.Empty(t, r.Applications[1].Path)
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newTestReport().WriteJSON(&buf))
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "1", decoded["schemaVersion"])
+	assert.Equal(t, "failure", decoded["state"])
+	assert.Len(t, decoded["applications"], 2)
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newTestReport().WriteSARIF(&buf))
+
+	var decoded sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, "2.1.0", decoded.Version)
+	require.Len(t, decoded.Runs, 3)
+
+	kubeconform := decoded.Runs[0]
+	assert.Equal(t, "kubeconform", kubeconform.Tool.Driver.Name)
+	require.Len(t, kubeconform.Results, 1)
+	assert.Equal(t, "warning", kubeconform.Results[0].Level)
+	assert.Equal(t, "app-a (v1 ConfigMap test): bad field", kubeconform.Results[0].Message.Text)
+	assert.Equal(t, "apps/a", kubeconform.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+
+	conftest := decoded.Runs[1]
+	require.Len(t, conftest.Results, 1)
+	assert.Equal(t, "error", conftest.Results[0].Level)
+	assert.Equal(t, []sarifRule{{ID: "conftest/main"}}, conftest.Tool.Driver.Rules)
+
+	kubepug := decoded.Runs[2]
+	assert.Empty(t, kubepug.Results)
+}
+
+func TestSarifLevel(t *testing.T) {
+	for level, expected := range map[string]string{
+		"panic": "error", "error": "error", "failure": "error",
+		"warning": "warning",
+		"success": "note", "skip": "note", "none": "note",
+	} {
+		assert.Equal(t, expected, sarifLevel(level), level)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	r := newTestReport()
+	r.Applications[1].Checks = append(r.Applications[1].Checks, Check{Name: "rendering hooks", State: "skip", Summary: "no hooks"})
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteJUnit(&buf))
+
+	var decoded junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, 5, decoded.Tests)
+	assert.Equal(t, 2, decoded.Failures)
+	assert.Equal(t, 1, decoded.Skipped)
+	require.Len(t, decoded.Suites, 2)
+
+	appA := decoded.Suites[0]
+	assert.Equal(t, "app-a", appA.Name)
+	assert.Equal(t, "2.000", appA.Time)
+	require.Len(t, appA.TestCases, 3)
+	assert.Nil(t, appA.TestCases[0].Failure)
+	assert.Nil(t, appA.TestCases[1].Failure, "warnings are not failures")
+	require.NotNil(t, appA.TestCases[2].Failure)
+	assert.Equal(t, "failure", appA.TestCases[2].Failure.Type)
+
+	appB := decoded.Suites[1]
+	assert.Equal(t, 1, appB.Failures)
+	require.NotNil(t, appB.TestCases[1].Skipped)
+	assert.Equal(t, "no hooks", appB.TestCases[1].Skipped.Message)
+}
+
+func TestWriteTerminal(t *testing.T) {
+	color.NoColor = true
+
+	var buf bytes.Buffer
+	require.NoError(t, newTestReport().WriteTerminal(&buf))
+
+	out := buf.String()
+	assert.Contains(t, out, "Kubechecks report for main..feature")
+	assert.Contains(t, out, "Affected application sets: set-a, set-b")
+	assert.Contains(t, out, "FAILURE app-a (apps/a)")
+	assert.Contains(t, out, "  SUCCESS generating diff for app (2.0s)\n      + added\n")
+	assert.Contains(t, out, "      no changes detected")
+	assert.Contains(t, out, "Result: FAILURE in 60.0s")
+}
+
+func TestFailed(t *testing.T) {
+	r := newTestReport()
+	assert.True(t, r.Failed())
+
+	r.State = "warning"
+	assert.False(t, r.Failed())
+}
+
+func TestSetStatus(t *testing.T) {
+	r := newTestReport()
+	assert.Equal(t, StatusCompleted, r.Status)
+
+	r.SetStatus(StatusSuperseded, nil)
+	assert.Equal(t, "failure", r.State, "the results of an interrupted run keep their state")
+
+	var buf bytes.Buffer
+	require.NoError(t, r.WriteJUnit(&buf))
+	var suites junitTestSuites
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &suites))
+	require.Len(t, suites.Suites, 3)
+	require.NotNil(t, suites.Suites[2].TestCases[0].Skipped)
+	assert.Equal(t, StatusSuperseded, suites.Suites[2].TestCases[0].Skipped.Message)
+
+	r.SetStatus(StatusFailed, errors.New("failed to push comment"))
+	assert.Equal(t, "error", r.State)
+	assert.True(t, r.Failed())
+
+	buf.Reset()
+	require.NoError(t, r.WriteSARIF(&buf))
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	for _, run := range log.Runs {
+		require.Len(t, run.Invocations, 1)
+		assert.False(t, run.Invocations[0].ExecutionSuccessful)
+		assert.Equal(t, "kubechecks run failed: failed to push comment", run.Invocations[0].ToolExecutionNotifications[0].Message.Text)
+	}
+}
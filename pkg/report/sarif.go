@@ -0,0 +1,187 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifTools are the tools whose findings are exported, in the order their runs appear in the log.
+var sarifTools = []struct {
+	name, informationURI string
+}{
+	{"kubeconform", "https://github.com/yannh/kubeconform"},
+	{"conftest", "https://www.conftest.dev"},
+	{"kubepug", "https://github.com/kubepug/kubepug"},
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations,omitempty"`
+	Results     []sarifResult     `json:"results"`
+}
+
+// sarifInvocation tells whether the tool ran to completion, as the results of a run that did not are incomplete.
+type sarifInvocation struct {
+	ExecutionSuccessful        bool                `json:"executionSuccessful"`
+	ToolExecutionNotifications []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations,omitempty"`
+	Properties sarifProperties `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifProperties struct {
+	Application string `json:"application"`
+	Resource    string `json:"resource,omitempty"`
+}
+
+// sarifLevel maps a finding level onto the levels defined by SARIF.
+func sarifLevel(level string) string {
+	switch level {
+	case "failure", "error", "panic":
+		return "error"
+	case "warning":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// WriteSARIF writes the kubeconform, conftest and kubepug findings as a SARIF 2.1.0 log, with one
+// run per tool. Findings point at the source path of the app whose manifests they were found in. When the kubechecks
+// run did not complete, every run records an unsuccessful invocation.
+func (r *Report) WriteSARIF(w io.Writer) error {
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    make([]sarifRun, 0, len(sarifTools)),
+	}
+
+	for _, tool := range sarifTools {
+		run := sarifRun{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           tool.name,
+				InformationURI: tool.informationURI,
+				Rules:          []sarifRule{},
+			}},
+			Results: []sarifResult{},
+		}
+
+		rules := make(map[string]struct{})
+		for _, app := range r.Applications {
+			for _, check := range app.Checks {
+				for _, finding := range check.Findings {
+					if finding.Tool != tool.name {
+						continue
+					}
+
+					rules[finding.RuleID] = struct{}{}
+					run.Results = append(run.Results, newSarifResult(app, finding))
+				}
+			}
+		}
+
+		for id := range rules {
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id})
+		}
+		sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+			return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+		})
+
+		if r.Status != "" && r.Status != StatusCompleted {
+			notification := sarifNotification{Level: "note", Message: sarifMessage{Text: "kubechecks run " + r.Status}}
+			if r.Status == StatusFailed {
+				notification = sarifNotification{Level: "error", Message: sarifMessage{Text: "kubechecks run failed: " + r.Error}}
+			}
+			run.Invocations = []sarifInvocation{{ToolExecutionNotifications: []sarifNotification{notification}}}
+		}
+
+		log.Runs = append(log.Runs, run)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return errors.Wrap(encoder.Encode(log), "failed to encode sarif log")
+}
+
+func newSarifResult(app Application, finding Finding) sarifResult {
+	text := fmt.Sprintf("%s: %s", app.Name, finding.Message)
+	if finding.Resource != "" {
+		text = fmt.Sprintf("%s (%s): %s", app.Name, finding.Resource, finding.Message)
+	}
+
+	result := sarifResult{
+		RuleID:  finding.RuleID,
+		Level:   sarifLevel(finding.Level),
+		Message: sarifMessage{Text: text},
+		Properties: sarifProperties{
+			Application: app.Name,
+			Resource:    finding.Resource,
+		},
+	}
+
+	if app.Path != "" {
+		result.Locations = []sarifLocation{{
+			PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{URI: app.Path},
+			},
+		}}
+	}
+
+	return result
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var stateColors = map[string]*color.Color{
+	"success": color.New(color.FgGreen),
+	"skip":    color.New(color.FgHiBlack),
+	"running": color.New(color.FgCyan),
+	"warning": color.New(color.FgYellow),
+	"failure": color.New(color.FgRed, color.Bold),
+	"error":   color.New(color.FgRed, color.Bold),
+	"panic":   color.New(color.FgMagenta, color.Bold),
+}
+
+func colorState(state string) string {
+	label := fmt.Sprintf("%-7s", strings.ToUpper(state))
+	if c, ok := stateColors[state]; ok {
+		return c.Sprint(label)
+	}
+	return label
+}
+
+// WriteTerminal writes a human-readable summary of the report, meant to be printed to a terminal.
+// Colors are disabled automatically when the output is not a terminal, or when NO_COLOR is set.
+func (r *Report) WriteTerminal(w io.Writer) error {
+	bold := color.New(color.Bold)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s..%s\n", bold.Sprint("Kubechecks report for"), r.PullRequest.BaseRef, r.PullRequest.HeadRef)
+
+	if len(r.AffectedApplicationSets) > 0 {
+		fmt.Fprintf(&b, "Affected application sets: %s\n", strings.Join(r.AffectedApplicationSets, ", "))
+	}
+
+	if len(r.Applications) == 0 {
+		b.WriteString("\nNo affected applications.\n")
+	}
+
+	for _, app := range r.Applications {
+		fmt.Fprintf(&b, "\n%s %s", colorState(app.State), bold.Sprint(app.Name))
+		if app.Path != "" {
+			fmt.Fprintf(&b, " (%s)", app.Path)
+		}
+		if app.Parent != "" {
+			fmt.Fprintf(&b, " rendered by %s", app.Parent)
+		}
+		b.WriteString("\n")
+
+		for _, check := range app.Checks {
+			fmt.Fprintf(&b, "  %s %s (%.1fs)\n", colorState(check.State), check.Name, check.DurationSeconds)
+			if check.NoChangesDetected {
+				b.WriteString("      no changes detected\n")
+				continue
+			}
+			if details := strings.TrimSpace(check.Details); details != "" {
+				for _, line := range strings.Split(details, "\n") {
+					fmt.Fprintf(&b, "      %s\n", line)
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%s %s in %.1fs\n", bold.Sprint("Result:"), colorState(r.State), r.DurationSeconds)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Failed returns true if any check that detected changes failed, errored or panicked.
+func (r *Report) Failed() bool {
+	switch r.State {
+	case "failure", "error", "panic":
+		return true
+	default:
+		return false
+	}
+}
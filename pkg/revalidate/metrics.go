@@ -0,0 +1,30 @@
+package revalidate
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	trackedPullRequests = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "kubechecks",
+			Subsystem: "revalidation",
+			Name:      "tracked_pull_requests",
+			Help:      "Number of pull requests that are checked again when their base or the live state of their apps changes",
+		},
+	)
+	revalidationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "kubechecks",
+			Subsystem: "revalidation",
+			Name:      "runs_total",
+			Help:      "Number of pull requests that were checked again because their report got stale, by reason",
+		},
+		[]string{"reason"},
+	)
+)
+
+func init() {
+	r := prometheus.DefaultRegisterer
+
+	r.MustRegister(trackedPullRequests)
+	r.MustRegister(revalidationsTotal)
+}
@@ -0,0 +1,422 @@
+// Package revalidate checks open pull requests again when the reports posted on them got stale, because their base
+// branch moved on in a way that affects their apps, or because the live state of their apps drifted.
+package revalidate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+var tracer = otel.Tracer("pkg/revalidate")
+
+// Reasons a pull request is checked again, as recorded by the metrics.
+const (
+	reasonBase      = "base"
+	reasonLiveState = "live_state"
+)
+
+// Config configures the scheduler.
+type Config struct {
+	// Interval is how often base branches and live states are compared to the last check.
+	Interval time.Duration
+	// LiveState checks pull requests again when the live state of one of their apps changed, not only their base.
+	LiveState bool
+	// MaxAge is how long after its last check a pull request is no longer watched, e.g. because it was abandoned.
+	MaxAge time.Duration
+}
+
+// Backend is what the scheduler needs from the rest of kubechecks.
+type Backend interface {
+	// BaseHead returns the commit the base branch of a pull request points at.
+	BaseHead(ctx context.Context, pr vcs.PullRequest) (string, error)
+	// AffectedApps returns the qualified names of the apps that the changes between two commits of the base branch
+	// of a pull request affect.
+	AffectedApps(ctx context.Context, pr vcs.PullRequest, from, to string) ([]string, error)
+	// LiveState identifies the live state of an app, so that changes can be noticed.
+	LiveState(ctx context.Context, app v1alpha1.Application) (string, error)
+	// Reload returns the current state of a pull request.
+	Reload(ctx context.Context, pr vcs.PullRequest) (vcs.PullRequest, error)
+	// UpdateMessage replaces the content of a comment.
+	UpdateMessage(ctx context.Context, note *msg.Message, comment string) error
+	// Enqueue queues a check of a pull request.
+	Enqueue(ctx context.Context, pr vcs.PullRequest) error
+	// Prune drops what was kept for base branches that none of the watched pull requests use anymore.
+	Prune(watched []vcs.PullRequest)
+}
+
+// Baseline is what a check saw of the inputs that are watched, so that changes made while and after it ran are
+// noticed.
+type Baseline struct {
+	// BaseHead is the commit the base branch pointed at when the check started.
+	BaseHead string
+	// LiveState identifies the live state of each app when it was checked, keyed by the qualified name of the app.
+	LiveState map[string]string
+}
+
+// tracked is a pull request whose last check completed.
+type tracked struct {
+	pr        vcs.PullRequest
+	note      *msg.Message
+	comment   string
+	apps      []v1alpha1.Application
+	checkedAt time.Time
+
+	// baseHead and liveState start out as what the check saw, and are only used by scans afterwards. What the check
+	// could not tell is taken from the first scan that can.
+	baseHead  string
+	liveState map[string]string
+}
+
+// Scheduler watches the pull requests that this replica checked, and queues them again when their report got stale.
+type Scheduler struct {
+	cfg Config
+
+	lock    sync.Mutex
+	prs     map[string]*tracked
+	backend Backend
+}
+
+func New(cfg Config) *Scheduler {
+	return &Scheduler{
+		cfg: cfg,
+		prs: make(map[string]*tracked),
+	}
+}
+
+func key(pr vcs.PullRequest) string {
+	return fmt.Sprintf("%s#%d", pr.FullName, pr.CheckID)
+}
+
+// BaseHead returns the commit the base branch of a pull request points at, for the baseline of a check. It returns ""
+// when it can't tell, e.g. before the scheduler started.
+func (s *Scheduler) BaseHead(ctx context.Context, pr vcs.PullRequest) string {
+	backend := s.getBackend()
+	if backend == nil {
+		return ""
+	}
+
+	head, err := backend.BaseHead(ctx, pr)
+	if err != nil {
+		log.Warn().Err(err).Str("pr", key(pr)).Msg("failed to get the head of the base branch")
+		return ""
+	}
+	return head
+}
+
+// LiveState identifies the live state of an app, for the baseline of a check. It returns "" when live states are not
+// watched, or it can't tell.
+func (s *Scheduler) LiveState(ctx context.Context, app v1alpha1.Application) string {
+	backend := s.getBackend()
+	if backend == nil || !s.cfg.LiveState {
+		return ""
+	}
+
+	version, err := backend.LiveState(ctx, app)
+	if err != nil {
+		log.Warn().Err(err).Str("app", pkg.QualifiedName(app.ObjectMeta)).Msg("failed to get the live state of app")
+		return ""
+	}
+	return version
+}
+
+func (s *Scheduler) getBackend() Backend {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.backend
+}
+
+// Track watches a pull request whose check completed. The comment is the report that was posted in note, apps are
+// the apps it checked, and baseline is what the check saw of them.
+func (s *Scheduler) Track(pr vcs.PullRequest, note *msg.Message, comment string, apps []v1alpha1.Application, baseline Baseline) {
+	if note == nil || len(apps) == 0 {
+		return
+	}
+
+	liveState := make(map[string]string, len(apps))
+	for _, app := range apps {
+		name := pkg.QualifiedName(app.ObjectMeta)
+		if version := baseline.LiveState[name]; version != "" {
+			liveState[name] = version
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.prs[key(pr)] = &tracked{
+		pr:        pr,
+		note:      note,
+		comment:   comment,
+		apps:      apps,
+		checkedAt: time.Now(),
+		baseHead:  baseline.BaseHead,
+		liveState: liveState,
+	}
+	trackedPullRequests.Set(float64(len(s.prs)))
+}
+
+// Forget stops watching a pull request, e.g. because it is being checked again.
+func (s *Scheduler) Forget(pr vcs.PullRequest) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.prs, key(pr))
+	trackedPullRequests.Set(float64(len(s.prs)))
+}
+
+// remove stops watching a pull request, unless it was tracked again since t was taken.
+func (s *Scheduler) remove(t *tracked) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.prs[key(t.pr)] == t {
+		delete(s.prs, key(t.pr))
+	}
+	trackedPullRequests.Set(float64(len(s.prs)))
+}
+
+// watched returns the pull requests that are watched, and stops watching the ones that were checked too long ago.
+func (s *Scheduler) watched() []*tracked {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var prs []*tracked
+	for k, t := range s.prs {
+		if s.cfg.MaxAge > 0 && time.Since(t.checkedAt) > s.cfg.MaxAge {
+			delete(s.prs, k)
+			continue
+		}
+		prs = append(prs, t)
+	}
+	trackedPullRequests.Set(float64(len(s.prs)))
+
+	sort.Slice(prs, func(i, j int) bool { return key(prs[i].pr) < key(prs[j].pr) })
+	return prs
+}
+
+// Start scans the watched pull requests with backend every interval, until ctx is done. Baselines of checks are only
+// taken once it started.
+func (s *Scheduler) Start(ctx context.Context, backend Backend) {
+	s.lock.Lock()
+	s.backend = backend
+	s.lock.Unlock()
+
+	go s.run(ctx, backend)
+}
+
+func (s *Scheduler) run(ctx context.Context, backend Backend) {
+	log.Info().Dur("interval", s.cfg.Interval).Bool("live_state", s.cfg.LiveState).Msg("re-validating open pull requests")
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Scan(ctx, backend)
+		}
+	}
+}
+
+// baseKey identifies the base branch of a repository.
+type baseKey struct {
+	cloneURL, ref string
+}
+
+// scan holds what a single scan learned, so that each base branch and change is only looked at once.
+type scan struct {
+	backend  Backend
+	heads    map[baseKey]string
+	affected map[string][]string
+}
+
+// Scan queues a check of every watched pull request whose base branch changed in a way that affects its apps, or the
+// live state of whose apps changed, since its last check.
+func (s *Scheduler) Scan(ctx context.Context, backend Backend) {
+	ctx, span := tracer.Start(ctx, "Scan")
+	defer span.End()
+
+	sc := &scan{
+		backend:  backend,
+		heads:    make(map[baseKey]string),
+		affected: make(map[string][]string),
+	}
+
+	for _, t := range s.watched() {
+		logger := log.With().Str("pr", key(t.pr)).Logger()
+
+		var reasons, kinds []string
+		if reason := sc.baseChange(ctx, t); reason != "" {
+			reasons = append(reasons, reason)
+			kinds = append(kinds, reasonBase)
+		}
+		if s.cfg.LiveState {
+			if reason := sc.liveStateChange(ctx, t); reason != "" {
+				reasons = append(reasons, reason)
+				kinds = append(kinds, reasonLiveState)
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+
+		logger.Info().Strs("reasons", reasons).Msg("report is stale, checking again")
+		if s.revalidate(ctx, backend, t, kinds, reasons) {
+			for _, kind := range kinds {
+				revalidationsTotal.WithLabelValues(kind).Inc()
+			}
+		}
+	}
+
+	var watched []vcs.PullRequest
+	for _, t := range s.watched() {
+		watched = append(watched, t.pr)
+	}
+	backend.Prune(watched)
+}
+
+// baseChange describes how the base branch changed since the last check, if that affects the apps of the pull request.
+// Changes that don't affect them become the new baseline.
+func (sc *scan) baseChange(ctx context.Context, t *tracked) string {
+	logger := log.With().Str("pr", key(t.pr)).Str("base", t.pr.BaseRef).Logger()
+
+	bk := baseKey{t.pr.CloneURL, t.pr.BaseRef}
+	head, ok := sc.heads[bk]
+	if !ok {
+		var err error
+		if head, err = sc.backend.BaseHead(ctx, t.pr); err != nil {
+			logger.Warn().Err(err).Msg("failed to get the head of the base branch")
+		}
+		sc.heads[bk] = head
+	}
+
+	if head == "" || head == t.baseHead {
+		return ""
+	}
+	if t.baseHead == "" {
+		// the check could not tell, so the base as it is now is the baseline
+		t.baseHead = head
+		return ""
+	}
+
+	changeKey := fmt.Sprintf("%s %s %s..%s", bk.cloneURL, bk.ref, t.baseHead, head)
+	affected, ok := sc.affected[changeKey]
+	if !ok {
+		var err error
+		if affected, err = sc.backend.AffectedApps(ctx, t.pr, t.baseHead, head); err != nil {
+			logger.Warn().Err(err).Msg("failed to find the apps that changes to the base branch affect")
+			return ""
+		}
+		sc.affected[changeKey] = affected
+	}
+
+	var overlap []string
+	for _, app := range t.apps {
+		if name := pkg.QualifiedName(app.ObjectMeta); slices.Contains(affected, name) {
+			overlap = append(overlap, fmt.Sprintf("`%s`", name))
+		}
+	}
+
+	if len(overlap) == 0 {
+		t.baseHead = head
+		return ""
+	}
+
+	return fmt.Sprintf("`%s` moved from %s to %s, which affects %s", t.pr.BaseRef, shortSHA(t.baseHead), shortSHA(head), strings.Join(overlap, ", "))
+}
+
+// liveStateChange describes which apps of the pull request drifted since the last check.
+func (sc *scan) liveStateChange(ctx context.Context, t *tracked) string {
+	var changed []string
+	for _, app := range t.apps {
+		name := pkg.QualifiedName(app.ObjectMeta)
+
+		version, err := sc.backend.LiveState(ctx, app)
+		if err != nil {
+			log.Warn().Err(err).Str("pr", key(t.pr)).Str("app", name).Msg("failed to get the live state of app")
+			continue
+		}
+
+		if previous, ok := t.liveState[name]; !ok {
+			t.liveState[name] = version
+		} else if previous != version {
+			changed = append(changed, fmt.Sprintf("`%s`", name))
+		}
+	}
+
+	if len(changed) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("the live state of %s changed", strings.Join(changed, ", "))
+}
+
+// revalidate marks the report of a pull request as stale and queues a new check, and reports whether it did. Pull
+// requests that were merged or closed are no longer watched.
+func (s *Scheduler) revalidate(ctx context.Context, backend Backend, t *tracked, kinds, reasons []string) bool {
+	logger := log.With().Str("pr", key(t.pr)).Logger()
+
+	pr, err := backend.Reload(ctx, t.pr)
+	if err != nil {
+		logger.Warn().Err(err).Msg("failed to reload pull request, trying again later")
+		return false
+	}
+	if pr.Closed {
+		logger.Info().Msg("pull request was merged or closed, no longer watching it")
+		s.remove(t)
+		return false
+	}
+
+	if err = backend.UpdateMessage(ctx, t.note, staleComment(t.comment, kinds, reasons)); err != nil {
+		logger.Warn().Err(err).Msg("failed to mark report as stale")
+	}
+
+	if err = backend.Enqueue(ctx, pr); err != nil {
+		logger.Warn().Err(err).Msg("failed to queue a new check, trying again later")
+		return false
+	}
+
+	// the new check tracks the pull request again once it completes
+	s.remove(t)
+	return true
+}
+
+// staleComment puts a notice above a report whose inputs changed since it was posted.
+func staleComment(comment string, kinds, reasons []string) string {
+	what := "Base"
+	if !slices.Contains(kinds, reasonBase) {
+		what = "Live state"
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("> :warning: **%s changed since last check**, this report may be out of date. A new check is queued and replaces it when it finishes.\n", what))
+	for _, reason := range reasons {
+		sb.WriteString(fmt.Sprintf("> - %s\n", reason))
+	}
+	sb.WriteString("\n")
+	sb.WriteString(comment)
+	return sb.String()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,240 @@
+package revalidate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+type fakeBackend struct {
+	head       string
+	affected   []string
+	liveState  map[string]string
+	closed     bool
+	enqueueErr error
+
+	affectedCalls int
+	comments      []string
+	enqueued      []vcs.PullRequest
+	watched       []vcs.PullRequest
+}
+
+func (b *fakeBackend) BaseHead(context.Context, vcs.PullRequest) (string, error) {
+	return b.head, nil
+}
+
+func (b *fakeBackend) AffectedApps(context.Context, vcs.PullRequest, string, string) ([]string, error) {
+	b.affectedCalls++
+	return b.affected, nil
+}
+
+func (b *fakeBackend) LiveState(_ context.Context, app v1alpha1.Application) (string, error) {
+	return b.liveState[app.Name], nil
+}
+
+func (b *fakeBackend) Reload(_ context.Context, pr vcs.PullRequest) (vcs.PullRequest, error) {
+	pr.Closed = b.closed
+	return pr, nil
+}
+
+func (b *fakeBackend) UpdateMessage(_ context.Context, _ *msg.Message, comment string) error {
+	b.comments = append(b.comments, comment)
+	return nil
+}
+
+func (b *fakeBackend) Enqueue(_ context.Context, pr vcs.PullRequest) error {
+	if b.enqueueErr != nil {
+		return b.enqueueErr
+	}
+	b.enqueued = append(b.enqueued, pr)
+	return nil
+}
+
+func (b *fakeBackend) Prune(watched []vcs.PullRequest) {
+	b.watched = watched
+}
+
+func newApp(name string) v1alpha1.Application {
+	return v1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func newPR(checkID int) vcs.PullRequest {
+	return vcs.PullRequest{FullName: "zapier/kubechecks", CheckID: checkID, CloneURL: "https://github.com/zapier/kubechecks.git", BaseRef: "main"}
+}
+
+func (s *Scheduler) tracking(pr vcs.PullRequest) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.prs[key(pr)]
+	return ok
+}
+
+func TestScheduler_BaseChange(t *testing.T) {
+	ctx := context.Background()
+	s := New(Config{Interval: time.Minute})
+	backend := &fakeBackend{head: "aaaaaaaaaaaa"}
+
+	pr1, pr2 := newPR(1), newPR(2)
+	s.Track(pr1, msg.NewMessage("zapier/kubechecks", 1, 1, nil), "report 1", []v1alpha1.Application{newApp("api")}, Baseline{BaseHead: "aaaaaaaaaaaa"})
+	s.Track(pr2, msg.NewMessage("zapier/kubechecks", 2, 2, nil), "report 2", []v1alpha1.Application{newApp("web")}, Baseline{BaseHead: "aaaaaaaaaaaa"})
+
+	s.Scan(ctx, backend)
+	assert.Empty(t, backend.enqueued)
+	assert.Zero(t, backend.affectedCalls, "the base did not move")
+
+	// changes that affect no app of a pull request become its new baseline
+	backend.head = "bbbbbbbbbbbb"
+	backend.affected = []string{"other"}
+	s.Scan(ctx, backend)
+	assert.Empty(t, backend.enqueued)
+	assert.Equal(t, 1, backend.affectedCalls, "a change is only looked at once per scan")
+
+	backend.head = "cccccccccccc"
+	backend.affected = []string{"api"}
+	s.Scan(ctx, backend)
+	require.Len(t, backend.enqueued, 1)
+	assert.Equal(t, 1, backend.enqueued[0].CheckID)
+	require.Len(t, backend.comments, 1)
+	assert.Equal(t, "> :warning: **Base changed since last check**, this report may be out of date. A new check is queued and replaces it when it finishes.\n"+
+		"> - `main` moved from bbbbbbbb to cccccccc, which affects `api`\n\nreport 1", backend.comments[0])
+
+	assert.False(t, s.tracking(pr1), "the new check tracks it again once it completes")
+	assert.True(t, s.tracking(pr2))
+	assert.Equal(t, []vcs.PullRequest{pr2}, backend.watched, "base branches are only kept for watched pull requests")
+}
+
+func TestScheduler_BaselineOfCheck(t *testing.T) {
+	ctx := context.Background()
+	s := New(Config{Interval: time.Minute, LiveState: true})
+	backend := &fakeBackend{head: "bbbbbbbb", affected: []string{"api"}, liveState: map[string]string{"api": "2", "web": "1"}}
+
+	// the base moved and the live state of api drifted between the check and the first scan
+	apps := []v1alpha1.Application{newApp("api"), newApp("web")}
+	s.Track(newPR(1), msg.NewMessage("zapier/kubechecks", 1, 1, nil), "report", apps, Baseline{
+		BaseHead:  "aaaaaaaa",
+		LiveState: map[string]string{"api": "1", "web": "1"},
+	})
+
+	s.Scan(ctx, backend)
+	require.Len(t, backend.enqueued, 1)
+	assert.Contains(t, backend.comments[0], "> - `main` moved from aaaaaaaa to bbbbbbbb, which affects `api`\n")
+	assert.Contains(t, backend.comments[0], "> - the live state of `api` changed\n")
+}
+
+func TestScheduler_UnknownBaseline(t *testing.T) {
+	ctx := context.Background()
+	s := New(Config{Interval: time.Minute, LiveState: true})
+	backend := &fakeBackend{head: "aaaaaaaa", affected: []string{"api"}, liveState: map[string]string{"api": "1"}}
+
+	// what the check could not tell is taken from the first scan
+	s.Track(newPR(1), msg.NewMessage("zapier/kubechecks", 1, 1, nil), "report", []v1alpha1.Application{newApp("api")}, Baseline{})
+	s.Scan(ctx, backend)
+	assert.Empty(t, backend.enqueued)
+
+	backend.head = "bbbbbbbb"
+	s.Scan(ctx, backend)
+	assert.Len(t, backend.enqueued, 1)
+}
+
+func TestScheduler_Baseline(t *testing.T) {
+	ctx := context.Background()
+	s := New(Config{Interval: time.Minute, LiveState: true})
+	assert.Empty(t, s.BaseHead(ctx, newPR(1)), "no baselines before the scheduler started")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s.Start(ctx, &fakeBackend{head: "aaaaaaaa", liveState: map[string]string{"api": "1"}})
+	assert.Equal(t, "aaaaaaaa", s.BaseHead(ctx, newPR(1)))
+	assert.Equal(t, "1", s.LiveState(ctx, newApp("api")))
+
+	s = New(Config{Interval: time.Minute})
+	s.Start(ctx, &fakeBackend{liveState: map[string]string{"api": "1"}})
+	assert.Empty(t, s.LiveState(ctx, newApp("api")), "live states are not watched")
+}
+
+func TestScheduler_LiveStateChange(t *testing.T) {
+	ctx := context.Background()
+	s := New(Config{Interval: time.Minute, LiveState: true})
+	backend := &fakeBackend{head: "aaaaaaaa", liveState: map[string]string{"api": "1", "web": "1"}}
+
+	pr := newPR(1)
+	s.Track(pr, msg.NewMessage("zapier/kubechecks", 1, 1, nil), "report", []v1alpha1.Application{newApp("api"), newApp("web")}, Baseline{
+		BaseHead:  "aaaaaaaa",
+		LiveState: map[string]string{"api": "1", "web": "1"},
+	})
+
+	s.Scan(ctx, backend)
+	assert.Empty(t, backend.enqueued)
+
+	backend.liveState["web"] = "2"
+	backend.enqueueErr = errors.New("queue is full")
+	s.Scan(ctx, backend)
+	assert.Empty(t, backend.enqueued)
+	assert.True(t, s.tracking(pr), "tried again on the next scan")
+
+	backend.enqueueErr = nil
+	s.Scan(ctx, backend)
+	require.Len(t, backend.enqueued, 1)
+	assert.Contains(t, backend.comments[len(backend.comments)-1], "**Live state changed since last check**")
+	assert.Contains(t, backend.comments[len(backend.comments)-1], "> - the live state of `web` changed\n")
+	assert.False(t, s.tracking(pr))
+}
+
+func TestScheduler_LiveStateDisabled(t *testing.T) {
+	ctx := context.Background()
+	s := New(Config{Interval: time.Minute})
+	backend := &fakeBackend{head: "aaaaaaaa", liveState: map[string]string{"api": "2"}}
+
+	s.Track(newPR(1), msg.NewMessage("zapier/kubechecks", 1, 1, nil), "report", []v1alpha1.Application{newApp("api")}, Baseline{
+		BaseHead:  "aaaaaaaa",
+		LiveState: map[string]string{"api": "1"},
+	})
+	s.Scan(ctx, backend)
+	assert.Empty(t, backend.enqueued)
+}
+
+func TestScheduler_Closed(t *testing.T) {
+	ctx := context.Background()
+	s := New(Config{Interval: time.Minute})
+	backend := &fakeBackend{head: "bbbbbbbb", affected: []string{"api"}, closed: true}
+
+	pr := newPR(1)
+	s.Track(pr, msg.NewMessage("zapier/kubechecks", 1, 1, nil), "report", []v1alpha1.Application{newApp("api")}, Baseline{BaseHead: "aaaaaaaa"})
+	s.Scan(ctx, backend)
+	assert.Empty(t, backend.enqueued)
+	assert.Empty(t, backend.comments)
+	assert.False(t, s.tracking(pr))
+}
+
+func TestScheduler_Untrack(t *testing.T) {
+	s := New(Config{Interval: time.Minute, MaxAge: time.Hour})
+	note := msg.NewMessage("zapier/kubechecks", 1, 1, nil)
+	apps := []v1alpha1.Application{newApp("api")}
+
+	s.Track(newPR(1), nil, "report", apps, Baseline{})
+	assert.False(t, s.tracking(newPR(1)), "nothing to mark as stale without a comment")
+
+	s.Track(newPR(1), note, "report", nil, Baseline{})
+	assert.False(t, s.tracking(newPR(1)), "nothing to re-validate without apps")
+
+	s.Track(newPR(1), note, "report", apps, Baseline{})
+	s.Forget(newPR(1))
+	assert.False(t, s.tracking(newPR(1)))
+
+	s.Track(newPR(1), note, "report", apps, Baseline{})
+	s.Track(newPR(2), note, "report", apps, Baseline{})
+	s.prs[key(newPR(1))].checkedAt = time.Now().Add(-2 * time.Hour)
+	assert.Len(t, s.watched(), 1)
+	assert.False(t, s.tracking(newPR(1)), "pull requests checked too long ago are no longer watched")
+	assert.True(t, s.tracking(newPR(2)))
+}
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zapier/kubechecks/pkg/checks"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/events"
+	"github.com/zapier/kubechecks/pkg/git"
+	"github.com/zapier/kubechecks/pkg/queue"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+const KubeChecksAdminPathPrefix = "/admin"
+
+// Names of the caches the admin API inspects and purges.
+const (
+	cacheArchive  = "archive"
+	cacheRepo     = "repo"
+	cacheManifest = "manifest"
+	cacheCheck    = "check"
+)
+
+// RunRegistry tracks the check runs this replica is processing, so that the admin API can list them.
+type RunRegistry struct {
+	mu   sync.Mutex
+	runs map[*events.CheckEvent]struct{}
+}
+
+func NewRunRegistry() *RunRegistry {
+	return &RunRegistry{runs: make(map[*events.CheckEvent]struct{})}
+}
+
+// ProcessCheckEvent processes a check request like the package's ProcessCheckEvent, while tracking its run.
+func (r *RunRegistry) ProcessCheckEvent(ctx context.Context, pr vcs.PullRequest, ctr container.Container, processors []checks.ProcessorEntry, aiReviewChecker queue.AIReviewChecker) error {
+	return processCheckEvent(ctx, pr, ctr, processors, aiReviewChecker, r)
+}
+
+func (r *RunRegistry) add(ce *events.CheckEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[ce] = struct{}{}
+}
+
+func (r *RunRegistry) remove(ce *events.CheckEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.runs, ce)
+}
+
+// progress returns the progress of every run, the oldest first.
+func (r *RunRegistry) progress() []events.Progress {
+	r.mu.Lock()
+	progress := make([]events.Progress, 0, len(r.runs))
+	for ce := range r.runs {
+		progress = append(progress, ce.Progress())
+	}
+	r.mu.Unlock()
+
+	sort.Slice(progress, func(i, j int) bool {
+		return progress[i].StartedAt.Before(progress[j].StartedAt)
+	})
+	return progress
+}
+
+// AdminHandler serves the admin API, which lists, cancels and re-runs checks, and inspects and purges caches.
+type AdminHandler struct {
+	ctr             container.Container
+	processors      []checks.ProcessorEntry
+	aiReviewChecker queue.AIReviewChecker
+	queueManager    queue.Queue
+	runs            *RunRegistry
+}
+
+func NewAdminHandler(ctr container.Container, processors []checks.ProcessorEntry, aiReviewChecker queue.AIReviewChecker, queueManager queue.Queue, runs *RunRegistry) *AdminHandler {
+	return &AdminHandler{
+		ctr:             ctr,
+		processors:      processors,
+		aiReviewChecker: aiReviewChecker,
+		queueManager:    queueManager,
+		runs:            runs,
+	}
+}
+
+// AttachHandlers adds the admin routes to grp, behind bearer token authentication.
+func (h *AdminHandler) AttachHandlers(grp *echo.Group) {
+	grp.Use(middleware.KeyAuth(func(key string, _ echo.Context) (bool, error) {
+		return subtle.ConstantTimeCompare([]byte(key), []byte(h.ctr.Config.AdminToken)) == 1, nil
+	}))
+
+	grp.GET("/queues", h.listQueues)
+	grp.GET("/checks", h.listChecks)
+	grp.POST("/checks/cancel", h.cancelCheck)
+	grp.POST("/checks/rerun", h.rerunCheck)
+	grp.GET("/caches", h.listCaches)
+	grp.DELETE("/caches/:name", h.purgeCache)
+}
+
+// statsQueue is a queue that reports statistics about itself.
+type statsQueue interface {
+	GetStats() map[string]interface{}
+}
+
+type queuesResponse struct {
+	Stats map[string]interface{} `json:"stats,omitempty"`
+	Order []queue.QueuedRequest  `json:"order,omitempty"`
+}
+
+func (h *AdminHandler) listQueues(c echo.Context) error {
+	var response queuesResponse
+	if stats, ok := h.queueManager.(statsQueue); ok {
+		response.Stats = stats.GetStats()
+	}
+	if ordered, ok := h.queueManager.(queue.OrderedQueue); ok {
+		response.Order = ordered.Order()
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// listChecks returns the progress of the checks this replica is running.
+func (h *AdminHandler) listChecks(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.runs.progress())
+}
+
+// pullRequestBody names a pull request the way the process command does, as owner/repo#id.
+type pullRequestBody struct {
+	PR string `json:"pr"`
+}
+
+type cancelResponse struct {
+	PR        string `json:"pr"`
+	Cancelled bool   `json:"cancelled"`
+}
+
+func (h *AdminHandler) cancelCheck(c echo.Context) error {
+	ctx := context.Background()
+
+	cancellable, ok := h.queueManager.(queue.CancellableQueue)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotImplemented, "the queue does not support cancelling checks")
+	}
+
+	ref, err := pullRequestRef(c)
+	if err != nil {
+		return err
+	}
+
+	pr, err := h.ctr.VcsClient.LoadHook(ctx, ref)
+	if err != nil {
+		log.Warn().Err(err).Str("pr", ref).Msg("failed to load pull request to cancel")
+		return echo.NewHTTPError(http.StatusNotFound, "failed to load pull request")
+	}
+
+	cancelled, err := cancellable.Cancel(ctx, pr)
+	if err != nil {
+		log.Error().Err(err).Str("pr", ref).Msg("failed to cancel checks")
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to cancel checks")
+	}
+
+	log.Info().Str("pr", ref).Bool("cancelled", cancelled).Msg("cancelled checks through the admin api")
+	return c.JSON(http.StatusOK, cancelResponse{PR: ref, Cancelled: cancelled})
+}
+
+// rerunCheck queues a check of a pull request, like a replan comment would.
+func (h *AdminHandler) rerunCheck(c echo.Context) error {
+	ctx := context.Background()
+
+	ref, err := pullRequestRef(c)
+	if err != nil {
+		return err
+	}
+
+	pr, err := h.ctr.VcsClient.LoadHook(ctx, ref)
+	if err != nil {
+		log.Warn().Err(err).Str("pr", ref).Msg("failed to load pull request to re-run")
+		return echo.NewHTTPError(http.StatusNotFound, "failed to load pull request")
+	}
+
+	if err = h.queueManager.Enqueue(ctx, queue.EnqueueParams{
+		PullRequest:     pr,
+		Container:       h.ctr,
+		Processors:      h.processors,
+		AIReviewChecker: h.aiReviewChecker,
+	}); err != nil {
+		log.Warn().Err(err).Str("pr", ref).Msg("failed to queue re-run")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	log.Info().Str("pr", ref).Str("sha", pr.SHA).Msg("queued re-run through the admin api")
+	return c.JSON(http.StatusAccepted, queue.QueuedRequest{
+		Repo:     pr.FullName,
+		CheckID:  pr.CheckID,
+		SHA:      pr.SHA,
+		Priority: queue.PriorityOf(pr, h.ctr.Config.RepoPriorityLevels, h.ctr.Config.LabelPriorities).String(),
+		State:    queue.RequestQueued,
+	})
+}
+
+func pullRequestRef(c echo.Context) (string, error) {
+	var body pullRequestBody
+	if err := c.Bind(&body); err != nil {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "invalid request body")
+	}
+
+	if !strings.Contains(body.PR, "#") {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "pr must look like owner/repo#id")
+	}
+	return body.PR, nil
+}
+
+// cacheInfo describes the contents of a cache.
+type cacheInfo struct {
+	Enabled   bool  `json:"enabled"`
+	Entries   int   `json:"entries"`
+	SizeBytes int64 `json:"size_bytes,omitempty"`
+	// Items lists the entries of caches that hold a few large ones, like repositories.
+	Items any `json:"items,omitempty"`
+}
+
+func (h *AdminHandler) listCaches(c echo.Context) error {
+	caches := map[string]cacheInfo{
+		cacheArchive:  {},
+		cacheRepo:     {},
+		cacheManifest: {},
+		cacheCheck:    {},
+	}
+
+	if h.ctr.ArchiveManager != nil {
+		items := h.ctr.ArchiveManager.CacheEntries()
+		caches[cacheArchive] = cacheInfo{Enabled: true, Entries: len(items), Items: items}
+	}
+
+	if repos, ok := h.ctr.RepoManager.(*git.PersistentRepoManager); ok {
+		items := repos.Entries()
+		caches[cacheRepo] = cacheInfo{Enabled: true, Entries: len(items), Items: sanitizeRepoURLs(items)}
+	}
+
+	if h.ctr.ManifestCache != nil {
+		entries, size, err := h.ctr.ManifestCache.Stats()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to inspect manifest cache")
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to inspect manifest cache")
+		}
+		caches[cacheManifest] = cacheInfo{Enabled: true, Entries: entries, SizeBytes: size}
+	}
+
+	if h.ctr.CheckCache != nil {
+		entries, size, err := h.ctr.CheckCache.Stats()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to inspect check cache")
+			return echo.NewHTTPError(http.StatusInternalServerError, "failed to inspect check cache")
+		}
+		caches[cacheCheck] = cacheInfo{Enabled: true, Entries: entries, SizeBytes: size}
+	}
+
+	return c.JSON(http.StatusOK, caches)
+}
+
+type purgeResponse struct {
+	Cache   string `json:"cache"`
+	Removed int    `json:"removed"`
+}
+
+// purgeCache removes the entries of a cache. Archives and repositories that checks are using are kept.
+func (h *AdminHandler) purgeCache(c echo.Context) error {
+	name := c.Param("name")
+	removed := 0
+
+	switch name {
+	case cacheArchive:
+		if h.ctr.ArchiveManager != nil {
+			removed = h.ctr.ArchiveManager.PurgeCache()
+		}
+	case cacheRepo:
+		if repos, ok := h.ctr.RepoManager.(*git.PersistentRepoManager); ok {
+			removed = repos.Purge()
+		}
+	case cacheManifest:
+		if h.ctr.ManifestCache != nil {
+			n, err := h.ctr.ManifestCache.Purge()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to purge manifest cache")
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to purge manifest cache")
+			}
+			removed = n
+		}
+	case cacheCheck:
+		if h.ctr.CheckCache != nil {
+			n, err := h.ctr.CheckCache.Purge()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to purge check cache")
+				return echo.NewHTTPError(http.StatusInternalServerError, "failed to purge check cache")
+			}
+			removed = n
+		}
+	default:
+		return echo.NewHTTPError(http.StatusNotFound, "unknown cache")
+	}
+
+	log.Info().Str("cache", name).Int("removed", removed).Msg("purged cache through the admin api")
+	return c.JSON(http.StatusOK, purgeResponse{Cache: name, Removed: removed})
+}
+
+// sanitizeRepoURLs removes credentials from the clone URLs of repositories.
+func sanitizeRepoURLs(repos []git.CachedRepo) []git.CachedRepo {
+	for i, repo := range repos {
+		if u, err := url.Parse(repo.URL); err == nil && u.User != nil {
+			u.User = url.User(u.User.Username())
+			repos[i].URL = u.String()
+		}
+	}
+	return repos
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	vcsmocks "github.com/zapier/kubechecks/mocks/vcs/mocks"
+	"github.com/zapier/kubechecks/pkg/checkcache"
+	"github.com/zapier/kubechecks/pkg/checks"
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/manifestcache"
+	"github.com/zapier/kubechecks/pkg/queue"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func TestAdminHandler(t *testing.T) {
+	pr := vcs.PullRequest{
+		CloneURL: "https://github.com/zapier/kubechecks.git",
+		FullName: "zapier/kubechecks",
+		CheckID:  1,
+		SHA:      "sha-1",
+	}
+
+	vcsClient := new(vcsmocks.MockClient)
+	vcsClient.EXPECT().LoadHook(mock.Anything, "zapier/kubechecks#1").Return(pr, nil)
+
+	checkCache, err := checkcache.NewStore(checkcache.Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, checkCache.Put(pr, "app", checkcache.Entry{Fingerprint: "fingerprint"}))
+
+	manifestCache, err := manifestcache.NewCache(manifestcache.Config{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, manifestCache.Put("key", []string{"manifest"}))
+
+	started := make(chan string, 1)
+	qm := queue.NewQueueManager(queue.Config{QueueSize: 10}, func(ctx context.Context, pr vcs.PullRequest, _ container.Container, _ []checks.ProcessorEntry, _ queue.AIReviewChecker) error {
+		started <- pr.SHA
+		<-ctx.Done()
+		return nil
+	})
+	t.Cleanup(func() { _ = qm.Shutdown(context.Background()) })
+
+	ctr := container.Container{
+		Config:        config.ServerConfig{AdminToken: "secret"},
+		VcsClient:     vcsClient,
+		CheckCache:    checkCache,
+		ManifestCache: manifestCache,
+	}
+	e := echo.New()
+	NewAdminHandler(ctr, nil, nil, qm, NewRunRegistry()).AttachHandlers(e.Group("/admin"))
+
+	call := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if token != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("authentication", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/admin/checks", "", "").Code, "the token is missing")
+		assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/admin/checks", "wrong", "").Code)
+		assert.Equal(t, http.StatusOK, call(http.MethodGet, "/admin/checks", "secret", "").Code)
+	})
+
+	t.Run("rerun and cancel", func(t *testing.T) {
+		rec := call(http.MethodPost, "/admin/checks/rerun", "secret", `{"pr":"zapier/kubechecks#1"}`)
+		require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+		assert.Equal(t, "sha-1", <-started)
+
+		rec = call(http.MethodGet, "/admin/queues", "secret", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var queues struct {
+			Order []queue.QueuedRequest `json:"order"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &queues))
+		require.Len(t, queues.Order, 1)
+		assert.Equal(t, queue.RequestRunning, queues.Order[0].State)
+
+		rec = call(http.MethodPost, "/admin/checks/cancel", "secret", `{"pr":"zapier/kubechecks#1"}`)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		assert.JSONEq(t, `{"pr":"zapier/kubechecks#1","cancelled":true}`, rec.Body.String())
+
+		assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/admin/checks/cancel", "secret", `{"pr":"zapier/kubechecks"}`).Code)
+	})
+
+	t.Run("caches", func(t *testing.T) {
+		rec := call(http.MethodGet, "/admin/caches", "secret", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		var caches map[string]cacheInfo
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &caches))
+		assert.Equal(t, 1, caches[cacheCheck].Entries)
+		assert.Equal(t, 1, caches[cacheManifest].Entries)
+		assert.False(t, caches[cacheRepo].Enabled)
+
+		rec = call(http.MethodDelete, "/admin/caches/check", "secret", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"cache":"check","removed":1}`, rec.Body.String())
+
+		rec = call(http.MethodDelete, "/admin/caches/manifest", "secret", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"cache":"manifest","removed":1}`, rec.Body.String())
+
+		assert.Equal(t, http.StatusNotFound, call(http.MethodDelete, "/admin/caches/unknown", "secret", "").Code)
+	})
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zapier/kubechecks/pkg/history"
+)
+
+//go:embed templates/dashboard.html
+var dashboardFS embed.FS
+
+var dashboardTemplates = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"shortSHA": func(sha string) string {
+		if len(sha) > 8 {
+			return sha[:8]
+		}
+		return sha
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"seconds": func(s float64) string {
+		return time.Duration(s * float64(time.Second)).Round(100 * time.Millisecond).String()
+	},
+	"timestamp": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04:05 UTC")
+	},
+	"percent": func(part, total int) string {
+		if total == 0 {
+			return "0%"
+		}
+		return strconv.Itoa(part*100/total) + "%"
+	},
+}).ParseFS(dashboardFS, "templates/dashboard.html"))
+
+const (
+	// dashboardRunLimit is the most runs the list of runs shows.
+	dashboardRunLimit = 100
+	// dashboardTrendDays is how many days of runs the trends cover, unless asked otherwise.
+	dashboardTrendDays = 30
+)
+
+// DashboardHandler serves the pages that show the history of runs.
+type DashboardHandler struct {
+	store *history.Store
+	// base is the path the dashboard is served under, which its links start with.
+	base string
+}
+
+func NewDashboardHandler(store *history.Store, base string) *DashboardHandler {
+	return &DashboardHandler{store: store, base: base}
+}
+
+// AttachHandlers adds the dashboard routes to grp.
+func (h *DashboardHandler) AttachHandlers(grp *echo.Group) {
+	grp.GET("", h.listRuns)
+	grp.GET("/", h.listRuns)
+	grp.GET("/runs/:id", h.showRun)
+	grp.GET("/trends", h.showTrends)
+}
+
+type runsPage struct {
+	Base       string
+	Repository string
+	CheckID    int
+	Runs       []history.Run
+}
+
+// listRuns shows the most recent runs, optionally of a single repository or pull request.
+func (h *DashboardHandler) listRuns(c echo.Context) error {
+	filter := history.Filter{Repository: c.QueryParam("repo"), Limit: dashboardRunLimit}
+	if pr := c.QueryParam("pr"); pr != "" {
+		checkID, err := strconv.Atoi(pr)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "pr must be a number")
+		}
+		filter.CheckID = checkID
+	}
+
+	runs, err := h.store.List(filter)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to list runs")
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list runs")
+	}
+
+	return h.render(c, "runs", runsPage{Base: h.base, Repository: filter.Repository, CheckID: filter.CheckID, Runs: runs})
+}
+
+type runPage struct {
+	Base string
+	Run  history.Run
+}
+
+func (h *DashboardHandler) showRun(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid run id")
+	}
+
+	run, ok, err := h.store.Get(id)
+	if err != nil {
+		log.Error().Err(err).Uint64("id", id).Msg("failed to get run")
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to get run")
+	}
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "run not found, it may have expired")
+	}
+
+	return h.render(c, "run", runPage{Base: h.base, Run: run})
+}
+
+type trendsPage struct {
+	Base       string
+	Repository string
+	Days       int
+	Trends     []history.CheckTrend
+}
+
+// showTrends shows the checks that failed most often over the last days.
+func (h *DashboardHandler) showTrends(c echo.Context) error {
+	days := dashboardTrendDays
+	if value := c.QueryParam("days"); value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil || days <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "days must be a positive number")
+		}
+	}
+
+	repo := c.QueryParam("repo")
+	trends, err := h.store.Trends(history.Filter{
+		Repository: repo,
+		Since:      time.Now().AddDate(0, 0, -days),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to compute trends")
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to compute trends")
+	}
+
+	return h.render(c, "trends", trendsPage{Base: h.base, Repository: repo, Days: days, Trends: trends})
+}
+
+func (h *DashboardHandler) render(c echo.Context, name string, data any) error {
+	var page bytes.Buffer
+	if err := dashboardTemplates.ExecuteTemplate(&page, name, data); err != nil {
+		log.Error().Err(err).Str("page", name).Msg("failed to render dashboard page")
+		return echo.NewHTTPError(http.StatusInternalServerError, "failed to render page")
+	}
+
+	return c.HTMLBlob(http.StatusOK, page.Bytes())
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zapier/kubechecks/pkg/history"
+)
+
+func TestDashboardHandler(t *testing.T) {
+	store, err := history.NewStore(history.Config{Path: filepath.Join(t.TempDir(), "history.db")})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+
+	record := func(repo string, number int, title string, apps ...history.App) uint64 {
+		id, err := store.Start(history.Run{Repository: repo, Number: number, Title: title, SHA: "0123456789abcdef", StartedAt: time.Now()})
+		require.NoError(t, err)
+		require.NoError(t, store.Finish(history.Run{
+			ID:         id,
+			Status:     history.StatusCompleted,
+			CommentURL: "https://github.com/" + repo + "/pull/1#issuecomment-2",
+			Repository: repo,
+			Number:     number,
+			Title:      title,
+			SHA:        "0123456789abcdef",
+			StartedAt:  time.Now(),
+			FinishedAt: time.Now(),
+			State:      "failure",
+			Apps:       apps,
+		}))
+		return id
+	}
+
+	id := record("zapier/kubechecks", 1, "Bump <chart>", history.App{Name: "api", State: "failure", Checks: []history.Check{
+		{Name: "rego", State: "failure", Summary: "Policy"},
+	}})
+	record("zapier/other", 2, "Other change")
+
+	e := echo.New()
+	NewDashboardHandler(store, "/prefix/dashboard").AttachHandlers(e.Group("/prefix/dashboard"))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/prefix/dashboard/")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "zapier/kubechecks")
+	assert.Contains(t, rec.Body.String(), "zapier/other")
+	assert.Contains(t, rec.Body.String(), "Bump &lt;chart&gt;", "titles are escaped")
+	assert.Contains(t, rec.Body.String(), `href="/prefix/dashboard/runs/`+strconv.FormatUint(id, 10)+`"`)
+
+	rec = get("/prefix/dashboard?repo=zapier/other")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "zapier/kubechecks")
+
+	rec = get("/prefix/dashboard/runs/" + strconv.FormatUint(id, 10))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "0123456789abcdef")
+	assert.Contains(t, rec.Body.String(), "Policy")
+	assert.Contains(t, rec.Body.String(), `href="https://github.com/zapier/kubechecks/pull/1#issuecomment-2"`)
+
+	assert.Equal(t, http.StatusNotFound, get("/prefix/dashboard/runs/42").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/prefix/dashboard/runs/abc").Code)
+
+	rec = get("/prefix/dashboard/trends?days=7")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "<td>api</td><td>rego</td><td>1</td><td>1</td><td>100%</td>")
+
+	assert.Equal(t, http.StatusBadRequest, get("/prefix/dashboard/trends?days=0").Code)
+}
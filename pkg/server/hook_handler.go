@@ -26,10 +26,10 @@ type VCSHookHandler struct {
 	ctr             container.Container
 	processors      []checks.ProcessorEntry
 	aiReviewChecker queue.AIReviewChecker
-	queueManager    *queue.QueueManager
+	queueManager    queue.Queue
 }
 
-func NewVCSHookHandler(ctr container.Container, processors []checks.ProcessorEntry, aiReviewChecker queue.AIReviewChecker, queueManager *queue.QueueManager) *VCSHookHandler {
+func NewVCSHookHandler(ctr container.Container, processors []checks.ProcessorEntry, aiReviewChecker queue.AIReviewChecker, queueManager queue.Queue) *VCSHookHandler {
 	return &VCSHookHandler{
 		ctr:             ctr,
 		processors:      processors,
@@ -113,6 +113,11 @@ type RepoDirectory struct {
 }
 
 func ProcessCheckEvent(ctx context.Context, pr vcs.PullRequest, ctr container.Container, processors []checks.ProcessorEntry, aiReviewChecker queue.AIReviewChecker) error {
+	return processCheckEvent(ctx, pr, ctr, processors, aiReviewChecker, nil)
+}
+
+// processCheckEvent processes a check request, tracking its run in runs unless it is nil.
+func processCheckEvent(ctx context.Context, pr vcs.PullRequest, ctr container.Container, processors []checks.ProcessorEntry, aiReviewChecker queue.AIReviewChecker, runs *RunRegistry) error {
 	ctx, span := tracer.Start(ctx, "processCheckEvent",
 		trace.WithAttributes(
 			attribute.Int("mr_id", pr.CheckID),
@@ -131,6 +136,11 @@ func ProcessCheckEvent(ctx context.Context, pr vcs.PullRequest, ctr container.Co
 
 	// If we've gotten here, we can now begin running checks (or trying to)
 	cEvent := events.NewCheckEvent(pr, ctr, repoMgr, processors, aiReviewChecker)
+	if runs != nil {
+		runs.add(cEvent)
+		defer runs.remove(cEvent)
+	}
+
 	if err := cEvent.Process(ctx); err != nil {
 		span.RecordError(err)
 		log.Error().Caller().Err(err).Msg("failed to process the request")
@@ -142,14 +152,14 @@ func ProcessCheckEvent(ctx context.Context, pr vcs.PullRequest, ctr container.Co
 // passesLabelFilter checks if the given mergeEvent has a label that starts with "kubechecks:"
 // and matches the handler's labelFilter. Returns true if there's a matching label or no
 // "kubechecks:" labels are found, and false if a "kubechecks:" label is found but none match
-// the labelFilter.
+// the labelFilter. Priority labels, e.g. "kubechecks:priority-high", are not filter labels.
 func (h *VCSHookHandler) passesLabelFilter(repo vcs.PullRequest) bool {
 	foundKubechecksLabel := false
 
 	for _, label := range repo.Labels {
 		log.Debug().Caller().Str("check_label", label).Msg("checking label for match")
 		// Check if label starts with "kubechecks:"
-		if strings.HasPrefix(label, "kubechecks:") {
+		if strings.HasPrefix(label, "kubechecks:") && !strings.HasPrefix(label, queue.PriorityLabelPrefix) {
 			foundKubechecksLabel = true
 
 			// Get the remaining string after "kubechecks:"
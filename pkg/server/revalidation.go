@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/pkg/errors"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/checkcache"
+	"github.com/zapier/kubechecks/pkg/checks"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/events"
+	"github.com/zapier/kubechecks/pkg/git"
+	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/queue"
+	"github.com/zapier/kubechecks/pkg/revalidate"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// revalidationBackend lets the re-validation scheduler look at base branches and live states, and queue checks. It
+// is used by the scheduler's goroutine, and by checks as they record their baselines.
+type revalidationBackend struct {
+	ctr             container.Container
+	processors      []checks.ProcessorEntry
+	aiReviewChecker queue.AIReviewChecker
+	queueManager    queue.Queue
+
+	// bases are the clones of the base branches of the watched pull requests, which are fetched whenever their head is
+	// asked for, and wiped once none of them uses it anymore.
+	basesLock sync.Mutex
+	bases     map[string]*git.Repo
+}
+
+var _ revalidate.Backend = (*revalidationBackend)(nil)
+
+func (s *Server) revalidationBackend() *revalidationBackend {
+	return &revalidationBackend{
+		ctr:             s.ctr,
+		processors:      s.processors,
+		aiReviewChecker: s.aiReviewChecker,
+		queueManager:    s.queueManager,
+		bases:           make(map[string]*git.Repo),
+	}
+}
+
+func baseKey(pr vcs.PullRequest) string {
+	return pr.CloneURL + "@" + pr.BaseRef
+}
+
+// base returns the clone of the base branch of a pull request, fetching it first when fetch is set.
+func (b *revalidationBackend) base(ctx context.Context, pr vcs.PullRequest, fetch bool) (*git.Repo, error) {
+	key := baseKey(pr)
+	if repo, ok := b.bases[key]; ok {
+		if !fetch {
+			return repo, nil
+		}
+		if err := repo.Update(ctx); err != nil {
+			return nil, errors.Wrap(err, "failed to update base branch")
+		}
+		return repo, nil
+	}
+
+	repo := git.New(b.ctr.Config, pr.CloneURL, pr.BaseRef)
+	if err := repo.Clone(ctx); err != nil {
+		repo.Wipe()
+		return nil, errors.Wrap(err, "failed to clone base branch")
+	}
+
+	b.bases[key] = repo
+	return repo, nil
+}
+
+func (b *revalidationBackend) BaseHead(ctx context.Context, pr vcs.PullRequest) (string, error) {
+	b.basesLock.Lock()
+	defer b.basesLock.Unlock()
+
+	repo, err := b.base(ctx, pr, true)
+	if err != nil {
+		return "", err
+	}
+
+	return repo.GetCurrentCommitSHA()
+}
+
+func (b *revalidationBackend) AffectedApps(ctx context.Context, pr vcs.PullRequest, from, to string) ([]string, error) {
+	b.basesLock.Lock()
+	defer b.basesLock.Unlock()
+
+	// the head was just asked for, so the clone is recent enough
+	repo, err := b.base(ctx, pr, false)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := repo.GetListOfChangedFilesBetween(ctx, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list changed files")
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	ce := events.NewCheckEvent(pr, b.ctr, b.ctr.RepoManager, nil, nil)
+	affected, err := ce.AffectedLocal(ctx, repo, files)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(affected.Applications))
+	for _, app := range affected.Applications {
+		names = append(names, pkg.QualifiedName(app.ObjectMeta))
+	}
+	return names, nil
+}
+
+func (b *revalidationBackend) Prune(watched []vcs.PullRequest) {
+	b.basesLock.Lock()
+	defer b.basesLock.Unlock()
+
+	inUse := make(map[string]bool, len(watched))
+	for _, pr := range watched {
+		inUse[baseKey(pr)] = true
+	}
+
+	for key, repo := range b.bases {
+		if !inUse[key] {
+			repo.Wipe()
+			delete(b.bases, key)
+		}
+	}
+}
+
+func (b *revalidationBackend) LiveState(ctx context.Context, app v1alpha1.Application) (string, error) {
+	ctr := b.ctr.ForInstance(pkg.ArgoCDInstanceOf(app.ObjectMeta))
+
+	return checkcache.LiveState(ctx, ctr.ArgoClient, app.Name)
+}
+
+func (b *revalidationBackend) Reload(ctx context.Context, pr vcs.PullRequest) (vcs.PullRequest, error) {
+	ref := fmt.Sprintf("%s#%d", pr.FullName, pr.CheckID)
+	if b.ctr.VcsClient.GetName() == "gitlab" {
+		ref = fmt.Sprintf("%s!%d", pr.FullName, pr.CheckID)
+	}
+
+	return b.ctr.VcsClient.LoadHook(ctx, ref)
+}
+
+func (b *revalidationBackend) UpdateMessage(ctx context.Context, note *msg.Message, comment string) error {
+	return b.ctr.VcsClient.UpdateMessage(ctx, note, comment)
+}
+
+func (b *revalidationBackend) Enqueue(ctx context.Context, pr vcs.PullRequest) error {
+	return b.queueManager.Enqueue(ctx, queue.EnqueueParams{
+		PullRequest:     pr,
+		Container:       b.ctr,
+		Processors:      b.processors,
+		AIReviewChecker: b.aiReviewChecker,
+	})
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	vcsmocks "github.com/zapier/kubechecks/mocks/vcs/mocks"
+	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/git"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+func TestRevalidationBackend_Reload(t *testing.T) {
+	tests := map[string]struct {
+		vcs, ref string
+	}{
+		"github": {vcs: "github", ref: "zapier/kubechecks#1"},
+		"gitlab": {vcs: "gitlab", ref: "zapier/kubechecks!1"},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			pr := vcs.PullRequest{FullName: "zapier/kubechecks", CheckID: 1}
+
+			vcsClient := new(vcsmocks.MockClient)
+			vcsClient.EXPECT().GetName().Return(tc.vcs)
+			vcsClient.EXPECT().LoadHook(mock.Anything, tc.ref).Return(vcs.PullRequest{FullName: pr.FullName, CheckID: pr.CheckID, Closed: true}, nil)
+
+			s := NewServer(container.Container{VcsClient: vcsClient}, nil, nil, nil, nil)
+			reloaded, err := s.revalidationBackend().Reload(context.Background(), pr)
+			require.NoError(t, err)
+			assert.True(t, reloaded.Closed)
+		})
+	}
+}
+
+func TestRevalidationBackend_Prune(t *testing.T) {
+	watched := vcs.PullRequest{CloneURL: "https://github.com/zapier/kubechecks.git", BaseRef: "main"}
+	unwatched := vcs.PullRequest{CloneURL: "https://github.com/zapier/kubechecks.git", BaseRef: "release"}
+
+	b := NewServer(container.Container{}, nil, nil, nil, nil).revalidationBackend()
+	b.bases[baseKey(watched)] = &git.Repo{Directory: t.TempDir()}
+	b.bases[baseKey(unwatched)] = &git.Repo{Directory: t.TempDir()}
+	dir := b.bases[baseKey(unwatched)].Directory
+
+	b.Prune([]vcs.PullRequest{watched, watched})
+	assert.Contains(t, b.bases, baseKey(watched))
+	assert.NotContains(t, b.bases, baseKey(unwatched))
+	assert.NoDirExists(t, dir)
+
+	b.Prune(nil)
+	assert.Empty(t, b.bases)
+}
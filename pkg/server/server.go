@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/heptiolabs/healthcheck"
@@ -12,11 +13,14 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 	"github.com/ziflex/lecho/v3"
 
 	"github.com/zapier/kubechecks/pkg/checks"
 	"github.com/zapier/kubechecks/pkg/container"
+	"github.com/zapier/kubechecks/pkg/history"
+	"github.com/zapier/kubechecks/pkg/leader"
 	"github.com/zapier/kubechecks/pkg/queue"
 	"github.com/zapier/kubechecks/pkg/vcs"
 )
@@ -27,11 +31,35 @@ type Server struct {
 	ctr             container.Container
 	processors      []checks.ProcessorEntry
 	aiReviewChecker queue.AIReviewChecker
-	queueManager    *queue.QueueManager
+	queueManager    queue.Queue
+	runs            *RunRegistry
 	echo            *echo.Echo
+	// dashboard serves the dashboard on its own listener, away from the public webhook endpoints.
+	dashboard *echo.Echo
 }
 
-func NewServer(ctr container.Container, processors []checks.ProcessorEntry, aiReviewChecker queue.AIReviewChecker) *Server {
+// NewServer creates the webhook server. Check requests wait in checkQueue, or in a queue of this replica when it
+// is nil. The admin API lists the checks runs tracks, which should be the registry the queue processes checks with.
+func NewServer(ctr container.Container, processors []checks.ProcessorEntry, aiReviewChecker queue.AIReviewChecker, checkQueue queue.Queue, runs *RunRegistry) *Server {
+	if runs == nil {
+		runs = NewRunRegistry()
+	}
+	if checkQueue == nil {
+		checkQueue = NewQueueManager(ctr, nil, runs)
+	}
+
+	return &Server{
+		ctr:             ctr,
+		processors:      processors,
+		aiReviewChecker: aiReviewChecker,
+		queueManager:    checkQueue,
+		runs:            runs,
+	}
+}
+
+// NewQueueManager creates a queue of this replica, that persists requests to the store if there is one and tracks
+// the checks it runs in runs.
+func NewQueueManager(ctr container.Container, store queue.Store, runs *RunRegistry) *queue.QueueManager {
 	// Create queue manager with configurable queue size
 	queueSize := ctr.Config.MaxRepoWorkerQueueSize
 	if queueSize <= 0 {
@@ -39,19 +67,48 @@ func NewServer(ctr container.Container, processors []checks.ProcessorEntry, aiRe
 	}
 
 	queueManager := queue.NewQueueManager(
-		queue.Config{QueueSize: queueSize},
-		ProcessCheckEvent,
+		queue.Config{
+			QueueSize:       queueSize,
+			Store:           store,
+			MaxConcurrent:   ctr.Config.MaxConcurrentRepoChecks,
+			RepoPriorities:  ctr.Config.RepoPriorityLevels,
+			LabelPriorities: ctr.Config.LabelPriorities,
+		},
+		runs.ProcessCheckEvent,
 	)
 
 	log.Info().
 		Int("repo_worker_queue_size", queueSize).
+		Int("max_concurrent_repo_checks", ctr.Config.MaxConcurrentRepoChecks).
 		Msg("initialized repo worker queue manager")
 
-	return &Server{
-		ctr:             ctr,
-		processors:      processors,
-		aiReviewChecker: aiReviewChecker,
-		queueManager:    queueManager,
+	return queueManager
+}
+
+// NewRedisQueue creates a queue that is shared with the other replicas through Redis, and tracks the checks this
+// replica runs in runs.
+func NewRedisQueue(ctr container.Container, client redis.UniversalClient, consumer string, runs *RunRegistry) *queue.RedisQueue {
+	log.Info().
+		Str("consumer", consumer).
+		Int("workers", ctr.Config.RedisQueueWorkers).
+		Msg("initialized shared redis queue")
+
+	return queue.NewRedisQueue(queue.RedisConfig{
+		Client:    client,
+		Consumer:  consumer,
+		Workers:   ctr.Config.RedisQueueWorkers,
+		QueueSize: ctr.Config.MaxRepoWorkerQueueSize,
+	}, runs.ProcessCheckEvent)
+}
+
+// LeaderDuties returns the work that only the elected leader does, when kubechecks runs several replicas.
+func (s *Server) LeaderDuties() []leader.Duty {
+	return []leader.Duty{
+		func(ctx context.Context) {
+			if err := s.ensureWebhooks(ctx); err != nil {
+				log.Warn().Err(err).Msg("failed to create webhooks")
+			}
+		},
 	}
 }
 
@@ -69,6 +126,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 			httpErr = err
 		}
 	}
+	if s.dashboard != nil {
+		if err := s.dashboard.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("dashboard server shutdown failed")
+		}
+	}
 
 	// Then shutdown queue workers
 	log.Info().Msg("shutting down queue workers")
@@ -86,8 +148,23 @@ func (s *Server) Shutdown(ctx context.Context) error {
 
 // Start initializes and starts the HTTP server (blocking)
 func (s *Server) Start(ctx context.Context) error {
-	if err := s.ensureWebhooks(ctx); err != nil {
-		log.Warn().Err(err).Msg("failed to create webhooks")
+	// with leader election, only the leader creates webhooks, see LeaderDuties
+	if !s.ctr.Config.LeaderElection {
+		if err := s.ensureWebhooks(ctx); err != nil {
+			log.Warn().Err(err).Msg("failed to create webhooks")
+		}
+	}
+
+	if err := s.queueManager.Resume(ctx, queue.ResumeParams{
+		Container:       s.ctr,
+		Processors:      s.processors,
+		AIReviewChecker: s.aiReviewChecker,
+	}); err != nil {
+		log.Error().Err(err).Msg("failed to resume persisted check requests")
+	}
+
+	if s.ctr.Revalidation != nil {
+		s.ctr.Revalidation.Start(ctx, s.revalidationBackend())
 	}
 
 	s.echo = echo.New()
@@ -108,6 +185,15 @@ func (s *Server) Start(ctx context.Context) error {
 	ghHooks := NewVCSHookHandler(s.ctr, s.processors, s.aiReviewChecker, s.queueManager)
 	ghHooks.AttachHandlers(hooksGroup)
 
+	if s.ctr.Config.AdminToken != "" {
+		admin := NewAdminHandler(s.ctr, s.processors, s.aiReviewChecker, s.queueManager, s.runs)
+		admin.AttachHandlers(s.echo.Group(s.adminPrefix()))
+	}
+
+	if s.ctr.History != nil && s.ctr.Config.DashboardAddr != "" {
+		s.startDashboard()
+	}
+
 	fmt.Println("Method\tPath")
 	for _, r := range s.echo.Routes() {
 		fmt.Printf("%s\t%s\n", r.Method, r.Path)
@@ -122,8 +208,35 @@ func (s *Server) Start(ctx context.Context) error {
 }
 
 func (s *Server) hooksPrefix() string {
+	return s.pathPrefix(KubeChecksHooksPathPrefix)
+}
+
+func (s *Server) adminPrefix() string {
+	return s.pathPrefix(KubeChecksAdminPathPrefix)
+}
+
+// startDashboard serves the dashboard on the dashboard listener, which is not authenticated and is meant to be
+// reachable only from trusted networks.
+func (s *Server) startDashboard() {
+	s.dashboard = echo.New()
+	s.dashboard.HideBanner = true
+	s.dashboard.HidePort = true
+	s.dashboard.Logger = lecho.New(log.Logger)
+	s.dashboard.Use(middleware.Recover())
+
+	NewDashboardHandler(s.ctr.History, history.DashboardPathPrefix).AttachHandlers(s.dashboard.Group(history.DashboardPathPrefix))
+
+	go func() {
+		log.Info().Str("addr", s.ctr.Config.DashboardAddr).Msg("starting dashboard server")
+		if err := s.dashboard.Start(s.ctr.Config.DashboardAddr); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("failed to start dashboard server")
+		}
+	}()
+}
+
+func (s *Server) pathPrefix(path string) string {
 	prefix := s.ctr.Config.UrlPrefix
-	serverUrl, err := url.JoinPath("/", prefix, KubeChecksHooksPathPrefix)
+	serverUrl, err := url.JoinPath("/", prefix, path)
 	if err != nil {
 		log.Warn().Err(err).Msg(":whatintarnation:")
 	}
@@ -156,7 +269,16 @@ func (s *Server) ensureWebhooks(ctx context.Context) error {
 	}
 	log.Info().Str("webhookUrl", fullUrl).Msg("webhook URL for this kubechecks instance")
 
-	for _, repo := range s.ctr.VcsToArgoMap.GetVcsRepos() {
+	var repos []string
+	for _, ctr := range s.ctr.InstanceContainers() {
+		for _, repo := range ctr.VcsToArgoMap.GetVcsRepos() {
+			if !slices.Contains(repos, repo) {
+				repos = append(repos, repo)
+			}
+		}
+	}
+
+	for _, repo := range repos {
 		wh, err := vcsClient.GetHookByUrl(ctx, repo, fullUrl)
 		if err != nil && !errors.Is(err, vcs.ErrHookNotFound) {
 			log.Error().Err(err).Msgf("failed to get hook for %s:", repo)
@@ -0,0 +1,28 @@
+package pkg
+
+import "fmt"
+
+// SupersededError is the cause of a check run being cancelled, because a newer commit of the same pull request
+// has been pushed and will be checked instead.
+type SupersededError struct {
+	SHA string
+}
+
+func (e SupersededError) Error() string {
+	return fmt.Sprintf("superseded by %s", e.SHA)
+}
+
+// CancelledError is the cause of a check run being cancelled on request, e.g. through the admin API.
+type CancelledError struct{}
+
+func (e CancelledError) Error() string {
+	return "cancelled"
+}
+
+// LockLostError is the cause of a check run being cancelled, because the lock of its pull request expired and may
+// be held by another replica now.
+type LockLostError struct{}
+
+func (e LockLostError) Error() string {
+	return "lost the lock of the pull request"
+}
@@ -179,6 +179,7 @@ func (c *Client) buildRepo(pullRequest *github.PullRequest) vcs.PullRequest {
 		Labels:        labels,
 		Title:         pullRequest.GetTitle(),
 		Description:   pullRequest.GetBody(),
+		Closed:        pullRequest.GetState() == "closed",
 
 		Config: c.cfg,
 	}
@@ -41,7 +41,9 @@ func (c *Client) PostMessage(ctx context.Context, pr vcs.PullRequest, message st
 		return nil, errors.Wrap(err, "could not post message to PR")
 	}
 
-	return msg.NewMessage(pr.FullName, pr.CheckID, int(*comment.ID), c), nil
+	m := msg.NewMessage(pr.FullName, pr.CheckID, int(*comment.ID), c)
+	m.URL = comment.GetHTMLURL()
+	return m, nil
 }
 
 func (c *Client) UpdateMessage(ctx context.Context, m *msg.Message, msg string) error {
@@ -72,6 +74,9 @@ func (c *Client) UpdateMessage(ctx context.Context, m *msg.Message, msg string)
 
 	// update note id just in case it changed
 	m.NoteID = int(*comment.ID)
+	if url := comment.GetHTMLURL(); url != "" {
+		m.URL = url
+	}
 
 	return nil
 }
@@ -267,6 +267,7 @@ func (c *Client) LoadHook(ctx context.Context, id string) (vcs.PullRequest, erro
 		Labels:        mergeRequest.Labels,
 		Title:         mergeRequest.Title,
 		Description:   mergeRequest.Description,
+		Closed:        mergeRequest.State == "closed" || mergeRequest.State == "merged",
 
 		Config: c.cfg,
 	}, nil
@@ -246,6 +246,12 @@ func TestClient_SimpleGetters(t *testing.T) {
 	assert.Equal(t, "gitlab", c.GetName())
 }
 
+func TestNoteURL(t *testing.T) {
+	pr := vcs.PullRequest{CloneURL: "https://gitlab.com/zapier/kubechecks.git", CheckID: 12}
+	assert.Equal(t, "https://gitlab.com/zapier/kubechecks/-/merge_requests/12#note_34", noteURL(pr, 34))
+	assert.Empty(t, noteURL(vcs.PullRequest{CheckID: 12}, 34))
+}
+
 func TestClient_GetAuthHeaders(t *testing.T) {
 	c := &Client{
 		cfg: config.ServerConfig{
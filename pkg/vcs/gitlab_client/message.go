@@ -36,7 +36,17 @@ func (c *Client) PostMessage(ctx context.Context, pr vcs.PullRequest, message st
 		return nil, errors.Wrap(err, "could not post message to MR")
 	}
 
-	return msg.NewMessage(pr.FullName, pr.CheckID, n.ID, c), nil
+	m := msg.NewMessage(pr.FullName, pr.CheckID, n.ID, c)
+	m.URL = noteURL(pr, n.ID)
+	return m, nil
+}
+
+// noteURL links to a note of a merge request, which is found next to the project's clone URL.
+func noteURL(pr vcs.PullRequest, noteID int) string {
+	if pr.CloneURL == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/-/merge_requests/%d#note_%d", strings.TrimSuffix(pr.CloneURL, ".git"), pr.CheckID, noteID)
 }
 
 func (c *Client) hideOutdatedMessages(ctx context.Context, projectName string, mergeRequestID int, notes []*gitlab.Note) error {
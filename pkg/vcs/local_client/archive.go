@@ -0,0 +1,30 @@
+package local_client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// DownloadArchive returns a file:// url for the "<sha>.zip" archive in the archive directory.
+func (c *Client) DownloadArchive(_ context.Context, pr vcs.PullRequest) (string, error) {
+	if c.archiveDir == "" {
+		return "", errors.Wrap(ErrNotSupported, "no archive directory configured")
+	}
+
+	archivePath, err := filepath.Abs(filepath.Join(c.archiveDir, pr.SHA+".zip"))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to resolve archive path")
+	}
+
+	if _, err = os.Stat(archivePath); err != nil {
+		return "", errors.Wrap(err, "archive not found")
+	}
+
+	return fmt.Sprintf("file://%s", filepath.ToSlash(archivePath)), nil
+}
@@ -0,0 +1,77 @@
+// Package local_client implements a vcs.Client that never talks to a remote VCS. Pull requests are
+// described by JSON or YAML files, archives are served from a local directory, and anything that would
+// be posted to the VCS is written to an output directory instead. Without an output directory, nothing
+// is written at all, which is what `kubechecks local` relies on.
+package local_client
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+var ErrNotSupported = errors.New("not supported by the local vcs client")
+
+type Client struct {
+	username, email string
+
+	outputDir  string
+	archiveDir string
+
+	// pull requests loaded by LoadHook, keyed by prKey
+	pullRequests map[string]PullRequestFile
+	// the last note id used per pull request
+	noteIDs map[string]int
+	lock    sync.Mutex
+}
+
+var _ vcs.Client = new(Client)
+
+func CreateLocalClient(cfg config.ServerConfig) *Client {
+	client := &Client{
+		username:   cfg.VcsUsername,
+		email:      cfg.VcsEmail,
+		outputDir:  cfg.LocalVcsOutputDir,
+		archiveDir: cfg.LocalVcsArchiveDir,
+
+		pullRequests: make(map[string]PullRequestFile),
+		noteIDs:      make(map[string]int),
+	}
+
+	if client.username == "" {
+		client.username = vcs.DefaultVcsUsername
+	}
+	if client.email == "" {
+		client.email = vcs.DefaultVcsEmail
+	}
+
+	return client
+}
+
+func (c *Client) Username() string      { return c.username }
+func (c *Client) CloneUsername() string { return c.username }
+func (c *Client) Email() string         { return c.email }
+func (c *Client) GetName() string       { return "local" }
+
+func (c *Client) GetAuthHeaders() map[string]string { return nil }
+
+func (c *Client) VerifyHook(*http.Request, string) ([]byte, error) {
+	return nil, ErrNotSupported
+}
+
+func (c *Client) ParseHook(context.Context, *http.Request, []byte) (vcs.PullRequest, error) {
+	return vcs.PullRequest{}, ErrNotSupported
+}
+
+func (c *Client) GetHookByUrl(context.Context, string, string) (*vcs.WebHookConfig, error) {
+	return nil, ErrNotSupported
+}
+
+func (c *Client) CreateHook(context.Context, string, string, string) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,139 @@
+package local_client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/config"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+const testPullRequest = `
+cloneUrl: git@github.com:zapier/kubechecks.git
+number: 12
+baseRef: main
+headRef: feature
+sha: abc123
+title: my change
+labels: [kubechecks:test]
+changedFiles:
+  - apps/a/values.yaml
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+
+	filename := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(filename, []byte(content), 0o644))
+	return filename
+}
+
+func TestLoadHook(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c := CreateLocalClient(config.ServerConfig{})
+
+	pr, err := c.LoadHook(ctx, writeFile(t, dir, "pr.yaml", testPullRequest))
+	require.NoError(t, err)
+
+	assert.Equal(t, "zapier/kubechecks", pr.FullName)
+	assert.Equal(t, "zapier", pr.Owner)
+	assert.Equal(t, "kubechecks", pr.Name)
+	assert.Equal(t, 12, pr.CheckID)
+	assert.Equal(t, "main", pr.BaseRef)
+	assert.Equal(t, "main", pr.DefaultBranch)
+	assert.Equal(t, "feature", pr.HeadRef)
+	assert.Equal(t, "abc123", pr.SHA)
+	assert.Equal(t, []string{"kubechecks:test"}, pr.Labels)
+	assert.Equal(t, vcs.DefaultVcsUsername, pr.Username)
+
+	files, err := c.GetPullRequestFiles(ctx, pr)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apps/a/values.yaml"}, files)
+
+	_, err = c.GetPullRequestFiles(ctx, vcs.PullRequest{FullName: "zapier/other", CheckID: 1})
+	assert.Error(t, err)
+}
+
+func TestLoadHookJSON(t *testing.T) {
+	dir := t.TempDir()
+	c := CreateLocalClient(config.ServerConfig{})
+
+	pr, err := c.LoadHook(context.Background(), writeFile(t, dir, "pr.json",
+		`{"cloneUrl": "https://gitlab.com/group/sub/repo.git", "number": 3, "baseRef": "main", "sha": "def456"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "group/sub/repo", pr.FullName)
+	assert.Equal(t, "group/sub", pr.Owner)
+
+	_, err = c.LoadHook(context.Background(), writeFile(t, dir, "bad.json", `{"number": 3}`))
+	assert.ErrorContains(t, err, "cloneUrl")
+}
+
+func TestOutput(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	c := CreateLocalClient(config.ServerConfig{LocalVcsOutputDir: dir})
+
+	pr, err := c.LoadHook(ctx, writeFile(t, t.TempDir(), "pr.yaml", testPullRequest))
+	require.NoError(t, err)
+	prDir := filepath.Join(dir, "zapier_kubechecks", "12")
+
+	m, err := c.PostMessage(ctx, pr, "running")
+	require.NoError(t, err)
+	assert.Equal(t, 1, m.NoteID)
+	require.NoError(t, c.UpdateMessage(ctx, m, "done"))
+
+	data, err := os.ReadFile(filepath.Join(prDir, "comment-1.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "done", string(data))
+
+	require.NoError(t, c.CommitStatus(ctx, pr, pkg.StateRunning))
+	require.NoError(t, c.CommitStatus(ctx, pr, pkg.StateSuccess))
+	data, err = os.ReadFile(filepath.Join(prDir, "statuses.jsonl"))
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"state":"Passed"`)
+
+	require.NoError(t, c.PostReviewSuggestions(ctx, pr, "summary", []vcs.ReviewSuggestion{{Path: "a.yaml", EndLine: 3}}))
+	data, err = os.ReadFile(filepath.Join(prDir, "review-2.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"summary": "summary"`)
+}
+
+func TestNoOutputDir(t *testing.T) {
+	ctx := context.Background()
+	c := CreateLocalClient(config.ServerConfig{})
+	pr := vcs.PullRequest{FullName: "zapier/kubechecks", CheckID: 1}
+
+	m, err := c.PostMessage(ctx, pr, "running")
+	require.NoError(t, err)
+	assert.NoError(t, c.UpdateMessage(ctx, m, "done"))
+	assert.NoError(t, c.CommitStatus(ctx, pr, pkg.StateSuccess))
+	assert.NoError(t, c.PostReviewSuggestions(ctx, pr, "summary", nil))
+}
+
+func TestDownloadArchive(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeFile(t, dir, "abc123.zip", "")
+
+	c := CreateLocalClient(config.ServerConfig{LocalVcsArchiveDir: dir})
+
+	url, err := c.DownloadArchive(ctx, vcs.PullRequest{SHA: "abc123"})
+	require.NoError(t, err)
+	assert.Equal(t, "file://"+filepath.Join(dir, "abc123.zip"), url)
+
+	_, err = c.DownloadArchive(ctx, vcs.PullRequest{SHA: "missing"})
+	assert.Error(t, err)
+
+	_, err = CreateLocalClient(config.ServerConfig{}).DownloadArchive(ctx, vcs.PullRequest{SHA: "abc123"})
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
@@ -0,0 +1,25 @@
+package local_client
+
+import "github.com/zapier/kubechecks/pkg"
+
+// results are read in a terminal, so use unicode emoji rather than markdown shortcodes
+var stateEmoji = map[pkg.CommitState]string{
+	pkg.StateNone:    "",
+	pkg.StateSkip:    "⏭️",
+	pkg.StateSuccess: "✅",
+	pkg.StateRunning: "🏃",
+	pkg.StateWarning: "⚠️",
+	pkg.StateFailure: "🔴",
+	pkg.StateError:   "❗",
+	pkg.StatePanic:   "💀",
+}
+
+const defaultEmoji = "⁉️"
+
+// ToEmoji returns a string representation of this state for use in the terminal
+func (c *Client) ToEmoji(s pkg.CommitState) string {
+	if emoji, ok := stateEmoji[s]; ok {
+		return emoji
+	}
+	return defaultEmoji
+}
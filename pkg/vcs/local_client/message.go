@@ -0,0 +1,68 @@
+package local_client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zapier/kubechecks/pkg/msg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// prDir returns the directory that output for the given pull request is written to, creating it if needed.
+func (c *Client) prDir(fullName string, number int) (string, error) {
+	dir := filepath.Join(c.outputDir, strings.ReplaceAll(fullName, "/", "_"), fmt.Sprintf("%d", number))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", errors.Wrap(err, "failed to create output directory")
+	}
+
+	return dir, nil
+}
+
+func (c *Client) writeComment(fullName string, number, noteID int, message string) error {
+	dir, err := c.prDir(fullName, number)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("comment-%d.md", noteID))
+	if err = os.WriteFile(filename, []byte(message), 0o644); err != nil {
+		return errors.Wrap(err, "failed to write comment")
+	}
+
+	log.Debug().Caller().Str("path", filename).Msg("wrote comment")
+	return nil
+}
+
+func (c *Client) PostMessage(_ context.Context, pr vcs.PullRequest, message string) (*msg.Message, error) {
+	c.lock.Lock()
+	key := prKey(pr.FullName, pr.CheckID)
+	c.noteIDs[key]++
+	noteID := c.noteIDs[key]
+	c.lock.Unlock()
+
+	if c.outputDir == "" {
+		log.Debug().Caller().Str("pr", key).Msg("no output directory, not posting message")
+	} else if err := c.writeComment(pr.FullName, pr.CheckID, noteID, message); err != nil {
+		return nil, err
+	}
+
+	return msg.NewMessage(pr.FullName, pr.CheckID, noteID, c), nil
+}
+
+func (c *Client) UpdateMessage(_ context.Context, m *msg.Message, message string) error {
+	if c.outputDir == "" {
+		log.Debug().Caller().Str("pr", prKey(m.Name, m.CheckID)).Msg("no output directory, not updating message")
+		return nil
+	}
+
+	return c.writeComment(m.Name, m.CheckID, m.NoteID, message)
+}
+
+// TidyOutdatedComments does nothing, every run keeps its own comment files.
+func (c *Client) TidyOutdatedComments(context.Context, vcs.PullRequest) error { return nil }
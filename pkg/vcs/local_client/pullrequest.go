@@ -0,0 +1,106 @@
+package local_client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+// PullRequestFile is the JSON or YAML document that describes a pull request to the local vcs client.
+type PullRequestFile struct {
+	CloneURL      string   `json:"cloneUrl"`
+	Number        int      `json:"number"`
+	BaseRef       string   `json:"baseRef"`
+	HeadRef       string   `json:"headRef"`
+	DefaultBranch string   `json:"defaultBranch,omitempty"`
+	SHA           string   `json:"sha"`
+	Title         string   `json:"title,omitempty"`
+	Description   string   `json:"description,omitempty"`
+	Labels        []string `json:"labels,omitempty"`
+	ChangedFiles  []string `json:"changedFiles"`
+}
+
+func prKey(fullName string, number int) string {
+	return fmt.Sprintf("%s#%d", fullName, number)
+}
+
+// LoadHook reads the pull request description at the given path.
+func (c *Client) LoadHook(_ context.Context, filename string) (vcs.PullRequest, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return vcs.PullRequest{}, errors.Wrap(err, "failed to read pull request file")
+	}
+
+	var file PullRequestFile
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return vcs.PullRequest{}, errors.Wrap(err, "failed to parse pull request file")
+	}
+
+	switch {
+	case file.CloneURL == "":
+		return vcs.PullRequest{}, errors.New("pull request file is missing cloneUrl")
+	case file.BaseRef == "":
+		return vcs.PullRequest{}, errors.New("pull request file is missing baseRef")
+	case file.SHA == "":
+		return vcs.PullRequest{}, errors.New("pull request file is missing sha")
+	}
+
+	parsed, err := pkg.Canonicalize(file.CloneURL)
+	if err != nil {
+		return vcs.PullRequest{}, err
+	}
+
+	if file.DefaultBranch == "" {
+		file.DefaultBranch = file.BaseRef
+	}
+
+	c.lock.Lock()
+	c.pullRequests[prKey(parsed.Path, file.Number)] = file
+	c.lock.Unlock()
+
+	return vcs.PullRequest{
+		BaseRef:       file.BaseRef,
+		HeadRef:       file.HeadRef,
+		DefaultBranch: file.DefaultBranch,
+		CloneURL:      file.CloneURL,
+		Name:          path.Base(parsed.Path),
+		Owner:         path.Dir(parsed.Path),
+		CheckID:       file.Number,
+		SHA:           file.SHA,
+		FullName:      parsed.Path,
+		Username:      c.username,
+		Email:         c.email,
+		Labels:        file.Labels,
+		Title:         file.Title,
+		Description:   file.Description,
+	}, nil
+}
+
+func (c *Client) getPullRequestFile(pr vcs.PullRequest) (PullRequestFile, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	file, ok := c.pullRequests[prKey(pr.FullName, pr.CheckID)]
+	if !ok {
+		return file, fmt.Errorf("pull request %s was not loaded", prKey(pr.FullName, pr.CheckID))
+	}
+
+	return file, nil
+}
+
+// GetPullRequestFiles returns the changed files listed in the pull request file.
+func (c *Client) GetPullRequestFiles(_ context.Context, pr vcs.PullRequest) ([]string, error) {
+	file, err := c.getPullRequestFile(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	return file.ChangedFiles, nil
+}
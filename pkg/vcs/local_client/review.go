@@ -0,0 +1,46 @@
+package local_client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+type review struct {
+	Summary     string                 `json:"summary"`
+	Suggestions []vcs.ReviewSuggestion `json:"suggestions"`
+}
+
+// PostReviewSuggestions writes the review to review-<n>.json, numbered like comments.
+func (c *Client) PostReviewSuggestions(_ context.Context, pr vcs.PullRequest, summary string, suggestions []vcs.ReviewSuggestion) error {
+	if c.outputDir == "" {
+		log.Debug().Caller().Str("pr", prKey(pr.FullName, pr.CheckID)).Int("count", len(suggestions)).Msg("no output directory, not posting review suggestions")
+		return nil
+	}
+
+	c.lock.Lock()
+	key := prKey(pr.FullName, pr.CheckID)
+	c.noteIDs[key]++
+	reviewID := c.noteIDs[key]
+	c.lock.Unlock()
+
+	dir, err := c.prDir(pr.FullName, pr.CheckID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(review{Summary: summary, Suggestions: suggestions}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode review")
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("review-%d.json", reviewID))
+	return errors.Wrap(os.WriteFile(filename, data, 0o644), "failed to write review")
+}
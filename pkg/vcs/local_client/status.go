@@ -0,0 +1,48 @@
+package local_client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog/log"
+
+	"github.com/zapier/kubechecks/pkg"
+	"github.com/zapier/kubechecks/pkg/vcs"
+)
+
+type commitStatus struct {
+	SHA   string    `json:"sha"`
+	State string    `json:"state"`
+	Time  time.Time `json:"time"`
+}
+
+// CommitStatus appends the status to statuses.jsonl, so that every transition is kept.
+func (c *Client) CommitStatus(_ context.Context, pr vcs.PullRequest, state pkg.CommitState) error {
+	if c.outputDir == "" {
+		log.Debug().Caller().Str("pr", prKey(pr.FullName, pr.CheckID)).Str("state", state.BareString()).Msg("no output directory, not setting commit status")
+		return nil
+	}
+
+	dir, err := c.prDir(pr.FullName, pr.CheckID)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(commitStatus{SHA: pr.SHA, State: state.BareString(), Time: time.Now()})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode commit status")
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, "statuses.jsonl"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return errors.Wrap(err, "failed to open statuses file")
+	}
+	defer pkg.WithErrorLogging(f.Close, "failed to close statuses file")
+
+	_, err = f.Write(append(line, '\n'))
+	return errors.Wrap(err, "failed to write commit status")
+}
@@ -21,6 +21,7 @@ type PullRequest struct {
 	Labels        []string // Labels associated with the MR/PR
 	Title         string   // MR/PR title — author's one-line intent
 	Description   string   // MR/PR description — author's stated intent (may be truncated before sending to LLM)
+	Closed        bool     // MR/PR was merged or closed, as far as the client knows
 
 	Config config.ServerConfig
 }